@@ -1,16 +1,27 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
-	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"todo-api/internal/auth"
+	"todo-api/internal/config"
+	"todo-api/internal/feed"
 	"todo-api/internal/handlers"
+	"todo-api/internal/logging"
 	"todo-api/internal/models"
 	"todo-api/internal/service"
 	"todo-api/internal/storage"
+	"todo-api/internal/telemetry"
 
 	_ "todo-api/docs"
 
@@ -18,13 +29,10 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-var (
-	port = flag.Int("p", 8080, "port for the server")
-)
-
 // @title           Todo List API
 // @version         1.0
-// @description     REST API для управления задачами (To-Do List)
+// @description     REST API для управления задачами (To-Do List).
+// @description     Ошибки возвращаются в формате application/problem+json (RFC 7807), ошибки полей запроса - в errors
 
 // @contact.name   API Support
 // @contact.url    http://www.swagger.io/support
@@ -36,43 +44,324 @@ var (
 // @host      localhost:8080
 // @BasePath  /api/v1
 
-// @securityDefinitions.basic  BasicAuth
+// @securityDefinitions.apikey  BearerAuth
+// @in                          header
+// @name                        Authorization
+// @description                 Токен доступа в формате "Bearer <token>"
 func main() {
-	flag.Parse()
+	cfg, options, err := config.Load(os.Args[1:], os.Getenv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fatal("Failed to load configuration", err)
+	}
+	if options.PrintConfig {
+		if err := cfg.Write(os.Stdout); err != nil {
+			fatal("Failed to print configuration", err)
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		fatal("Invalid configuration", err, slog.String("file", options.File))
+	}
+	if options.PrintConfig {
+		return
+	}
 
-	storage := storage.NewMemoryStorage()
-	todoService := service.NewTodoService(storage)
+	level, _ := logging.ParseLevel(cfg.Log.Level)
+	logger, err := logging.New(os.Stderr, cfg.Log.Format, level)
+	if err != nil {
+		fatal("Failed to set up logging", err)
+	}
+	// Сюда же попадают записи стандартного пакета log
+	slog.SetDefault(logger)
+	if level > slog.LevelDebug {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	shutdownTracing, err := telemetry.SetupTracing(context.Background(), telemetry.TracingConfig{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.OTLPEndpoint,
+		Insecure:    cfg.Tracing.OTLPInsecure,
+		ServiceName: "todo-api",
+	})
+	if err != nil {
+		fatal("Failed to set up tracing", err)
+	}
+
+	source, err := openStorage(cfg.Storage)
+	if err != nil {
+		fatal("Failed to open storage", err, slog.String("storage", cfg.Storage.Type))
+	}
+
+	// Сервисы работают с хранилищем через обертку, которая измеряет операции;
+	// счетчики задач опрашивают хранилище напрямую, чтобы не попадать в метрики операций
+	metrics := telemetry.NewMetrics()
+	metrics.RegisterTaskGauges(source)
+	store := telemetry.InstrumentStorage(source, metrics)
+
+	todoService := service.NewTodoService(store)
 	todoHandler := handlers.NewTodoHandler(todoService)
+	keyset, err := openKeyset(cfg.Auth.Keyset)
+	if err != nil {
+		fatal("Failed to load keyset", err)
+	}
+	authService := service.NewAuthService(store, keyset, service.TokenConfig{
+		AccessTTL:  time.Duration(cfg.Auth.AccessTTL),
+		RefreshTTL: time.Duration(cfg.Auth.RefreshTTL),
+	})
+	authHandler := handlers.NewAuthHandler(authService)
+	listHandler := handlers.NewListHandler(service.NewListService(store))
+	webhookConfig := service.WebhookConfig{
+		Interval:             time.Duration(cfg.Webhooks.Interval),
+		Timeout:              time.Duration(cfg.Webhooks.Timeout),
+		MaxAttempts:          cfg.Webhooks.Attempts,
+		Backoff:              time.Duration(cfg.Webhooks.Backoff),
+		AllowPrivateNetworks: cfg.Webhooks.AllowPrivateNetworks,
+	}
+	webhookHandler := handlers.NewWebhookHandler(service.NewWebhookService(store, webhookConfig))
+
+	if cfg.Seed.Enabled {
+		seedData(store, authService, cfg.Seed)
+	}
+
+	// SIGINT и SIGTERM отменяют ctx: фоновые задачи прерываются, а сервер
+	// дожидается текущих запросов и отправляет спаны, которые еще не ушли
+	// экспортеру
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	reminders := service.NewReminderScheduler(store, time.Duration(cfg.Reminders.Interval), func(event models.ReminderEvent) {
+		slog.Info("Reminder",
+			slog.String("task_id", event.TaskID),
+			slog.String("title", event.Title),
+			slog.String("owner_id", event.OwnerID),
+			slog.Time("remind_at", event.RemindAt),
+		)
+	})
+	reminders.Start(ctx)
+	jobs := []job{reminders}
+
+	dispatcher := service.NewWebhookDispatcher(store, webhookConfig)
+	dispatcher.Start(ctx)
+	jobs = append(jobs, dispatcher)
+
+	if cfg.Trash.Days > 0 {
+		purger := service.NewTrashPurger(store, time.Duration(cfg.Trash.Days)*24*time.Hour, time.Duration(cfg.Trash.PurgeInterval))
+		purger.Start(ctx)
+		jobs = append(jobs, purger)
+	}
 
-	seedData(storage)
+	// Ленту изменений питают изменения MemoryStorage
+	var feedHandler *handlers.FeedHandler
+	if changes, ok := source.(storage.ChangeSource); ok {
+		hub := feed.NewHub(cfg.Feed.Buffer)
+		changes.OnChange(hub.Publish)
+		feedHandler = handlers.NewFeedHandler(service.NewFeedService(hub, store))
+	} else {
+		slog.Warn("Change feed is not available", slog.String("storage", cfg.Storage.Type))
+	}
 
-	router := gin.Default()
+	// ID запроса, метрики и спан снаружи Recovery, чтобы учитывать и паники как 500
+	router := gin.New()
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		fatal("Invalid trusted proxies", err)
+	}
+	router.Use(logging.Middleware(logger), metrics.Middleware(), handlers.Recovery())
+	// CORS отвечает на предварительные запросы OPTIONS раньше, чем они
+	// дойдут до NoRoute
+	router.Use(handlers.CORS(handlers.CORSConfig{
+		AllowedOrigins:   cfg.CORS.AllowedOrigins,
+		AllowedMethods:   cfg.CORS.AllowedMethods,
+		AllowedHeaders:   cfg.CORS.AllowedHeaders,
+		AllowCredentials: cfg.CORS.AllowCredentials,
+		MaxAge:           time.Duration(cfg.CORS.MaxAge),
+	}))
+	router.NoRoute(handlers.NoRoute)
 
-	v1 := router.Group("/api/v1")
+	v1 := router.Group("/api/v1", handlers.RateLimit(cfg.RateLimit.RequestsPerSecond, cfg.RateLimit.Burst))
 	{
-		tasks := v1.Group("/tasks")
+		// Ленту изменений срок запроса не ограничивает
+		timeout := handlers.Timeout(time.Duration(cfg.Server.RequestTimeout))
+
+		auth := v1.Group("/auth", timeout)
+		{
+			auth.POST("/register", authHandler.Register)
+			auth.POST("/login", authHandler.Login)
+			auth.POST("/refresh", authHandler.Refresh)
+		}
+
+		if feedHandler != nil {
+			events := v1.Group("/tasks/events", authHandler.StreamAuth())
+			events.GET("", feedHandler.StreamEvents)
+			events.GET("/ws", feedHandler.StreamEventsWS)
+		}
+
+		protected := v1.Group("", timeout, authHandler.BearerAuth())
+		protected.POST("/auth/logout", authHandler.Logout)
+
+		tasks := protected.Group("/tasks")
 		{
 			tasks.GET("", todoHandler.GetTasks)
 			tasks.POST("", todoHandler.CreateTask)
+			tasks.POST("/batch", todoHandler.BatchTasks)
+			tasks.GET("/trash", todoHandler.GetTrash)
 			tasks.GET("/:id", todoHandler.GetTask)
 			tasks.PUT("/:id", todoHandler.UpdateTask)
+			tasks.PATCH("/:id", todoHandler.PatchTask)
 			tasks.DELETE("/:id", todoHandler.DeleteTask)
 			tasks.PATCH("/:id/complete", todoHandler.CompleteTask)
+			tasks.POST("/:id/restore", todoHandler.RestoreTask)
+			tasks.GET("/:id/occurrences", todoHandler.GetOccurrences)
+			tasks.GET("/:id/history", todoHandler.GetTaskHistory)
+			tasks.GET("/:id/blockers", todoHandler.GetBlockers)
+			tasks.POST("/:id/blockers", todoHandler.AddBlocker)
+			tasks.DELETE("/:id/blockers/:blocker_id", todoHandler.RemoveBlocker)
+		}
+
+		tags := protected.Group("/tags")
+		{
+			tags.GET("", todoHandler.GetTags)
+			tags.POST("", todoHandler.CreateTag)
+			tags.GET("/counts", todoHandler.GetTagCounts)
+			tags.PUT("/:name", todoHandler.UpdateTag)
+			tags.DELETE("/:name", todoHandler.DeleteTag)
+		}
+
+		lists := protected.Group("/lists")
+		{
+			lists.GET("", listHandler.GetLists)
+			lists.POST("", listHandler.CreateList)
+			lists.GET("/:id", listHandler.GetList)
+			lists.PUT("/:id", listHandler.UpdateList)
+			lists.DELETE("/:id", listHandler.DeleteList)
+			lists.GET("/:id/members", listHandler.GetMembers)
+			lists.POST("/:id/members", listHandler.AddMember)
+			lists.PUT("/:id/members/:user_id", listHandler.UpdateMember)
+			lists.DELETE("/:id/members/:user_id", listHandler.RemoveMember)
+		}
+
+		webhooks := protected.Group("/webhooks")
+		{
+			webhooks.GET("", webhookHandler.GetWebhooks)
+			webhooks.POST("", webhookHandler.CreateWebhook)
+			webhooks.GET("/dead-letters", webhookHandler.GetDeadLetters)
+			webhooks.GET("/:id", webhookHandler.GetWebhook)
+			webhooks.PUT("/:id", webhookHandler.UpdateWebhook)
+			webhooks.DELETE("/:id", webhookHandler.DeleteWebhook)
+			webhooks.GET("/:id/deliveries", webhookHandler.GetDeliveries)
+			webhooks.POST("/:id/deliveries/:delivery_id/retry", webhookHandler.RetryDelivery)
 		}
 	}
 
-	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	if cfg.Swagger.Enabled {
+		router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	}
 
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	server := &http.Server{
+		Addr:    cfg.Server.Address,
+		Handler: router,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout))
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Failed to shut down server", slog.Any("error", err))
+		}
+	}()
+
+	slog.Info("Server starting", slog.String("address", cfg.Server.Address), slog.Bool("tls", cfg.Server.TLS.Enabled()))
+	if cfg.Server.TLS.Enabled() {
+		err = server.ListenAndServeTLS(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fatal("Server failed", err)
+	}
+
+	shutdown(jobs, source, shutdownTracing)
+	slog.Info("Server stopped")
+}
+
+// openStorage открывает хранилище; конфигурация уже проверена Validate
+func openStorage(cfg config.Storage) (storage.Storage, error) {
+	switch cfg.Type {
+	case config.StoragePostgres:
+		return storage.NewPostgresStorage(cfg.DSN)
+	case config.StorageSQLite:
+		return storage.NewSQLiteStorage(cfg.DSN)
+	default:
+		if cfg.DSN == "" {
+			return storage.NewMemoryStorage(), nil
+		}
+		return storage.NewPersistentMemoryStorage(storage.PersistenceOptions{
+			Dir:              cfg.DSN,
+			SnapshotInterval: time.Duration(cfg.SnapshotInterval),
+			SyncWrites:       cfg.SyncWrites,
+		})
+	}
+}
+
+// openKeyset загружает ключи подписи токенов из файла и перечитывает его по
+// SIGHUP. Без файла используется случайный ключ, и все токены перестают
+// действовать после перезапуска.
+func openKeyset(path string) (*auth.Keyset, error) {
+	if path == "" {
+		slog.Warn("No keyset configured, using an ephemeral signing key")
+		return auth.GenerateKeyset()
+	}
+
+	keyset, err := auth.LoadKeyset(path)
+	if err != nil {
+		return nil, err
+	}
 
-	addr := fmt.Sprintf(":%d", *port)
-	log.Printf("Server starting on port %d", *port)
-	log.Fatal(router.Run(addr))
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			if err := keyset.Reload(); err != nil {
+				slog.Error("Failed to reload keyset", slog.Any("error", err))
+			} else {
+				slog.Info("Keyset reloaded", slog.String("path", path))
+			}
+		}
+	}()
+
+	return keyset, nil
 }
 
-func seedData(storage *storage.MemoryStorage) {
+// fatal пишет ошибку запуска в лог и завершает процесс
+func fatal(msg string, err error, attrs ...any) {
+	slog.Error(msg, append([]any{slog.Any("error", err)}, attrs...)...)
+	os.Exit(1)
+}
+
+// seedData заполняет хранилище тестовыми задачами демо-пользователя, только
+// если оно пустое, чтобы перезапуск с постоянным хранилищем не создавал дубликаты.
+func seedData(storage storage.TaskStorage, authService *service.AuthService, seed config.Seed) {
+	ctx := context.Background()
+	if _, total, err := storage.GetAll(ctx, models.TaskQuery{Limit: 1}); err != nil || total > 0 {
+		return
+	}
+
+	demo, err := authService.Register(ctx, models.RegisterRequest{
+		Username: seed.Username,
+		Password: seed.Password,
+	})
+	if err != nil {
+		slog.Error("Failed to create demo user", slog.Any("error", err))
+		return
+	}
+
 	tasks := []struct {
 		title       string
 		description string
@@ -90,12 +379,16 @@ func seedData(storage *storage.MemoryStorage) {
 			Title:       task.title,
 			Description: task.description,
 			Completed:   task.completed,
+			OwnerID:     demo.ID,
 		}
-		_, err := storage.Create(newTask)
+		_, err := storage.Create(ctx, newTask)
 		if err != nil {
-			log.Printf("Failed to create task: %v", err)
+			slog.Error("Failed to create task", slog.Any("error", err))
 		}
 	}
 
-	log.Printf("Created %d test tasks", len(tasks))
+	slog.Info("Created test tasks",
+		slog.Int("count", len(tasks)),
+		slog.String("username", seed.Username),
+	)
 }
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// job - фоновая задача, которую Stop останавливает и дожидается
+type job interface {
+	Stop()
+}
+
+// shutdown останавливает фоновые задачи, затем закрывает хранилище и
+// отправляет оставшиеся спаны. Порядок важен: пока задача не остановлена,
+// она пишет в хранилище и создает спаны.
+func shutdown(jobs []job, source any, flushTracing func(context.Context) error) {
+	for _, job := range jobs {
+		job.Stop()
+	}
+
+	if closer, ok := source.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			slog.Error("Failed to close storage", slog.Any("error", err))
+		}
+	}
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := flushTracing(flushCtx); err != nil {
+		slog.Error("Failed to flush spans", slog.Any("error", err))
+	}
+}
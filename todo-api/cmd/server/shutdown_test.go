@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// shutdownLog записывает этапы остановки в порядке их завершения
+type shutdownLog struct {
+	mu    sync.Mutex
+	steps []string
+}
+
+func (l *shutdownLog) add(step string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.steps = append(l.steps, step)
+}
+
+// slowJob, как настоящие задачи, в Stop дожидается идущего прохода
+type slowJob struct {
+	name string
+	log  *shutdownLog
+}
+
+func (j slowJob) Stop() {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		time.Sleep(10 * time.Millisecond)
+		j.log.add(j.name)
+	}()
+	<-done
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+func TestShutdownOrder(t *testing.T) {
+	log := &shutdownLog{}
+	jobs := []job{
+		slowJob{name: "reminders", log: log},
+		slowJob{name: "dispatcher", log: log},
+		slowJob{name: "purger", log: log},
+	}
+	source := closerFunc(func() error {
+		log.add("storage")
+		return nil
+	})
+	flush := func(ctx context.Context) error {
+		log.add("tracing")
+		return nil
+	}
+
+	shutdown(jobs, source, flush)
+
+	want := []string{"reminders", "dispatcher", "purger", "storage", "tracing"}
+	if !slices.Equal(log.steps, want) {
+		t.Errorf("shutdown steps = %v, want %v", log.steps, want)
+	}
+}
@@ -0,0 +1,97 @@
+// Package apperr описывает категории ошибок предметной области. Хранилище и
+// сервисы объявляют свои ошибки с категорией, а обработчики выбирают код
+// ответа по категории через errors.Is, не перечисляя все ошибки слоев ниже.
+package apperr
+
+import (
+	"errors"
+	"strings"
+)
+
+// Категории ошибок
+var (
+	// ErrNotFound - запрошенного объекта нет или он недоступен пользователю
+	ErrNotFound = errors.New("not found")
+	// ErrValidation - запрос содержит недопустимые данные
+	ErrValidation = errors.New("validation failed")
+	// ErrConflict - запрос противоречит текущему состоянию данных
+	ErrConflict = errors.New("conflict")
+	// ErrForbidden - у пользователя недостаточно прав
+	ErrForbidden = errors.New("forbidden")
+	// ErrUnauthorized - учетные данные или токен неверны либо устарели
+	ErrUnauthorized = errors.New("unauthorized")
+)
+
+// Error - ошибка с категорией Kind. errors.Is(err, Kind) для нее истинно,
+// а текст ошибки - только Message.
+type Error struct {
+	Kind    error
+	Message string
+}
+
+// New возвращает ошибку категории kind с текстом message
+func New(kind error, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// NotFound возвращает ошибку категории ErrNotFound
+func NotFound(message string) *Error {
+	return New(ErrNotFound, message)
+}
+
+// Validation возвращает ошибку категории ErrValidation
+func Validation(message string) *Error {
+	return New(ErrValidation, message)
+}
+
+// Conflict возвращает ошибку категории ErrConflict
+func Conflict(message string) *Error {
+	return New(ErrConflict, message)
+}
+
+// Forbidden возвращает ошибку категории ErrForbidden
+func Forbidden(message string) *Error {
+	return New(ErrForbidden, message)
+}
+
+// Unauthorized возвращает ошибку категории ErrUnauthorized
+func Unauthorized(message string) *Error {
+	return New(ErrUnauthorized, message)
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// FieldError - ошибка в одном поле запроса. Field - путь к полю в JSON
+// запроса, например operations[1].create.title, или имя параметра запроса.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError - ошибка категории ErrValidation с перечнем неверных полей
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Field возвращает ValidationError с одной ошибкой поля
+func Field(field, message string) *ValidationError {
+	return &ValidationError{Fields: []FieldError{{Field: field, Message: message}}}
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		parts[i] = field.Field + ": " + field.Message
+	}
+	return ErrValidation.Error() + ": " + strings.Join(parts, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrValidation
+}
@@ -0,0 +1,40 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestKinds(t *testing.T) {
+	errTaskNotFound := NotFound("task not found")
+	wrapped := fmt.Errorf("get task: %w", errTaskNotFound)
+
+	if !errors.Is(wrapped, errTaskNotFound) || !errors.Is(wrapped, ErrNotFound) {
+		t.Errorf("errors.Is(%v) does not find the error or its kind", wrapped)
+	}
+	if errors.Is(wrapped, ErrConflict) {
+		t.Errorf("errors.Is(%v, ErrConflict) = true", wrapped)
+	}
+	if errTaskNotFound.Error() != "task not found" {
+		t.Errorf("Error() = %q, want message without kind", errTaskNotFound.Error())
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	err := error(&ValidationError{Fields: []FieldError{
+		{Field: "title", Message: "is required"},
+		{Field: "priority", Message: "must be at most 3"},
+	}})
+
+	if !errors.Is(err, ErrValidation) {
+		t.Errorf("errors.Is(%v, ErrValidation) = false", err)
+	}
+	var validation *ValidationError
+	if !errors.As(fmt.Errorf("bind: %w", err), &validation) || len(validation.Fields) != 2 {
+		t.Errorf("errors.As() = %+v", validation)
+	}
+	if want := "validation failed: title: is required; priority: must be at most 3"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
@@ -0,0 +1,11 @@
+package auth
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims - содержимое токена доступа. ID пользователя хранится в Subject,
+// уникальный идентификатор токена (для отзыва) - в ID.
+type Claims struct {
+	jwt.RegisteredClaims
+}
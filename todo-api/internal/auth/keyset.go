@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minSecretSize - минимальная длина ключа HMAC-SHA256 в байтах
+const minSecretSize = 32
+
+var (
+	ErrUnknownKey = errors.New("unknown signing key")
+)
+
+// keysetFile - формат файла ключей:
+//
+//	{
+//	  "active": "2024-06",
+//	  "keys": [
+//	    {"kid": "2024-05", "secret": "<base64>"},
+//	    {"kid": "2024-06", "secret": "<base64>"}
+//	  ]
+//	}
+//
+// Новые токены подписываются активным ключом, проверяются - любым из списка.
+// Для ротации добавляется новый ключ и делается активным; старый удаляется
+// из файла, когда истекут подписанные им токены.
+type keysetFile struct {
+	Active string `json:"active"`
+	Keys   []struct {
+		KID    string `json:"kid"`
+		Secret string `json:"secret"`
+	} `json:"keys"`
+}
+
+// Keyset хранит ключи подписи токенов доступа.
+type Keyset struct {
+	mu     sync.RWMutex
+	path   string
+	active string
+	keys   map[string][]byte
+}
+
+// LoadKeyset читает ключи из файла. Reload перечитывает тот же файл.
+func LoadKeyset(path string) (*Keyset, error) {
+	k := &Keyset{path: path}
+	if err := k.Reload(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// GenerateKeyset создаёт набор из одного случайного ключа. Токены,
+// подписанные им, перестают быть действительными после перезапуска.
+func GenerateKeyset() (*Keyset, error) {
+	secret := make([]byte, minSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	return &Keyset{
+		active: "ephemeral",
+		keys:   map[string][]byte{"ephemeral": secret},
+	}, nil
+}
+
+// Reload перечитывает файл ключей. При ошибке прежний набор сохраняется.
+func (k *Keyset) Reload() error {
+	if k.path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(k.path)
+	if err != nil {
+		return fmt.Errorf("read keyset: %w", err)
+	}
+
+	var file keysetFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("decode keyset: %w", err)
+	}
+
+	keys := make(map[string][]byte, len(file.Keys))
+	for _, key := range file.Keys {
+		if key.KID == "" {
+			return errors.New("keyset contains a key without kid")
+		}
+		secret, err := base64.StdEncoding.DecodeString(key.Secret)
+		if err != nil {
+			return fmt.Errorf("decode secret of key %q: %w", key.KID, err)
+		}
+		if len(secret) < minSecretSize {
+			return fmt.Errorf("secret of key %q is shorter than %d bytes", key.KID, minSecretSize)
+		}
+		keys[key.KID] = secret
+	}
+
+	if _, ok := keys[file.Active]; !ok {
+		return fmt.Errorf("active key %q is not in the keyset", file.Active)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.active = file.Active
+	k.keys = keys
+	return nil
+}
+
+// Sign подписывает claims активным ключом и указывает его kid в заголовке.
+func (k *Keyset) Sign(claims jwt.Claims) (string, error) {
+	k.mu.RLock()
+	kid, secret := k.active, k.keys[k.active]
+	k.mu.RUnlock()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = kid
+
+	return token.SignedString(secret)
+}
+
+// Parse проверяет подпись и срок действия токена и заполняет claims.
+func (k *Keyset) Parse(tokenString string, claims jwt.Claims) error {
+	_, err := jwt.ParseWithClaims(tokenString, claims, k.keyFunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+	)
+	return err
+}
+
+func (k *Keyset) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	secret, ok := k.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	return secret, nil
+}
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func writeKeyset(t *testing.T, path, active string, kids ...string) {
+	t.Helper()
+
+	file := map[string]any{"active": active}
+	var keys []map[string]string
+	for _, kid := range kids {
+		secret := strings.Repeat(kid, 32)[:32]
+		keys = append(keys, map[string]string{
+			"kid":    kid,
+			"secret": base64.StdEncoding.EncodeToString([]byte(secret)),
+		})
+	}
+	file["keys"] = keys
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newClaims(ttl time.Duration) Claims {
+	return Claims{RegisteredClaims: jwt.RegisteredClaims{
+		Subject:   "user",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+	}}
+}
+
+func TestKeysetRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyset.json")
+	writeKeyset(t, path, "a", "a")
+
+	keyset, err := LoadKeyset(path)
+	if err != nil {
+		t.Fatalf("LoadKeyset() error = %v", err)
+	}
+
+	oldToken, err := keyset.Sign(newClaims(time.Minute))
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	// Новый ключ становится активным, старый остаётся для проверки
+	writeKeyset(t, path, "b", "a", "b")
+	if err := keyset.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	newToken, err := keyset.Sign(newClaims(time.Minute))
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"Token signed by previous key", oldToken, false},
+		{"Token signed by active key", newToken, false},
+		{"Garbage", "not-a-token", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var claims Claims
+			err := keyset.Parse(tt.token, &claims)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Старый ключ удалён из файла - подписанные им токены больше не принимаются
+	writeKeyset(t, path, "b", "b")
+	if err := keyset.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	var claims Claims
+	if err := keyset.Parse(oldToken, &claims); err == nil {
+		t.Errorf("Parse() accepted token signed by a removed key")
+	}
+	if err := keyset.Parse(newToken, &claims); err != nil {
+		t.Errorf("Parse() error = %v", err)
+	}
+}
+
+func TestKeysetRejectsInvalidFile(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"Active key missing", `{"active":"x","keys":[]}`},
+		{"Short secret", `{"active":"a","keys":[{"kid":"a","secret":"c2hvcnQ="}]}`},
+		{"Invalid base64", `{"active":"a","keys":[{"kid":"a","secret":"%%%"}]}`},
+		{"Invalid JSON", `{`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "keyset.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadKeyset(path); err == nil {
+				t.Errorf("LoadKeyset() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestKeysetRejectsExpiredToken(t *testing.T) {
+	keyset, err := GenerateKeyset()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := keyset.Sign(newClaims(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var claims Claims
+	if err := keyset.Parse(token, &claims); err == nil {
+		t.Errorf("Parse() accepted an expired token")
+	}
+}
@@ -0,0 +1,298 @@
+// Package config собирает конфигурацию сервера из нескольких слоев: значения
+// по умолчанию, файл YAML или TOML, переменные окружения TODO_* и флаги
+// командной строки. Каждый следующий слой переопределяет предыдущий.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"todo-api/internal/logging"
+	"todo-api/internal/telemetry"
+)
+
+// Хранилища
+const (
+	StorageMemory   = "memory"
+	StoragePostgres = "postgres"
+	StorageSQLite   = "sqlite"
+)
+
+// Config - конфигурация сервера. Имена ключей файла совпадают с тегами yaml
+// и toml.
+type Config struct {
+	Server    Server    `yaml:"server" toml:"server"`
+	Storage   Storage   `yaml:"storage" toml:"storage"`
+	Auth      Auth      `yaml:"auth" toml:"auth"`
+	CORS      CORS      `yaml:"cors" toml:"cors"`
+	RateLimit RateLimit `yaml:"rate_limit" toml:"rate_limit"`
+	Log       Log       `yaml:"log" toml:"log"`
+	Tracing   Tracing   `yaml:"tracing" toml:"tracing"`
+	Seed      Seed      `yaml:"seed" toml:"seed"`
+	Swagger   Swagger   `yaml:"swagger" toml:"swagger"`
+	Reminders Reminders `yaml:"reminders" toml:"reminders"`
+	Trash     Trash     `yaml:"trash" toml:"trash"`
+	Feed      Feed      `yaml:"feed" toml:"feed"`
+	Webhooks  Webhooks  `yaml:"webhooks" toml:"webhooks"`
+}
+
+type Server struct {
+	// Address - адрес, который слушает сервер, например :8080 или 127.0.0.1:8080
+	Address string `yaml:"address" toml:"address"`
+	// RequestTimeout ограничивает обработку запроса API; 0 снимает ограничение
+	RequestTimeout Duration `yaml:"request_timeout" toml:"request_timeout"`
+	// ShutdownTimeout - сколько ждать текущих запросов при остановке
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// TrustedProxies - адреса и подсети прокси, которым можно верить в
+	// X-Forwarded-For. Пустой список - адрес клиента берется из соединения
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies"`
+	TLS            TLS      `yaml:"tls" toml:"tls"`
+}
+
+// TLS включается, если заданы оба файла
+type TLS struct {
+	CertFile string `yaml:"cert_file" toml:"cert_file"`
+	KeyFile  string `yaml:"key_file" toml:"key_file"`
+}
+
+// Enabled сообщает, нужно ли слушать HTTPS
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+type Storage struct {
+	// Type - memory, postgres или sqlite
+	Type string `yaml:"type" toml:"type"`
+	// DSN - строка подключения postgres, файл sqlite или каталог журнала
+	// memory. memory без DSN хранит данные только в памяти
+	DSN string `yaml:"dsn" toml:"dsn"`
+	// SnapshotInterval - как часто memory с DSN сворачивает журнал в снимок
+	SnapshotInterval Duration `yaml:"snapshot_interval" toml:"snapshot_interval"`
+	// SyncWrites - fsync после каждой записи журнала memory. Без него
+	// последние изменения теряются при сбое питания или ОС
+	SyncWrites bool `yaml:"sync_writes" toml:"sync_writes"`
+}
+
+type Auth struct {
+	// Keyset - JSON-файл с ключами подписи токенов. Без него ключ случайный,
+	// и токены перестают действовать после перезапуска
+	Keyset     string   `yaml:"keyset" toml:"keyset"`
+	AccessTTL  Duration `yaml:"access_ttl" toml:"access_ttl"`
+	RefreshTTL Duration `yaml:"refresh_ttl" toml:"refresh_ttl"`
+}
+
+// CORS выключен, пока список AllowedOrigins пуст
+type CORS struct {
+	// AllowedOrigins - разрешенные источники вида https://app.example.com или *
+	AllowedOrigins   []string `yaml:"allowed_origins" toml:"allowed_origins"`
+	AllowedMethods   []string `yaml:"allowed_methods" toml:"allowed_methods"`
+	AllowedHeaders   []string `yaml:"allowed_headers" toml:"allowed_headers"`
+	AllowCredentials bool     `yaml:"allow_credentials" toml:"allow_credentials"`
+	// MaxAge - сколько браузер может кешировать ответ на предварительный запрос
+	MaxAge Duration `yaml:"max_age" toml:"max_age"`
+}
+
+// RateLimit ограничивает запросы к API с одного адреса. Нулевой
+// RequestsPerSecond снимает ограничение
+type RateLimit struct {
+	RequestsPerSecond float64 `yaml:"requests_per_second" toml:"requests_per_second"`
+	// Burst - сколько запросов подряд можно сделать сверх средней скорости
+	Burst int `yaml:"burst" toml:"burst"`
+}
+
+type Log struct {
+	// Format - json или text
+	Format string `yaml:"format" toml:"format"`
+	// Level - debug, info, warn или error
+	Level string `yaml:"level" toml:"level"`
+}
+
+type Tracing struct {
+	// Exporter - none, stdout или otlp
+	Exporter string `yaml:"exporter" toml:"exporter"`
+	// OTLPEndpoint - host:port коллектора OTLP/HTTP; пустой берется из
+	// переменных OTEL_EXPORTER_OTLP_* или равен localhost:4318
+	OTLPEndpoint string `yaml:"otlp_endpoint" toml:"otlp_endpoint"`
+	OTLPInsecure bool   `yaml:"otlp_insecure" toml:"otlp_insecure"`
+}
+
+// Seed создает демо-пользователя с задачами, если хранилище пустое.
+// Выключен по умолчанию, а пароль демо-пользователя задается явно, чтобы
+// рабочее хранилище не получило учетную запись с известным паролем
+type Seed struct {
+	Enabled  bool   `yaml:"enabled" toml:"enabled"`
+	Username string `yaml:"username" toml:"username"`
+	Password string `yaml:"password" toml:"password"`
+}
+
+type Swagger struct {
+	// Enabled открывает документацию API на /swagger/index.html
+	Enabled bool `yaml:"enabled" toml:"enabled"`
+}
+
+type Reminders struct {
+	// Interval - как часто искать задачи, время напоминания которых наступило
+	Interval Duration `yaml:"interval" toml:"interval"`
+}
+
+type Trash struct {
+	// Days - сколько дней удаленные задачи хранятся в корзине; 0 - бессрочно
+	Days          int      `yaml:"days" toml:"days"`
+	PurgeInterval Duration `yaml:"purge_interval" toml:"purge_interval"`
+}
+
+type Feed struct {
+	// Buffer - сколько последних событий хранится для клиентов, которые
+	// продолжают ленту изменений с last-event-id
+	Buffer int `yaml:"buffer" toml:"buffer"`
+}
+
+type Webhooks struct {
+	Interval Duration `yaml:"interval" toml:"interval"`
+	Timeout  Duration `yaml:"timeout" toml:"timeout"`
+	// Attempts - после стольких неудачных попыток доставка становится мертвой
+	Attempts int `yaml:"attempts" toml:"attempts"`
+	// Backoff - задержка перед второй попыткой, с каждой следующей удваивается
+	// до суток
+	Backoff Duration `yaml:"backoff" toml:"backoff"`
+	// AllowPrivateNetworks разрешает вебхуки на loopback, частные и
+	// link-local адреса. По умолчанию выключено: иначе любой пользователь
+	// мог бы отправлять запросы во внутреннюю сеть сервера и читать ответы
+	// в журнале доставок.
+	AllowPrivateNetworks bool `yaml:"allow_private_networks" toml:"allow_private_networks"`
+}
+
+// Default возвращает конфигурацию по умолчанию
+func Default() Config {
+	return Config{
+		Server: Server{
+			Address:         ":8080",
+			RequestTimeout:  Duration(30 * time.Second),
+			ShutdownTimeout: Duration(10 * time.Second),
+		},
+		Storage: Storage{
+			Type:             StorageMemory,
+			SnapshotInterval: Duration(5 * time.Minute),
+			SyncWrites:       true,
+		},
+		Auth: Auth{
+			AccessTTL:  Duration(15 * time.Minute),
+			RefreshTTL: Duration(30 * 24 * time.Hour),
+		},
+		CORS: CORS{
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+			AllowedHeaders: []string{"Authorization", "Content-Type", "If-Match", "If-None-Match", "Last-Event-ID", "X-Request-ID"},
+			MaxAge:         Duration(10 * time.Minute),
+		},
+		RateLimit: RateLimit{
+			Burst: 20,
+		},
+		Log: Log{
+			Format: logging.FormatJSON,
+			Level:  "info",
+		},
+		Tracing: Tracing{
+			Exporter: telemetry.ExporterNone,
+		},
+		Seed: Seed{
+			Username: "demo",
+		},
+		Swagger:   Swagger{Enabled: true},
+		Reminders: Reminders{Interval: Duration(30 * time.Second)},
+		Trash: Trash{
+			Days:          30,
+			PurgeInterval: Duration(time.Hour),
+		},
+		Feed: Feed{Buffer: 1000},
+		Webhooks: Webhooks{
+			Interval: Duration(5 * time.Second),
+			Timeout:  Duration(10 * time.Second),
+			Attempts: 8,
+			Backoff:  Duration(30 * time.Second),
+		},
+	}
+}
+
+// Validate проверяет конфигурацию и возвращает все найденные ошибки разом
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Address != "", "server.address is required")
+	check(c.Server.RequestTimeout >= 0, "server.request_timeout must not be negative")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	if c.Server.TLS.Enabled() {
+		check(c.Server.TLS.CertFile != "" && c.Server.TLS.KeyFile != "", "server.tls needs both cert_file and key_file")
+		for _, file := range []string{c.Server.TLS.CertFile, c.Server.TLS.KeyFile} {
+			if file != "" {
+				_, err := os.Stat(file)
+				check(err == nil, "server.tls: %v", err)
+			}
+		}
+	}
+
+	switch c.Storage.Type {
+	case StorageMemory:
+	case StoragePostgres, StorageSQLite:
+		check(c.Storage.DSN != "", "storage.dsn is required for %s storage", c.Storage.Type)
+	default:
+		check(false, "storage.type must be memory, postgres or sqlite, got %q", c.Storage.Type)
+	}
+	check(c.Storage.SnapshotInterval > 0, "storage.snapshot_interval must be positive")
+
+	check(c.Auth.AccessTTL > 0, "auth.access_ttl must be positive")
+	check(c.Auth.RefreshTTL > 0, "auth.refresh_ttl must be positive")
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		check(origin == "*" || validOrigin(origin), "cors.allowed_origins: %q is not an origin such as https://app.example.com", origin)
+	}
+	check(!c.CORS.AllowCredentials || !slices.Contains(c.CORS.AllowedOrigins, "*"), "cors.allow_credentials cannot be used with the * origin")
+	check(c.CORS.MaxAge >= 0, "cors.max_age must not be negative")
+
+	check(c.RateLimit.RequestsPerSecond >= 0, "rate_limit.requests_per_second must not be negative")
+	check(c.RateLimit.RequestsPerSecond == 0 || c.RateLimit.Burst >= 1, "rate_limit.burst must be at least 1")
+
+	check(c.Log.Format == logging.FormatJSON || c.Log.Format == logging.FormatText, "log.format must be json or text, got %q", c.Log.Format)
+	_, err := logging.ParseLevel(c.Log.Level)
+	check(err == nil, "log.level must be debug, info, warn or error, got %q", c.Log.Level)
+
+	switch c.Tracing.Exporter {
+	case telemetry.ExporterNone, telemetry.ExporterStdout, telemetry.ExporterOTLP:
+	default:
+		check(false, "tracing.exporter must be none, stdout or otlp, got %q", c.Tracing.Exporter)
+	}
+
+	if c.Seed.Enabled {
+		// Демо-пользователь проходит те же проверки, что и при регистрации
+		check(len(c.Seed.Username) >= 3 && len(c.Seed.Username) <= 50, "seed.username must be 3 to 50 characters long")
+		check(len(c.Seed.Password) >= 8 && len(c.Seed.Password) <= 72, "seed.password must be 8 to 72 characters long")
+	}
+
+	check(c.Reminders.Interval > 0, "reminders.interval must be positive")
+	check(c.Trash.Days >= 0, "trash.days must not be negative")
+	check(c.Trash.PurgeInterval > 0, "trash.purge_interval must be positive")
+	check(c.Feed.Buffer > 0, "feed.buffer must be positive")
+	check(c.Webhooks.Interval > 0, "webhooks.interval must be positive")
+	check(c.Webhooks.Timeout > 0, "webhooks.timeout must be positive")
+	check(c.Webhooks.Attempts > 0, "webhooks.attempts must be positive")
+	check(c.Webhooks.Backoff > 0, "webhooks.backoff must be positive")
+
+	return errors.Join(errs...)
+}
+
+// validOrigin проверяет, что origin - это схема, хост и необязательный порт
+func validOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		u.Path == "" && u.RawQuery == "" && u.Fragment == "" && u.User == nil &&
+		!strings.HasSuffix(origin, "/")
+}
@@ -0,0 +1,193 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFile создает файл конфигурации name во временном каталоге
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func env(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+func TestLoadLayers(t *testing.T) {
+	yamlFile := writeFile(t, "todo.yaml", `
+server:
+  address: ":9000"
+  request_timeout: 5s
+log:
+  level: warn
+  format: text
+cors:
+  allowed_origins: [https://app.example.com]
+`)
+	tomlFile := writeFile(t, "todo.toml", `
+[server]
+address = ":9000"
+request_timeout = "5s"
+
+[log]
+level = "warn"
+format = "text"
+
+[cors]
+allowed_origins = ["https://app.example.com"]
+`)
+
+	for _, file := range []string{yamlFile, tomlFile} {
+		t.Run(filepath.Ext(file), func(t *testing.T) {
+			cfg, options, err := Load(
+				[]string{"-log-level", "debug", "-rate-limit", "2.5"},
+				env(map[string]string{
+					"TODO_CONFIG":       file,
+					"TODO_LOG_LEVEL":    "error",
+					"TODO_ADDR":         ":9100",
+					"TODO_CORS_ORIGINS": "https://a.example.com, https://b.example.com",
+					"TODO_SYNC_WRITES":  "false",
+				}),
+				&bytes.Buffer{},
+			)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if options.File != file {
+				t.Errorf("File = %q, want %q", options.File, file)
+			}
+
+			for _, tc := range []struct {
+				name      string
+				got, want any
+			}{
+				// Файл переопределяет значения по умолчанию
+				{"request_timeout", cfg.Server.RequestTimeout, Duration(5 * time.Second)},
+				{"log.format", cfg.Log.Format, "text"},
+				// Окружение переопределяет файл
+				{"server.address", cfg.Server.Address, ":9100"},
+				{"cors.allowed_origins", strings.Join(cfg.CORS.AllowedOrigins, " "), "https://a.example.com https://b.example.com"},
+				{"storage.sync_writes", cfg.Storage.SyncWrites, false},
+				// Флаги переопределяют окружение
+				{"log.level", cfg.Log.Level, "debug"},
+				{"rate_limit", cfg.RateLimit.RequestsPerSecond, 2.5},
+				// Остальное берется по умолчанию
+				{"webhooks.attempts", cfg.Webhooks.Attempts, 8},
+			} {
+				if tc.got != tc.want {
+					t.Errorf("%s = %v, want %v", tc.name, tc.got, tc.want)
+				}
+			}
+			if err := cfg.Validate(); err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		args []string
+		env  map[string]string
+		want string
+	}{
+		"unknown yaml key": {args: []string{"-config", writeFile(t, "todo.yaml", "server:\n  adress: ':80'\n")}, want: "adress"},
+		"unknown toml key": {args: []string{"-config", writeFile(t, "todo.toml", "[server]\nadress = ':80'\n")}, want: "adress"},
+		"bad duration":     {args: []string{"-config", writeFile(t, "todo.yaml", "server:\n  request_timeout: 30\n")}, want: "missing unit"},
+		"unknown format":   {args: []string{"-config", writeFile(t, "todo.json", "{}")}, want: "unknown format"},
+		"missing file":     {args: []string{"-config", "/nonexistent/todo.yaml"}, want: "read config"},
+		"bad env value":    {env: map[string]string{"TODO_TRASH_DAYS": "many"}, want: "TODO_TRASH_DAYS"},
+		"unknown flag":     {args: []string{"-colour"}, want: "not defined"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := Load(tc.args, env(tc.env), &bytes.Buffer{})
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Load() error = %v, want %q", err, tc.want)
+			}
+		})
+	}
+
+	var usage bytes.Buffer
+	if _, _, err := Load([]string{"-h"}, env(nil), &usage); !errors.Is(err, flag.ErrHelp) || !strings.Contains(usage.String(), "TODO_LOG_LEVEL") {
+		t.Errorf("Load(-h) error = %v, usage %q", err, usage.String())
+	}
+}
+
+func TestLoadPort(t *testing.T) {
+	cfg, _, err := Load([]string{"-p", "3000"}, env(nil), &bytes.Buffer{})
+	if err != nil || cfg.Server.Address != ":3000" {
+		t.Errorf("Load(-p 3000) address = %q, %v", cfg.Server.Address, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("Default().Validate() error = %v", err)
+	}
+
+	cfg := Default()
+	cfg.Storage.Type = StorageSQLite
+	cfg.Log.Level = "loud"
+	cfg.CORS.AllowedOrigins = []string{"*", "app.example.com"}
+	cfg.CORS.AllowCredentials = true
+	cfg.RateLimit = RateLimit{RequestsPerSecond: 5}
+	cfg.Server.TLS.CertFile = "cert.pem"
+	cfg.Seed.Enabled = true
+	cfg.Seed.Password = "short"
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate() error = nil")
+	}
+	// Все ошибки сообщаются разом
+	for _, want := range []string{
+		"storage.dsn is required",
+		"log.level",
+		`"app.example.com" is not an origin`,
+		"cors.allow_credentials",
+		"rate_limit.burst",
+		"needs both cert_file and key_file",
+		"seed.password",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() error does not mention %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestWriteRedactsSecrets(t *testing.T) {
+	for _, dsn := range []string{"postgres://todo:secret@db:5432/todo", "host=db user=todo password=secret dbname=todo"} {
+		cfg := Default()
+		cfg.Storage.DSN = dsn
+		cfg.Seed.Password = "demo-password"
+
+		var out bytes.Buffer
+		if err := cfg.Write(&out); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if strings.Contains(out.String(), "secret") || strings.Contains(out.String(), "demo-password") {
+			t.Errorf("Write() leaks a password:\n%s", out.String())
+		}
+
+		// Вывод снова читается как файл конфигурации
+		var printed Config
+		if err := loadFile(writeFile(t, "printed.yaml", out.String()), &printed); err != nil {
+			t.Fatalf("load printed config: %v", err)
+		}
+		if printed.Server.RequestTimeout != cfg.Server.RequestTimeout || printed.Storage.Type != cfg.Storage.Type {
+			t.Errorf("printed config = %+v", printed)
+		}
+	}
+}
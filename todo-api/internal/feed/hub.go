@@ -0,0 +1,119 @@
+// Package feed рассылает события изменений задач подписчикам и хранит
+// последние события, чтобы подписчик мог продолжить ленту после
+// переподключения.
+package feed
+
+import (
+	"sync"
+	"time"
+
+	"todo-api/internal/models"
+)
+
+// subscriberBuffer - сколько новых событий может ждать подписчика. Подписчик,
+// который отстал сильнее, отключается и должен переподключиться с last-event-id.
+const subscriberBuffer = 256
+
+// Hub нумерует события, хранит последние из них и рассылает подписчикам.
+// Publish не блокируется, поэтому его можно вызывать под блокировкой хранилища.
+type Hub struct {
+	mu sync.Mutex
+	// lastID - ID последнего опубликованного события
+	lastID int64
+	// recent - последние события по возрастанию ID, не больше size
+	recent      []models.ChangeEvent
+	size        int
+	subscribers map[*Subscription]struct{}
+}
+
+// NewHub создаёт ленту, которая помнит последние size событий.
+func NewHub(size int) *Hub {
+	return &Hub{
+		size:        size,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish назначает событию следующий ID и время, если оно не задано, и
+// рассылает его подписчикам.
+func (h *Hub) Publish(event models.ChangeEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	event.ID = h.lastID
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+
+	h.recent = append(h.recent, event)
+	if len(h.recent) > h.size {
+		h.recent = h.recent[len(h.recent)-h.size:]
+	}
+
+	for sub := range h.subscribers {
+		select {
+		case sub.events <- event:
+		default:
+			h.drop(sub)
+		}
+	}
+}
+
+// Subscribe подписывает на события после lastEventID (0 - только на новые).
+// Сохранённые события после lastEventID приходят первыми. Если часть из них
+// уже вытеснена или lastEventID неизвестен (например, после перезапуска
+// сервера), первым приходит событие ChangeReset, а за ним только новые.
+func (h *Hub) Subscribe(lastEventID int64) *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var replay []models.ChangeEvent
+	if lastEventID > 0 {
+		oldest := h.lastID - int64(len(h.recent)) + 1
+		if lastEventID+1 < oldest || lastEventID > h.lastID {
+			replay = []models.ChangeEvent{{ID: h.lastID, Type: models.ChangeReset, At: time.Now()}}
+		} else {
+			replay = h.recent[lastEventID+1-oldest:]
+		}
+	}
+
+	sub := &Subscription{
+		hub:    h,
+		events: make(chan models.ChangeEvent, len(replay)+subscriberBuffer),
+	}
+	for _, event := range replay {
+		sub.events <- event
+	}
+	h.subscribers[sub] = struct{}{}
+
+	return sub
+}
+
+// drop отключает подписчика. Вызывается под h.mu.
+func (h *Hub) drop(sub *Subscription) {
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// Subscription - подписка на ленту изменений
+type Subscription struct {
+	hub    *Hub
+	events chan models.ChangeEvent
+}
+
+// Events возвращает канал событий. Канал закрывается после Close или если
+// подписчик не успевал читать события.
+func (s *Subscription) Events() <-chan models.ChangeEvent {
+	return s.events
+}
+
+// Close отменяет подписку.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	s.hub.drop(s)
+}
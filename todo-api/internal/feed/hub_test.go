@@ -0,0 +1,79 @@
+package feed
+
+import (
+	"testing"
+
+	"todo-api/internal/models"
+)
+
+// receive возвращает ID событий, уже ждущих подписчика
+func receive(sub *Subscription) []int64 {
+	var ids []int64
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				return ids
+			}
+			ids = append(ids, event.ID)
+		default:
+			return ids
+		}
+	}
+}
+
+func publish(hub *Hub, n int) {
+	for range n {
+		hub.Publish(models.ChangeEvent{Type: models.ChangeUpdated, Task: &models.Task{}})
+	}
+}
+
+func TestHubSubscribe(t *testing.T) {
+	hub := NewHub(3)
+	publish(hub, 5)
+
+	live := hub.Subscribe(0)
+	defer live.Close()
+	resumed := hub.Subscribe(3)
+	defer resumed.Close()
+
+	publish(hub, 1)
+	if ids := receive(live); len(ids) != 1 || ids[0] != 6 {
+		t.Errorf("new subscriber got %v, want [6]", ids)
+	}
+	if ids := receive(resumed); len(ids) != 3 || ids[0] != 4 || ids[2] != 6 {
+		t.Errorf("resumed subscriber got %v, want [4 5 6]", ids)
+	}
+}
+
+func TestHubSubscribeReset(t *testing.T) {
+	hub := NewHub(3)
+	publish(hub, 5)
+
+	for _, lastID := range []int64{1, 10} {
+		sub := hub.Subscribe(lastID)
+		event := <-sub.Events()
+		if event.Type != models.ChangeReset || event.ID != 5 {
+			t.Errorf("Subscribe(%d) first event = %s %d, want reset 5", lastID, event.Type, event.ID)
+		}
+		if ids := receive(sub); len(ids) != 0 {
+			t.Errorf("Subscribe(%d) replayed %v after reset, want nothing", lastID, ids)
+		}
+		sub.Close()
+	}
+}
+
+func TestHubDropsSlowSubscriber(t *testing.T) {
+	hub := NewHub(10)
+	sub := hub.Subscribe(0)
+
+	publish(hub, subscriberBuffer+1)
+	if ids := receive(sub); len(ids) != subscriberBuffer {
+		t.Errorf("slow subscriber got %d events, want %d", len(ids), subscriberBuffer)
+	}
+	if _, ok := <-sub.Events(); ok {
+		t.Error("slow subscriber channel is open, want closed")
+	}
+	// Повторное закрытие отключенной подписки безопасно
+	sub.Close()
+}
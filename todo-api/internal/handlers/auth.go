@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"todo-api/internal/apperr"
+	"todo-api/internal/auth"
+	"todo-api/internal/models"
+	"todo-api/internal/service"
+)
+
+// Ключи gin.Context, под которыми middleware авторизации сохраняет
+// ID пользователя и claims токена доступа
+const (
+	userIDKey = "userID"
+	claimsKey = "claims"
+)
+
+type AuthHandler struct {
+	service *service.AuthService
+}
+
+func NewAuthHandler(service *service.AuthService) *AuthHandler {
+	return &AuthHandler{
+		service: service,
+	}
+}
+
+// Register регистрирует нового пользователя
+// @Summary Зарегистрировать пользователя
+// @Description Создает учетную запись с указанным именем и паролем
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param user body models.RegisterRequest true "Имя пользователя и пароль"
+// @Success 201 {object} models.User
+// @Failure 400 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /auth/register [post]
+func (h *AuthHandler) Register(c *gin.Context) {
+	var req models.RegisterRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	user, err := h.service.Register(c.Request.Context(), req)
+	if err != nil {
+		respondServiceError(c, err, "Failed to register user")
+		return
+	}
+
+	c.JSON(http.StatusCreated, user)
+}
+
+// Login выдает токены доступа и обновления
+// @Summary Войти
+// @Description Проверяет имя пользователя и пароль и выдает пару токенов
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body models.LoginRequest true "Имя пользователя и пароль"
+// @Success 200 {object} models.TokenResponse
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /auth/login [post]
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req models.LoginRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	tokens, err := h.service.Login(c.Request.Context(), req)
+	if err != nil {
+		respondServiceError(c, err, "Failed to log in")
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Refresh обменивает токен обновления на новую пару токенов
+// @Summary Обновить токены
+// @Description Выдает новую пару токенов. Переданный токен обновления становится недействительным
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body models.RefreshRequest true "Токен обновления"
+// @Success 200 {object} models.TokenResponse
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /auth/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req models.RefreshRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	tokens, err := h.service.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		respondServiceError(c, err, "Failed to refresh token")
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout отзывает текущий токен доступа и цепочку токена обновления
+// @Summary Выйти
+// @Description Отзывает текущий токен доступа и, если передан, токен обновления вместе со всеми его предшественниками
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param token body models.LogoutRequest false "Токен обновления"
+// @Success 204 "No Content"
+// @Failure 401 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req models.LogoutRequest
+	if c.Request.ContentLength != 0 {
+		if !bindJSON(c, &req) {
+			return
+		}
+	}
+
+	claims, _ := c.Get(claimsKey)
+	if err := h.service.Logout(c.Request.Context(), claims.(auth.Claims), req.RefreshToken); err != nil {
+		respondInternalError(c, err, "Failed to log out")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// BearerAuth проверяет токен доступа из заголовка Authorization: Bearer и
+// сохраняет ID пользователя и claims токена в контексте запроса.
+func (h *AuthHandler) BearerAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h.authenticate(c, bearerToken(c))
+	}
+}
+
+// StreamAuth работает как BearerAuth, но принимает токен и из параметра
+// access_token: браузерные EventSource и WebSocket не умеют передавать
+// заголовок Authorization.
+func (h *AuthHandler) StreamAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := bearerToken(c)
+		if token == "" {
+			token = c.Query("access_token")
+		}
+		h.authenticate(c, token)
+	}
+}
+
+// bearerToken возвращает токен из заголовка Authorization: Bearer или пустую строку
+func bearerToken(c *gin.Context) string {
+	scheme, token, found := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return token
+}
+
+func (h *AuthHandler) authenticate(c *gin.Context, token string) {
+	if token == "" {
+		c.Header("WWW-Authenticate", `Bearer realm="todo-api"`)
+		abortWithError(c, http.StatusUnauthorized, "Authorization required")
+		return
+	}
+
+	claims, err := h.service.ValidateAccessToken(c.Request.Context(), token)
+	if err != nil {
+		if errors.Is(err, apperr.ErrUnauthorized) {
+			c.Header("WWW-Authenticate", `Bearer realm="todo-api", error="invalid_token"`)
+			abortWithError(c, http.StatusUnauthorized, "Invalid or expired token")
+		} else {
+			status, message := internalError(c, err, "Failed to authenticate")
+			abortWithError(c, status, message)
+		}
+		return
+	}
+
+	c.Set(userIDKey, claims.Subject)
+	c.Set(claimsKey, claims)
+	c.Next()
+}
+
+// currentUserID возвращает ID пользователя, установленный middleware авторизации
+func currentUserID(c *gin.Context) string {
+	return c.GetString(userIDKey)
+}
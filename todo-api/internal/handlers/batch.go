@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"todo-api/internal/models"
+	"todo-api/internal/service"
+)
+
+// BatchTasks выполняет пакет операций над задачами
+// @Summary Пакетные операции с задачами
+// @Description Создает, обновляет и удаляет задачи одним запросом (до 1000 операций). Каждая операция проверяется
+// @Description так же, как одиночный запрос, и получает свой код ответа в results.
+// @Description В режиме atomic (по умолчанию) применяются все операции или ни одной: при ошибке ответ получает код
+// @Description неудавшейся операции, остальные операции получают 424. В режиме best_effort операции выполняются
+// @Description независимо, ответ 200
+// @Tags tasks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param batch body models.BatchRequest true "Операции пакета"
+// @Success 200 {object} models.BatchResponse
+// @Failure 400 {object} models.BatchResponse
+// @Failure 401 {object} models.Problem
+// @Failure 403 {object} models.BatchResponse
+// @Failure 404 {object} models.BatchResponse
+// @Failure 409 {object} models.BatchResponse
+// @Failure 412 {object} models.BatchResponse
+// @Failure 500 {object} models.Problem
+// @Router /tasks/batch [post]
+func (h *TodoHandler) BatchTasks(c *gin.Context) {
+	var req models.BatchRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = models.BatchAtomic
+	}
+
+	results, err := h.service.Batch(c.Request.Context(), currentUserID(c), req)
+	if err != nil {
+		respondInternalError(c, err, "Failed to apply batch")
+		return
+	}
+
+	response := models.BatchResponse{Mode: req.Mode, Results: make([]models.BatchResult, len(results))}
+	status := http.StatusOK
+	for i, result := range results {
+		item := models.BatchResult{Index: i}
+		if result.Err != nil {
+			item.Status, item.Error, item.BlockedBy = batchError(result.Err)
+			response.Failed++
+			// Атомарный пакет отвечает кодом операции, из-за которой он не применён
+			if req.Mode == models.BatchAtomic && !errors.Is(result.Err, service.ErrBatchAborted) {
+				status = item.Status
+			}
+		} else {
+			item.Status = batchSuccessStatus(req.Operations[i].Op)
+			if item.Status != http.StatusNoContent {
+				task := result.Task
+				item.Task = &task
+			}
+			response.Succeeded++
+		}
+		response.Results[i] = item
+	}
+
+	c.JSON(status, response)
+}
+
+func batchSuccessStatus(op models.BatchOperationType) int {
+	switch op {
+	case models.BatchCreate:
+		return http.StatusCreated
+	case models.BatchDelete:
+		return http.StatusNoContent
+	default:
+		return http.StatusOK
+	}
+}
+
+// batchError возвращает код и текст, которыми одиночный запрос ответил бы
+// на ошибку операции пакета
+func batchError(err error) (status int, message string, blockedBy []string) {
+	if errors.Is(err, service.ErrBatchAborted) {
+		return http.StatusFailedDependency, err.Error(), nil
+	}
+	if status, ok := serviceErrorStatus(err); ok {
+		var blocked *service.BlockedError
+		if errors.As(err, &blocked) {
+			blockedBy = blocked.BlockerIDs
+		}
+		return status, err.Error(), blockedBy
+	}
+	if status, message, ok := contextErrorStatus(err); ok {
+		return status, message, nil
+	}
+	return http.StatusInternalServerError, "Failed to apply operation", nil
+}
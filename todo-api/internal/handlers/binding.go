@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"todo-api/internal/apperr"
+)
+
+func init() {
+	// Ошибки проверки называют поля так же, как они называются в JSON запроса
+	if engine, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		engine.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+// bindJSON разбирает тело запроса в obj и проверяет его. При ошибке отвечает
+// 400 с ошибками полей и возвращает false.
+func bindJSON(c *gin.Context, obj any) bool {
+	err := c.ShouldBindJSON(obj)
+	if err == nil {
+		return true
+	}
+
+	if validation := bindingError(err); validation != nil {
+		respondValidationError(c, validation)
+	} else {
+		respondError(c, http.StatusBadRequest, requestBodyError(err))
+	}
+	return false
+}
+
+// bindingError переводит ошибки проверки и несовпадения типов JSON в ошибки
+// полей или возвращает nil, если ошибка не относится к полям
+func bindingError(err error) *apperr.ValidationError {
+	var (
+		invalid   validator.ValidationErrors
+		typeError *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &invalid):
+		fields := make([]apperr.FieldError, len(invalid))
+		for i, fe := range invalid {
+			fields[i] = apperr.FieldError{Field: fieldPath(fe), Message: validationMessage(fe)}
+		}
+		return &apperr.ValidationError{Fields: fields}
+	case errors.As(err, &typeError) && typeError.Field != "":
+		return apperr.Field(jsonFieldPath(typeError.Field), "must be "+jsonTypeName(typeError.Type))
+	default:
+		return nil
+	}
+}
+
+// requestBodyError возвращает описание ошибки тела запроса, которая не
+// относится к отдельному полю
+func requestBodyError(err error) string {
+	var (
+		syntaxError *json.SyntaxError
+		timeError   *time.ParseError
+	)
+	switch {
+	case errors.Is(err, io.EOF):
+		return "Request body is empty"
+	case errors.As(err, &syntaxError), errors.Is(err, io.ErrUnexpectedEOF):
+		return "Request body is not valid JSON"
+	case errors.As(err, &timeError):
+		return fmt.Sprintf("Time %q must be an RFC 3339 timestamp", timeError.Value)
+	default:
+		return err.Error()
+	}
+}
+
+// fieldPath возвращает путь к полю без имени типа запроса, например
+// operations[0].create.title
+func fieldPath(fe validator.FieldError) string {
+	_, path, found := strings.Cut(fe.Namespace(), ".")
+	if !found {
+		return fe.Field()
+	}
+	return path
+}
+
+// jsonFieldPath записывает индексы в пути поля из ошибки encoding/json так
+// же, как в ошибках проверки: operations.0.title -> operations[0].title
+func jsonFieldPath(field string) string {
+	var path strings.Builder
+	for i, part := range strings.Split(field, ".") {
+		switch _, err := strconv.Atoi(part); {
+		case err == nil:
+			path.WriteString("[" + part + "]")
+		case i > 0:
+			path.WriteString("." + part)
+		default:
+			path.WriteString(part)
+		}
+	}
+	return path.String()
+}
+
+// validationMessage описывает нарушенное правило проверки поля
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "url":
+		return "must be a URL"
+	case "hexcolor":
+		return "must be a hex color such as #ff8800"
+	case "min", "max":
+		bound := "at least"
+		if fe.Tag() == "max" {
+			bound = "at most"
+		}
+		switch fe.Kind() {
+		case reflect.String:
+			return fmt.Sprintf("must be %s %s characters long", bound, fe.Param())
+		case reflect.Slice, reflect.Array, reflect.Map:
+			return fmt.Sprintf("must contain %s %s items", bound, fe.Param())
+		default:
+			return fmt.Sprintf("must be %s %s", bound, fe.Param())
+		}
+	default:
+		return fmt.Sprintf("failed the %s check", fe.Tag())
+	}
+}
+
+// jsonTypeName называет тип JSON, которым должно быть значение типа t
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Pointer:
+		return jsonTypeName(t.Elem())
+	default:
+		return "an object"
+	}
+}
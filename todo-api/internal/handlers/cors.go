@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSConfig описывает, каким страницам других источников браузер может
+// отдавать ответы API
+type CORSConfig struct {
+	// AllowedOrigins - разрешенные источники или * для любого
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// corsExposedHeaders - заголовки ответа, которые нужны клиентам API
+var corsExposedHeaders = strings.Join([]string{"ETag", "Accept-Patch", "Retry-After", "X-Request-ID"}, ", ")
+
+// CORS добавляет заголовки CORS к ответам на запросы разрешенных
+// источников и сам отвечает на предварительные запросы OPTIONS. Без
+// разрешенных источников ничего не делает.
+func CORS(config CORSConfig) gin.HandlerFunc {
+	anyOrigin := slices.Contains(config.AllowedOrigins, "*")
+	methods := strings.Join(config.AllowedMethods, ", ")
+	headers := strings.Join(config.AllowedHeaders, ", ")
+	maxAge := strconv.Itoa(int(config.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" || len(config.AllowedOrigins) == 0 {
+			c.Next()
+			return
+		}
+
+		// Ответ зависит от Origin, и кеши должны это учитывать
+		c.Writer.Header().Add("Vary", "Origin")
+		if !anyOrigin && !slices.Contains(config.AllowedOrigins, origin) {
+			c.Next()
+			return
+		}
+
+		if anyOrigin {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+		if config.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			c.Header("Access-Control-Allow-Methods", methods)
+			c.Header("Access-Control-Allow-Headers", headers)
+			c.Header("Access-Control-Max-Age", maxAge)
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Header("Access-Control-Expose-Headers", corsExposedHeaders)
+		c.Next()
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"todo-api/internal/models"
+)
+
+// GetBlockers возвращает задачи, блокирующие задачу
+// @Summary Получить блокирующие задачи
+// @Description Возвращает задачи, которые должны быть выполнены раньше указанной
+// @Tags tasks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "ID задачи"
+// @Success 200 {array} models.Task
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /tasks/{id}/blockers [get]
+func (h *TodoHandler) GetBlockers(c *gin.Context) {
+	blockers, err := h.service.GetBlockers(c.Request.Context(), currentUserID(c), c.Param("id"))
+	if err != nil {
+		respondServiceError(c, err, "Failed to get blockers")
+		return
+	}
+
+	c.JSON(http.StatusOK, blockers)
+}
+
+// AddBlocker добавляет блокирующую задачу
+// @Summary Добавить блокирующую задачу
+// @Description Задача не может быть выполнена, пока не выполнена блокирующая. Обе задачи должны быть в одном списке, зависимость не может замыкать цикл
+// @Tags tasks
+// @Security BearerAuth
+// @Accept json
+// @Param id path string true "ID задачи"
+// @Param blocker body models.AddBlockerRequest true "ID блокирующей задачи"
+// @Success 204 "No Content"
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /tasks/{id}/blockers [post]
+func (h *TodoHandler) AddBlocker(c *gin.Context) {
+	var req models.AddBlockerRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if err := h.service.AddBlocker(c.Request.Context(), currentUserID(c), c.Param("id"), req.BlockerID); err != nil {
+		respondServiceError(c, err, "Failed to add blocker")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RemoveBlocker удаляет блокирующую задачу
+// @Summary Удалить блокирующую задачу
+// @Description Удаляет зависимость между задачами, сами задачи не меняются
+// @Tags tasks
+// @Security BearerAuth
+// @Param id path string true "ID задачи"
+// @Param blocker_id path string true "ID блокирующей задачи"
+// @Success 204 "No Content"
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /tasks/{id}/blockers/{blocker_id} [delete]
+func (h *TodoHandler) RemoveBlocker(c *gin.Context) {
+	if err := h.service.RemoveBlocker(c.Request.Context(), currentUserID(c), c.Param("id"), c.Param("blocker_id")); err != nil {
+		respondServiceError(c, err, "Failed to remove blocker")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"todo-api/internal/apperr"
+	"todo-api/internal/logging"
+	"todo-api/internal/models"
+	"todo-api/internal/service"
+)
+
+// StatusClientClosedRequest - нестандартный статус nginx для запросов, клиент
+// которых закрыл соединение, не дождавшись ответа. Клиент его не увидит, он
+// нужен логам и метрикам.
+const StatusClientClosedRequest = 499
+
+// newProblem возвращает ответ об ошибке со статусом status и описанием
+// detail. request_id совпадает с заголовком X-Request-ID и записями лога,
+// по нему поддержка находит запрос.
+func newProblem(c *gin.Context, status int, detail string) models.Problem {
+	return models.Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  c.Request.URL.Path,
+		RequestID: logging.RequestID(c.Request.Context()),
+	}
+}
+
+// respondProblem отвечает problem с типом содержимого application/problem+json
+func respondProblem(c *gin.Context, problem models.Problem) {
+	// JSON-рендер gin не меняет уже выставленный Content-Type
+	c.Header("Content-Type", models.ProblemContentType)
+	c.JSON(problem.Status, problem)
+}
+
+func respondError(c *gin.Context, status int, message string) {
+	respondProblem(c, newProblem(c, status, message))
+}
+
+// respondValidationError отвечает 400 с ошибками полей запроса
+func respondValidationError(c *gin.Context, err *apperr.ValidationError) {
+	problem := newProblem(c, http.StatusBadRequest, err.Error())
+	problem.Errors = err.Fields
+	respondProblem(c, problem)
+}
+
+// respondServiceError отвечает на ошибку сервиса кодом ее категории, а на
+// ошибку без категории - 500 с текстом message
+func respondServiceError(c *gin.Context, err error, message string) {
+	status, ok := serviceErrorStatus(err)
+	if !ok {
+		respondInternalError(c, err, message)
+		return
+	}
+
+	problem := newProblem(c, status, err.Error())
+	var validation *apperr.ValidationError
+	if errors.As(err, &validation) {
+		problem.Errors = validation.Fields
+	}
+	var blocked *service.BlockedError
+	if errors.As(err, &blocked) {
+		problem.BlockedBy = blocked.BlockerIDs
+	}
+	respondProblem(c, problem)
+}
+
+// serviceErrorStatus возвращает код ответа на ошибку сервиса по ее
+// категории или false, если у ошибки нет категории
+func serviceErrorStatus(err error) (int, bool) {
+	switch {
+	case errors.Is(err, service.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed, true
+	case errors.Is(err, apperr.ErrValidation):
+		return http.StatusBadRequest, true
+	case errors.Is(err, apperr.ErrNotFound):
+		return http.StatusNotFound, true
+	case errors.Is(err, apperr.ErrUnauthorized):
+		return http.StatusUnauthorized, true
+	case errors.Is(err, apperr.ErrForbidden):
+		return http.StatusForbidden, true
+	case errors.Is(err, apperr.ErrConflict):
+		return http.StatusConflict, true
+	default:
+		return 0, false
+	}
+}
+
+// respondInternalError отвечает 500, не раскрывая клиенту err; err попадает
+// в строку лога запроса. Ошибки отмены запроса получают 499 или 504.
+func respondInternalError(c *gin.Context, err error, message string) {
+	status, message := internalError(c, err, message)
+	respondError(c, status, message)
+}
+
+// internalError прикладывает err к запросу для лога и возвращает статус и
+// текст ответа: 500 и message или, для отмены запроса, 499 или 504
+func internalError(c *gin.Context, err error, message string) (int, string) {
+	c.Error(err)
+	if status, message, ok := contextError(c, err); ok {
+		return status, message
+	}
+	return http.StatusInternalServerError, message
+}
+
+// contextError возвращает ответ на ошибку, вызванную отменой контекста
+// запроса: 504, если истек срок запроса, и 499, если клиент закрыл
+// соединение. Драйверы баз данных не всегда оборачивают ошибку контекста,
+// поэтому проверяется и сам контекст запроса.
+func contextError(c *gin.Context, err error) (int, string, bool) {
+	if ctxErr := c.Request.Context().Err(); ctxErr != nil {
+		err = ctxErr
+	}
+	return contextErrorStatus(err)
+}
+
+// contextErrorStatus возвращает код и текст ответа на ошибку контекста
+func contextErrorStatus(err error) (int, string, bool) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, "Request timed out", true
+	case errors.Is(err, context.Canceled):
+		return StatusClientClosedRequest, "Client closed request", true
+	default:
+		return 0, "", false
+	}
+}
+
+func abortWithError(c *gin.Context, status int, message string) {
+	c.Abort()
+	respondError(c, status, message)
+}
+
+// NoRoute отвечает 404 на запрос к несуществующему маршруту
+func NoRoute(c *gin.Context) {
+	respondError(c, http.StatusNotFound, "Route not found")
+}
+
+// Timeout ограничивает время обработки запроса: по истечении timeout
+// контекст запроса отменяется, и обработчик отвечает 504. Нулевой timeout
+// не ограничивает запрос.
+func Timeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// Recovery отвечает 500 на панику в обработчике и пишет ее в лог со стеком
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		slog.ErrorContext(c.Request.Context(), "Panic while handling request",
+			slog.Any("panic", recovered),
+			slog.String("stack", string(debug.Stack())),
+		)
+		c.Error(fmt.Errorf("panic: %v", recovered))
+		abortWithError(c, http.StatusInternalServerError, "Internal server error")
+	})
+}
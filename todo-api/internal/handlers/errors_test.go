@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"todo-api/internal/apperr"
+	"todo-api/internal/models"
+	"todo-api/internal/service"
+	"todo-api/internal/storage"
+)
+
+// serveProblem выполняет запрос к обработчику handler и разбирает ответ об ошибке
+func serveProblem(t *testing.T, handler gin.HandlerFunc, body string) (int, models.Problem) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/tasks", handler)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/tasks", strings.NewReader(body)))
+
+	if contentType := w.Header().Get("Content-Type"); contentType != models.ProblemContentType {
+		t.Errorf("Content-Type = %q, want %q", contentType, models.ProblemContentType)
+	}
+	var problem models.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("decode problem %s: %v", w.Body, err)
+	}
+	if problem.Status != w.Code || problem.Title != http.StatusText(w.Code) || problem.Instance != "/tasks" {
+		t.Errorf("problem = %+v, want status %d", problem, w.Code)
+	}
+	return w.Code, problem
+}
+
+func TestBindJSON(t *testing.T) {
+	bind := func(c *gin.Context) {
+		var req models.BatchRequest
+		if bindJSON(c, &req) {
+			c.Status(http.StatusNoContent)
+		}
+	}
+
+	for name, tc := range map[string]struct {
+		body   string
+		detail string
+		fields []apperr.FieldError
+	}{
+		"validation": {
+			body: `{"mode": "all", "operations": [{"op": "create"}, {"op": "move"}]}`,
+			fields: []apperr.FieldError{
+				{Field: "mode", Message: "must be one of: atomic, best_effort"},
+				{Field: "operations[1].op", Message: "must be one of: create, update, delete"},
+			},
+		},
+		"missing": {
+			body:   `{}`,
+			fields: []apperr.FieldError{{Field: "operations", Message: "is required"}},
+		},
+		"type": {
+			body:   `{"operations": [{"op": "create", "create": {"title": 42}}]}`,
+			fields: []apperr.FieldError{{Field: "operations[0].create.title", Message: "must be a string"}},
+		},
+		"syntax": {body: `{"operations": [`, detail: "Request body is not valid JSON"},
+		"empty":  {detail: "Request body is empty"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			status, problem := serveProblem(t, bind, tc.body)
+			if status != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400", status)
+			}
+			if tc.detail != "" && problem.Detail != tc.detail {
+				t.Errorf("detail = %q, want %q", problem.Detail, tc.detail)
+			}
+			if fmt.Sprint(problem.Errors) != fmt.Sprint(tc.fields) {
+				t.Errorf("errors = %v, want %v", problem.Errors, tc.fields)
+			}
+		})
+	}
+}
+
+func TestRespondServiceError(t *testing.T) {
+	for _, tc := range []struct {
+		err    error
+		status int
+	}{
+		{fmt.Errorf("get task: %w", storage.ErrTaskNotFound), http.StatusNotFound},
+		{service.ErrForbidden, http.StatusForbidden},
+		{service.ErrInvalidToken, http.StatusUnauthorized},
+		{fmt.Errorf("%w: unknown sort key", service.ErrInvalidSort), http.StatusBadRequest},
+		{storage.ErrVersionConflict, http.StatusConflict},
+		{service.ErrPreconditionFailed, http.StatusPreconditionFailed},
+		{&service.BlockedError{BlockerIDs: []string{"a", "b"}}, http.StatusConflict},
+		{fmt.Errorf("database is locked"), http.StatusInternalServerError},
+	} {
+		t.Run(tc.err.Error(), func(t *testing.T) {
+			status, problem := serveProblem(t, func(c *gin.Context) {
+				respondServiceError(c, tc.err, "Failed to update task")
+			}, "")
+			if status != tc.status {
+				t.Errorf("status = %d, want %d", status, tc.status)
+			}
+
+			switch {
+			case tc.status == http.StatusInternalServerError:
+				// Текст внутренней ошибки клиенту не отдается
+				if problem.Detail != "Failed to update task" {
+					t.Errorf("detail = %q", problem.Detail)
+				}
+			case problem.Detail != tc.err.Error():
+				t.Errorf("detail = %q, want %q", problem.Detail, tc.err.Error())
+			}
+			if _, blocked := tc.err.(*service.BlockedError); blocked && len(problem.BlockedBy) != 2 {
+				t.Errorf("blocked_by = %v", problem.BlockedBy)
+			}
+		})
+	}
+}
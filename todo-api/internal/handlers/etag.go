@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"todo-api/internal/models"
+)
+
+// etag возвращает сильный ETag версии задачи
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// setETag отдаёт версию задачи в заголовке ETag
+func setETag(c *gin.Context, task models.Task) {
+	c.Header("ETag", etag(task.Version))
+}
+
+// ifMatch разбирает заголовок If-Match в список версий задачи. nil означает,
+// что условия нет (заголовок не передан или равен "*"). Слабые и чужие
+// ETag не совпадают ни с одной версией, поэтому в список не попадают.
+func ifMatch(c *gin.Context) []int64 {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return nil
+	}
+
+	versions := []int64{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil
+		}
+		if version, ok := parseETag(tag); ok {
+			versions = append(versions, version)
+		}
+	}
+
+	return versions
+}
+
+// notModified отвечает 304, если ETag задачи совпадает с заголовком
+// If-None-Match. Сравнение слабое: префикс W/ не учитывается.
+func notModified(c *gin.Context, task models.Task) bool {
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if version, ok := parseETag(tag); tag == "*" || (ok && version == task.Version) {
+			setETag(c, task)
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseETag(tag string) (int64, bool) {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+
+	return version, true
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"todo-api/internal/apperr"
+	"todo-api/internal/models"
+	"todo-api/internal/service"
+)
+
+// keepAliveInterval - как часто поток без событий отправляет комментарий SSE
+// или ping WebSocket, чтобы прокси не закрывали соединение
+const keepAliveInterval = 30 * time.Second
+
+// upgrader принимает соединения с любых источников: доступ к ленте
+// проверяется по токену, а не по cookie
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+type FeedHandler struct {
+	service *service.FeedService
+}
+
+func NewFeedHandler(service *service.FeedService) *FeedHandler {
+	return &FeedHandler{service: service}
+}
+
+// StreamEvents отдает ленту изменений задач через Server-Sent Events
+// @Summary Лента изменений задач (SSE)
+// @Description Поток событий text/event-stream об изменениях видимых пользователю задач: created, updated,
+// @Description deleted (в корзину), restored, purged. У каждого события есть id; после переподключения передайте
+// @Description последний полученный id в заголовке Last-Event-ID или параметре last_event_id, чтобы получить
+// @Description пропущенные события. Если они уже недоступны, первым приходит событие reset: состояние задач
+// @Description нужно запросить заново. Фильтры пропускают событие, если задача подходила под них до или после
+// @Description изменения. Токен можно передать в параметре access_token
+// @Tags tasks
+// @Security BearerAuth
+// @Produce text/event-stream
+// @Param completed query bool false "Только задачи с указанным статусом выполнения"
+// @Param tag query string false "Только задачи с тегом"
+// @Param list_id query string false "Только задачи списка"
+// @Param last_event_id query int false "ID последнего полученного события"
+// @Param Last-Event-ID header int false "ID последнего полученного события"
+// @Success 200 {object} models.ChangeEvent
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Router /tasks/events [get]
+func (h *FeedHandler) StreamEvents(c *gin.Context) {
+	sub, ok := h.subscribe(c)
+	if !ok {
+		return
+	}
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	ctx := c.Request.Context()
+	for {
+		event, err := nextEvent(ctx, sub)
+		if errors.Is(err, context.DeadlineExceeded) {
+			fmt.Fprint(c.Writer, ": keep-alive\n\n")
+			c.Writer.Flush()
+			continue
+		}
+		if err != nil {
+			return
+		}
+
+		data, err := json.Marshal(event)
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+		c.Writer.Flush()
+	}
+}
+
+// StreamEventsWS отдает ленту изменений задач через WebSocket
+// @Summary Лента изменений задач (WebSocket)
+// @Description Те же события, что и в SSE, отправляются JSON-сообщениями по WebSocket. Параметры те же;
+// @Description ID последнего полученного события передается в last_event_id
+// @Tags tasks
+// @Security BearerAuth
+// @Param completed query bool false "Только задачи с указанным статусом выполнения"
+// @Param tag query string false "Только задачи с тегом"
+// @Param list_id query string false "Только задачи списка"
+// @Param last_event_id query int false "ID последнего полученного события"
+// @Success 101 {object} models.ChangeEvent
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Router /tasks/events/ws [get]
+func (h *FeedHandler) StreamEventsWS(c *gin.Context) {
+	sub, ok := h.subscribe(c)
+	if !ok {
+		return
+	}
+	defer sub.Close()
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade уже ответил клиенту
+		return
+	}
+	defer conn.Close()
+
+	// Клиент ничего не присылает; чтение нужно, чтобы заметить закрытие соединения
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		event, err := nextEvent(ctx, sub)
+		if errors.Is(err, context.DeadlineExceeded) {
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second)); err != nil {
+				return
+			}
+			continue
+		}
+		if errors.Is(err, service.ErrFeedClosed) {
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, err.Error()))
+			return
+		}
+		if err != nil {
+			return
+		}
+
+		if err := conn.WriteJSON(event); err != nil {
+			return
+		}
+	}
+}
+
+// nextEvent ждёт следующее событие не дольше keepAliveInterval; по его
+// истечении возвращает context.DeadlineExceeded
+func nextEvent(ctx context.Context, sub *service.FeedSubscription) (models.ChangeEvent, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, keepAliveInterval)
+	defer cancel()
+
+	event, err := sub.Next(waitCtx)
+	if ctx.Err() != nil {
+		return models.ChangeEvent{}, ctx.Err()
+	}
+	return event, err
+}
+
+// subscribe разбирает фильтры и last-event-id и подписывает пользователя на
+// ленту. При ошибке отвечает клиенту и возвращает false.
+func (h *FeedHandler) subscribe(c *gin.Context) (*service.FeedSubscription, bool) {
+	var filter models.ChangeFilter
+	if completedStr := c.Query("completed"); completedStr != "" {
+		completed, err := strconv.ParseBool(completedStr)
+		if err != nil {
+			respondValidationError(c, apperr.Field("completed", "must be a boolean"))
+			return nil, false
+		}
+		filter.Completed = &completed
+	}
+	filter.Tag = c.Query("tag")
+	filter.ListID = c.Query("list_id")
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	var lastID int64
+	if lastEventID != "" {
+		var err error
+		if lastID, err = strconv.ParseInt(lastEventID, 10, 64); err != nil || lastID < 0 {
+			// Заголовок Last-Event-ID и параметр - одно и то же значение
+			respondValidationError(c, apperr.Field("last_event_id", "must be a non-negative integer"))
+			return nil, false
+		}
+	}
+
+	sub, err := h.service.Subscribe(c.Request.Context(), currentUserID(c), filter, lastID)
+	if err != nil {
+		respondServiceError(c, err, "Failed to subscribe to changes")
+		return nil, false
+	}
+
+	return sub, true
+}
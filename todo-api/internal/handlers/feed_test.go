@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"todo-api/internal/feed"
+	"todo-api/internal/models"
+	"todo-api/internal/service"
+	"todo-api/internal/storage"
+)
+
+func TestStreamEventsQueryErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewFeedHandler(service.NewFeedService(feed.NewHub(10), storage.NewMemoryStorage()))
+
+	router := gin.New()
+	router.GET("/tasks/events", func(c *gin.Context) { c.Set(userIDKey, "user") }, handler.StreamEvents)
+
+	for _, tc := range []struct {
+		target, lastEventID, field string
+	}{
+		{"/tasks/events?completed=maybe", "", "completed"},
+		{"/tasks/events?last_event_id=-1", "", "last_event_id"},
+		{"/tasks/events", "first", "last_event_id"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+		if tc.lastEventID != "" {
+			req.Header.Set("Last-Event-ID", tc.lastEventID)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		// Ошибки параметров ленты - такие же problem+json с полем, как у
+		// остальных обработчиков
+		var problem models.Problem
+		if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+			t.Fatalf("%s: decode problem %s: %v", tc.target, w.Body, err)
+		}
+		if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != models.ProblemContentType ||
+			len(problem.Errors) != 1 || problem.Errors[0].Field != tc.field {
+			t.Errorf("%s (Last-Event-ID %q): %d %+v, want 400 with field %s", tc.target, tc.lastEventID, w.Code, problem, tc.field)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTaskHistory возвращает историю изменений задачи
+// @Summary История изменений задачи
+// @Description Возвращает записи о всех изменениях задачи от новых к старым: кто и когда создал, изменил, удалил
+// @Description или восстановил задачу, и какие поля изменились (значения до и после). История доступна и для задачи в корзине
+// @Tags tasks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "ID задачи"
+// @Param limit query int false "Лимит (по умолчанию 20, не больше 100)"
+// @Param offset query int false "Смещение (по умолчанию 0)"
+// @Success 200 {object} models.HistoryResponse
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /tasks/{id}/history [get]
+func (h *TodoHandler) GetTaskHistory(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+
+	response, err := h.service.GetTaskHistory(c.Request.Context(), currentUserID(c), c.Param("id"), limit, offset)
+	if err != nil {
+		respondServiceError(c, err, "Failed to get task history")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
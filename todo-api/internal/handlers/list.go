@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"todo-api/internal/models"
+	"todo-api/internal/service"
+)
+
+type ListHandler struct {
+	service *service.ListService
+}
+
+func NewListHandler(service *service.ListService) *ListHandler {
+	return &ListHandler{
+		service: service,
+	}
+}
+
+// GetLists возвращает списки пользователя
+// @Summary Получить списки
+// @Description Возвращает списки, в которых пользователь участвует, с его ролью
+// @Tags lists
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.List
+// @Failure 401 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /lists [get]
+func (h *ListHandler) GetLists(c *gin.Context) {
+	lists, err := h.service.GetLists(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		respondInternalError(c, err, "Failed to get lists")
+		return
+	}
+
+	c.JSON(http.StatusOK, lists)
+}
+
+// CreateList создает новый список
+// @Summary Создать список
+// @Description Создает общий список задач, создатель становится его владельцем
+// @Tags lists
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param list body models.CreateListRequest true "Данные списка"
+// @Success 201 {object} models.List
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /lists [post]
+func (h *ListHandler) CreateList(c *gin.Context) {
+	var req models.CreateListRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	list, err := h.service.CreateList(c.Request.Context(), currentUserID(c), req)
+	if err != nil {
+		respondInternalError(c, err, "Failed to create list")
+		return
+	}
+
+	c.JSON(http.StatusCreated, list)
+}
+
+// GetList возвращает список по ID
+// @Summary Получить список
+// @Description Возвращает список по его ID, если пользователь в нём участвует
+// @Tags lists
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "ID списка"
+// @Success 200 {object} models.List
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /lists/{id} [get]
+func (h *ListHandler) GetList(c *gin.Context) {
+	list, err := h.service.GetList(c.Request.Context(), currentUserID(c), c.Param("id"))
+	if err != nil {
+		respondServiceError(c, err, "Failed to get list")
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// UpdateList обновляет список
+// @Summary Обновить список
+// @Description Обновляет название и описание списка. Доступно только владельцу
+// @Tags lists
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "ID списка"
+// @Param list body models.UpdateListRequest true "Данные для обновления"
+// @Success 200 {object} models.List
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /lists/{id} [put]
+func (h *ListHandler) UpdateList(c *gin.Context) {
+	var req models.UpdateListRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	list, err := h.service.UpdateList(c.Request.Context(), currentUserID(c), c.Param("id"), req)
+	if err != nil {
+		respondServiceError(c, err, "Failed to update list")
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// DeleteList удаляет список
+// @Summary Удалить список
+// @Description Удаляет список вместе со всеми его задачами. Доступно только владельцу
+// @Tags lists
+// @Security BearerAuth
+// @Param id path string true "ID списка"
+// @Success 204
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /lists/{id} [delete]
+func (h *ListHandler) DeleteList(c *gin.Context) {
+	if err := h.service.DeleteList(c.Request.Context(), currentUserID(c), c.Param("id")); err != nil {
+		respondServiceError(c, err, "Failed to delete list")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetMembers возвращает участников списка
+// @Summary Получить участников списка
+// @Description Возвращает участников списка и их роли
+// @Tags lists
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "ID списка"
+// @Success 200 {array} models.ListMember
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /lists/{id}/members [get]
+func (h *ListHandler) GetMembers(c *gin.Context) {
+	members, err := h.service.GetMembers(c.Request.Context(), currentUserID(c), c.Param("id"))
+	if err != nil {
+		respondServiceError(c, err, "Failed to get members")
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// AddMember добавляет участника в список
+// @Summary Добавить участника
+// @Description Выдает пользователю роль editor или viewer в списке. Доступно только владельцу
+// @Tags lists
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "ID списка"
+// @Param member body models.AddMemberRequest true "Имя пользователя и роль"
+// @Success 201 {object} models.ListMember
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /lists/{id}/members [post]
+func (h *ListHandler) AddMember(c *gin.Context) {
+	var req models.AddMemberRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	member, err := h.service.AddMember(c.Request.Context(), currentUserID(c), c.Param("id"), req)
+	if err != nil {
+		respondServiceError(c, err, "Failed to add member")
+		return
+	}
+
+	c.JSON(http.StatusCreated, member)
+}
+
+// UpdateMember меняет роль участника
+// @Summary Изменить роль участника
+// @Description Меняет роль участника списка. Доступно только владельцу
+// @Tags lists
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "ID списка"
+// @Param user_id path string true "ID участника"
+// @Param member body models.UpdateMemberRequest true "Новая роль"
+// @Success 200 {object} models.ListMember
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /lists/{id}/members/{user_id} [put]
+func (h *ListHandler) UpdateMember(c *gin.Context) {
+	var req models.UpdateMemberRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	member, err := h.service.UpdateMember(c.Request.Context(), currentUserID(c), c.Param("id"), c.Param("user_id"), req)
+	if err != nil {
+		respondServiceError(c, err, "Failed to update member")
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+// RemoveMember исключает участника из списка
+// @Summary Исключить участника
+// @Description Владелец может исключить любого участника, остальные могут только выйти из списка сами
+// @Tags lists
+// @Security BearerAuth
+// @Param id path string true "ID списка"
+// @Param user_id path string true "ID участника"
+// @Success 204
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /lists/{id}/members/{user_id} [delete]
+func (h *ListHandler) RemoveMember(c *gin.Context) {
+	if err := h.service.RemoveMember(c.Request.Context(), currentUserID(c), c.Param("id"), c.Param("user_id")); err != nil {
+		respondServiceError(c, err, "Failed to remove member")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(CORS(CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{"GET", "PATCH"},
+		AllowedHeaders: []string{"Authorization", "If-Match"},
+		MaxAge:         10 * time.Minute,
+	}))
+	router.NoRoute(NoRoute)
+	router.GET("/tasks", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	serve := func(method, origin string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/tasks", nil)
+		req.Header.Set("Origin", origin)
+		for key, value := range header {
+			req.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(http.MethodGet, "https://app.example.com", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" || w.Header().Get("Access-Control-Expose-Headers") == "" {
+		t.Errorf("allowed origin: %d %v", w.Code, w.Header())
+	}
+
+	// Предварительный запрос получает ответ до маршрутизации
+	w = serve(http.MethodOptions, "https://app.example.com", map[string]string{"Access-Control-Request-Method": "PATCH"})
+	if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Methods") != "GET, PATCH" ||
+		w.Header().Get("Access-Control-Allow-Headers") != "Authorization, If-Match" || w.Header().Get("Access-Control-Max-Age") != "600" {
+		t.Errorf("preflight: %d %v", w.Code, w.Header())
+	}
+
+	w = serve(http.MethodGet, "https://evil.example.com", nil)
+	if w.Header().Get("Access-Control-Allow-Origin") != "" || w.Header().Get("Vary") != "Origin" {
+		t.Errorf("disallowed origin: %v", w.Header())
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	limiter := newRateLimiter(2, 3)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	for i := range 3 {
+		if ok, _ := limiter.allow("10.0.0.1"); !ok {
+			t.Fatalf("request %d within burst was limited", i+1)
+		}
+	}
+	ok, wait := limiter.allow("10.0.0.1")
+	if ok || wait != 500*time.Millisecond {
+		t.Errorf("request over burst = %v, wait %v, want limited for 500ms", ok, wait)
+	}
+	// У другого адреса своя корзина
+	if ok, _ := limiter.allow("10.0.0.2"); !ok {
+		t.Error("request from another address was limited")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := limiter.allow("10.0.0.1"); !ok {
+		t.Error("request after refill was limited")
+	}
+
+	// Полные корзины удаляются
+	now = now.Add(time.Hour)
+	limiter.allow("10.0.0.3")
+	if len(limiter.buckets) != 1 {
+		t.Errorf("buckets after sweep = %d, want 1", len(limiter.buckets))
+	}
+}
+
+func TestRateLimitResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/tasks", RateLimit(1, 1), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	var w *httptest.ResponseRecorder
+	for range 2 {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/tasks", nil))
+	}
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("second request = %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimiter - корзины токенов по адресам клиентов. Корзина вмещает burst
+// токенов и пополняется со скоростью rate токенов в секунду; запрос забирает
+// один токен.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	swept   time.Time
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		now:     time.Now,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow забирает токен из корзины key. Если токена нет, возвращает false и
+// время, через которое он появится.
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, updated: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = min(l.burst, bucket.tokens+now.Sub(bucket.updated).Seconds()*l.rate)
+	bucket.updated = now
+
+	if bucket.tokens < 1 {
+		return false, time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// sweep раз в минуту удаляет полные корзины: они ничем не отличаются от
+// новых, а без удаления карта росла бы с каждым новым адресом
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < time.Minute {
+		return
+	}
+	l.swept = now
+	for key, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.updated).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// RateLimit ограничивает частоту запросов с одного адреса клиента: в
+// среднем rate запросов в секунду и до burst подряд. Сверх лимита отвечает
+// 429 с заголовком Retry-After. Нулевой rate не ограничивает запросы.
+func RateLimit(rate float64, burst int) gin.HandlerFunc {
+	if rate <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	limiter := newRateLimiter(rate, burst)
+	return func(c *gin.Context) {
+		if ok, wait := limiter.allow(c.ClientIP()); !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			abortWithError(c, http.StatusTooManyRequests, "Rate limit exceeded, retry later")
+			return
+		}
+		c.Next()
+	}
+}
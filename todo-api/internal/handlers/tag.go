@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"todo-api/internal/models"
+)
+
+// GetTags возвращает каталог тегов пользователя
+// @Summary Получить теги
+// @Description Возвращает теги пользователя в алфавитном порядке
+// @Tags tags
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.Tag
+// @Failure 401 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /tags [get]
+func (h *TodoHandler) GetTags(c *gin.Context) {
+	tags, err := h.service.GetTags(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		respondInternalError(c, err, "Failed to get tags")
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
+// CreateTag добавляет тег в каталог
+// @Summary Создать тег
+// @Description Добавляет тег в каталог пользователя. Имя приводится к нижнему регистру
+// @Tags tags
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param tag body models.CreateTagRequest true "Данные тега"
+// @Success 201 {object} models.Tag
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /tags [post]
+func (h *TodoHandler) CreateTag(c *gin.Context) {
+	var req models.CreateTagRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	tag, err := h.service.CreateTag(c.Request.Context(), currentUserID(c), req)
+	if err != nil {
+		respondServiceError(c, err, "Failed to create tag")
+		return
+	}
+
+	c.JSON(http.StatusCreated, tag)
+}
+
+// UpdateTag меняет тег
+// @Summary Обновить тег
+// @Description Меняет цвет тега и, если передано имя, переименовывает тег во всех задачах пользователя
+// @Tags tags
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param name path string true "Имя тега"
+// @Param tag body models.UpdateTagRequest true "Данные для обновления"
+// @Success 200 {object} models.Tag
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /tags/{name} [put]
+func (h *TodoHandler) UpdateTag(c *gin.Context) {
+	var req models.UpdateTagRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	tag, err := h.service.UpdateTag(c.Request.Context(), currentUserID(c), c.Param("name"), req)
+	if err != nil {
+		respondServiceError(c, err, "Failed to update tag")
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// DeleteTag удаляет тег
+// @Summary Удалить тег
+// @Description Удаляет тег из каталога и из всех задач пользователя
+// @Tags tags
+// @Security BearerAuth
+// @Param name path string true "Имя тега"
+// @Success 204 "No Content"
+// @Failure 401 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /tags/{name} [delete]
+func (h *TodoHandler) DeleteTag(c *gin.Context) {
+	if err := h.service.DeleteTag(c.Request.Context(), currentUserID(c), c.Param("name")); err != nil {
+		respondServiceError(c, err, "Failed to delete tag")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetTagCounts возвращает число задач по тегам
+// @Summary Получить число задач по тегам
+// @Description Считает видимые пользователю задачи по тегам, например для боковой панели.
+// @Description Фильтры совпадают с фильтрами списка задач
+// @Tags tags
+// @Security BearerAuth
+// @Produce json
+// @Param completed query bool false "Фильтр по статусу выполнения"
+// @Param list_id query string false "Только задачи указанного списка"
+// @Param tags query string false "Теги через запятую"
+// @Param tags_mode query string false "any - хотя бы один из тегов (по умолчанию), all - все теги"
+// @Success 200 {array} models.TagCount
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /tags/counts [get]
+func (h *TodoHandler) GetTagCounts(c *gin.Context) {
+	query := models.TaskQuery{ListID: c.Query("list_id")}
+	if completedStr := c.Query("completed"); completedStr != "" {
+		if completed, err := strconv.ParseBool(completedStr); err == nil {
+			query.Completed = &completed
+		}
+	}
+	if !tagsQuery(c, &query) {
+		return
+	}
+
+	counts, err := h.service.GetTagCounts(c.Request.Context(), currentUserID(c), query)
+	if err != nil {
+		respondServiceError(c, err, "Failed to count tags")
+		return
+	}
+
+	c.JSON(http.StatusOK, counts)
+}
@@ -1,11 +1,15 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"todo-api/internal/apperr"
 	"todo-api/internal/models"
 	"todo-api/internal/service"
 )
@@ -24,45 +28,74 @@ func NewTodoHandler(service *service.TodoService) *TodoHandler {
 // @Summary Создать новую задачу
 // @Description Создает новую задачу с указанным заголовком и описанием
 // @Tags tasks
+// @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param task body models.CreateTaskRequest true "Данные для создания задачи"
 // @Success 201 {object} models.Task
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Header 201 {string} ETag "Версия задачи"
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /tasks [post]
 func (h *TodoHandler) CreateTask(c *gin.Context) {
 	var req models.CreateTaskRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	task, err := h.service.CreateTask(req)
+	task, err := h.service.CreateTask(c.Request.Context(), currentUserID(c), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
+		respondServiceError(c, err, "Failed to create task")
 		return
 	}
 
+	setETag(c, task)
 	c.JSON(http.StatusCreated, task)
 }
 
 // GetTasks возвращает список задач с пагинацией и фильтрацией
 // @Summary Получить список задач
-// @Description Возвращает список задач с поддержкой пагинации, фильтрации и поиска
+// @Description Возвращает список задач с поддержкой пагинации, фильтрации и поиска.
+// @Description Страницы можно получать по смещению (offset) или по курсорам next_cursor и prev_cursor,
+// @Description которые не дают пропусков и повторов, если задачи добавляются между запросами
 // @Tags tasks
+// @Security BearerAuth
 // @Accept json
 // @Produce json
-// @Param limit query int false "Лимит (по умолчанию 10)"
+// @Param limit query int false "Лимит (по умолчанию 10, не больше 100)"
 // @Param offset query int false "Смещение (по умолчанию 0)"
+// @Param cursor query string false "Курсор страницы из next_cursor или prev_cursor предыдущего ответа; заменяет offset, sort_by должен совпадать"
 // @Param completed query bool false "Фильтр по статусу выполнения"
-// @Param search query string false "Поиск по заголовку и описанию"
-// @Param sort_by query string false "Поле для сортировки (created_at, completed)"
-// @Param sort_order query string false "Порядок сортировки (asc, desc)"
+// @Param due_after query string false "Срок выполнения не раньше (RFC 3339)"
+// @Param due_before query string false "Срок выполнения раньше (RFC 3339)"
+// @Param overdue query bool false "Только невыполненные задачи с истёкшим сроком"
+// @Param list_id query string false "Только задачи указанного списка"
+// @Param tags query string false "Теги через запятую"
+// @Param tags_mode query string false "any - хотя бы один из тегов (по умолчанию), all - все теги"
+// @Param search query string false "Полнотекстовый поиск по заголовку и описанию с учетом словоформ; фразы в двойных кавычках ищутся целиком"
+// @Param sort_by query string false "Ключи сортировки через запятую с необязательным направлением: created_at, updated_at, completed, due_at, priority, title, relevance (только с search, по умолчанию по убыванию; например, priority:desc,due_at:asc,title)"
+// @Param sort_order query string false "Направление для ключей без суффикса (asc, desc; по умолчанию asc). Без sort_by задачи сортируются по created_at:desc"
 // @Success 200 {object} models.TasksResponse
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /tasks [get]
 func (h *TodoHandler) GetTasks(c *gin.Context) {
+	query, ok := taskQuery(c)
+	if !ok {
+		return
+	}
+
+	h.listTasks(c, query)
+}
+
+// taskQuery разбирает параметры списка задач. При ошибке отвечает 400 и
+// возвращает false.
+func taskQuery(c *gin.Context) (models.TaskQuery, bool) {
 	query := models.TaskQuery{}
 
 	// Параметры пагинации
@@ -89,22 +122,57 @@ func (h *TodoHandler) GetTasks(c *gin.Context) {
 		}
 	}
 
+	// Фильтры по сроку выполнения
+	var ok bool
+	if query.DueAfter, ok = timeQuery(c, "due_after"); !ok {
+		return query, false
+	}
+	if query.DueBefore, ok = timeQuery(c, "due_before"); !ok {
+		return query, false
+	}
+	if overdueStr := c.Query("overdue"); overdueStr != "" {
+		if overdue, err := strconv.ParseBool(overdueStr); err == nil {
+			query.Overdue = overdue
+		}
+	}
+
+	// Фильтр по списку
+	if listID := c.Query("list_id"); listID != "" {
+		query.ListID = listID
+	}
+
+	// Фильтр по тегам
+	if !tagsQuery(c, &query) {
+		return query, false
+	}
+
 	// Поиск
 	if search := c.Query("search"); search != "" {
 		query.Search = search
 	}
 
 	// Сортировка
-	if sortBy := c.Query("sort_by"); sortBy != "" {
-		query.SortBy = sortBy
+	sortKeys, err := service.ParseSort(c.Query("sort_by"), c.Query("sort_order"))
+	if err != nil {
+		respondValidationError(c, apperr.Field("sort_by", err.Error()))
+		return query, false
 	}
-	if sortOrder := c.Query("sort_order"); sortOrder != "" {
-		query.SortOrder = sortOrder
+	query.Sort = sortKeys
+
+	// Курсор заменяет offset
+	if query.Cursor, err = service.ParseCursor(c.Query("cursor"), query.Sort); err != nil {
+		respondValidationError(c, apperr.Field("cursor", err.Error()))
+		return query, false
 	}
 
-	response, err := h.service.GetAllTasks(query)
+	return query, true
+}
+
+// listTasks отвечает страницей задач по запросу query
+func (h *TodoHandler) listTasks(c *gin.Context, query models.TaskQuery) {
+	response, err := h.service.GetAllTasks(c.Request.Context(), currentUserID(c), query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get tasks"})
+		respondServiceError(c, err, "Failed to get tasks")
 		return
 	}
 
@@ -113,89 +181,174 @@ func (h *TodoHandler) GetTasks(c *gin.Context) {
 
 // GetTask возвращает задачу по ID
 // @Summary Получить задачу по ID
-// @Description Возвращает задачу по её уникальному идентификатору
+// @Description Возвращает задачу по её уникальному идентификатору. Версия задачи отдается в заголовке ETag;
+// @Description с If-None-Match, совпадающим с ней, ответ 304 без тела. С include=children ETag не отдается
 // @Tags tasks
+// @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param id path string true "ID задачи"
+// @Param include query string false "children - вложить все уровни подзадач"
+// @Param If-None-Match header string false "ETag ранее полученной версии задачи"
 // @Success 200 {object} models.Task
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Header 200 {string} ETag "Версия задачи"
+// @Success 304 "Not Modified"
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /tasks/{id} [get]
 func (h *TodoHandler) GetTask(c *gin.Context) {
 	id := c.Param("id")
 
-	task, err := h.service.GetTask(id)
+	// Версия задачи не меняется при изменении подзадач, поэтому дерево
+	// подзадач отдаётся без ETag
+	children := c.Query("include") == "children"
+
+	var (
+		task models.Task
+		err  error
+	)
+	if children {
+		task, err = h.service.GetTaskWithChildren(c.Request.Context(), currentUserID(c), id)
+	} else {
+		task, err = h.service.GetTask(c.Request.Context(), currentUserID(c), id)
+	}
 	if err != nil {
-		if err.Error() == "task not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
-		} else {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		}
+		respondServiceError(c, err, "Failed to get task")
 		return
 	}
 
+	if !children {
+		if notModified(c, task) {
+			return
+		}
+		setETag(c, task)
+	}
 	c.JSON(http.StatusOK, task)
 }
 
 // UpdateTask обновляет задачу
 // @Summary Обновить задачу
-// @Description Обновляет данные задачи по её ID
+// @Description Обновляет данные задачи по её ID. С If-Match задача обновляется, только если ее версия
+// @Description совпадает с одним из переданных ETag, иначе ответ 412
 // @Tags tasks
+// @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param id path string true "ID задачи"
+// @Param If-Match header string false "ETag версии задачи, которую изменяет клиент"
 // @Param task body models.UpdateTaskRequest true "Данные для обновления"
 // @Success 200 {object} models.Task
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Header 200 {string} ETag "Новая версия задачи"
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /tasks/{id} [put]
 func (h *TodoHandler) UpdateTask(c *gin.Context) {
 	id := c.Param("id")
 
 	var req models.UpdateTaskRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	task, err := h.service.UpdateTask(id, req)
+	task, err := h.service.UpdateTask(c.Request.Context(), currentUserID(c), id, req, ifMatch(c))
 	if err != nil {
-		if err.Error() == "task not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
-		} else {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		}
+		respondServiceError(c, err, "Failed to update task")
 		return
 	}
 
+	setETag(c, task)
 	c.JSON(http.StatusOK, task)
 }
 
-// DeleteTask удаляет задачу
+// PatchTask частично обновляет задачу
+// @Summary Частично обновить задачу
+// @Description Применяет к задаче JSON Merge Patch (RFC 7396, application/merge-patch+json) или JSON Patch
+// @Description (RFC 6902, application/json-patch+json). В отличие от PUT позволяет очистить поле: null в merge patch
+// @Description или операция remove. Менять можно title, description, completed, priority, parent_id, due_at,
+// @Description remind_at, recurrence и tags; остальные поля доступны только для операций test.
+// @Description Не прошедшая операция test дает ответ 409. If-Match проверяется так же, как при обновлении
+// @Tags tasks
+// @Security BearerAuth
+// @Accept application/merge-patch+json,application/json-patch+json
+// @Produce json
+// @Param id path string true "ID задачи"
+// @Param If-Match header string false "ETag версии задачи, которую изменяет клиент"
+// @Param patch body object true "Merge patch или массив операций JSON Patch"
+// @Success 200 {object} models.Task
+// @Header 200 {string} ETag "Новая версия задачи"
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 415 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /tasks/{id} [patch]
+func (h *TodoHandler) PatchTask(c *gin.Context) {
+	id := c.Param("id")
+
+	patch, err := c.GetRawData()
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	task, err := h.service.PatchTask(c.Request.Context(), currentUserID(c), id, c.ContentType(), patch, ifMatch(c))
+	if errors.Is(err, service.ErrUnsupportedPatch) {
+		c.Header("Accept-Patch", service.MergePatchType+", "+service.JSONPatchType)
+		respondError(c, http.StatusUnsupportedMediaType, "Content-Type must be "+service.MergePatchType+" or "+service.JSONPatchType)
+		return
+	}
+	if err != nil {
+		respondServiceError(c, err, "Failed to patch task")
+		return
+	}
+
+	setETag(c, task)
+	c.JSON(http.StatusOK, task)
+}
+
+// DeleteTask переносит задачу в корзину
 // @Summary Удалить задачу
-// @Description Удаляет задачу по её ID
+// @Description Переносит задачу в корзину, откуда ее можно восстановить, пока она не удалена окончательно
+// @Description (по умолчанию через 30 дней). По умолчанию подзадачи удаляются вместе с ней,
+// @Description children=detach делает их задачами верхнего уровня. If-Match проверяется так же, как при обновлении
 // @Tags tasks
+// @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param id path string true "ID задачи"
+// @Param children query string false "Что сделать с подзадачами: delete (по умолчанию) или detach"
+// @Param If-Match header string false "ETag версии задачи, которую удаляет клиент"
 // @Success 204 "No Content"
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /tasks/{id} [delete]
 func (h *TodoHandler) DeleteTask(c *gin.Context) {
 	id := c.Param("id")
 
-	err := h.service.DeleteTask(id)
+	policy := models.ChildrenPolicy(c.DefaultQuery("children", string(models.ChildrenDelete)))
+	if policy != models.ChildrenDelete && policy != models.ChildrenDetach {
+		respondValidationError(c, apperr.Field("children", "must be delete or detach"))
+		return
+	}
+
+	err := h.service.DeleteTask(c.Request.Context(), currentUserID(c), id, policy, ifMatch(c))
 	if err != nil {
-		if err.Error() == "task not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
-		} else {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		}
+		respondServiceError(c, err, "Failed to delete task")
 		return
 	}
 
@@ -204,28 +357,104 @@ func (h *TodoHandler) DeleteTask(c *gin.Context) {
 
 // CompleteTask отмечает задачу как выполненную
 // @Summary Отметить задачу как выполненную
-// @Description Устанавливает статус выполнения задачи в true
+// @Description Устанавливает статус выполнения задачи в true. Для повторяющейся задачи создает следующее вхождение серии.
+// @Description Задачу с невыполненными блокирующими задачами выполнить нельзя: ответ 409 со списком blocked_by.
+// @Description If-Match проверяется так же, как при обновлении
 // @Tags tasks
+// @Security BearerAuth
 // @Accept json
 // @Produce json
 // @Param id path string true "ID задачи"
+// @Param If-Match header string false "ETag версии задачи, которую изменяет клиент"
 // @Success 200 {object} models.Task
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Header 200 {string} ETag "Новая версия задачи"
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 412 {object} models.Problem
+// @Failure 500 {object} models.Problem
 // @Router /tasks/{id}/complete [patch]
 func (h *TodoHandler) CompleteTask(c *gin.Context) {
 	id := c.Param("id")
 
-	task, err := h.service.CompleteTask(id)
+	task, err := h.service.CompleteTask(c.Request.Context(), currentUserID(c), id, ifMatch(c))
 	if err != nil {
-		if err.Error() == "task not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
-		} else {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		}
+		respondServiceError(c, err, "Failed to complete task")
 		return
 	}
 
+	setETag(c, task)
 	c.JSON(http.StatusOK, task)
 }
+
+// GetOccurrences возвращает ближайшие сроки повторяющейся задачи
+// @Summary Предпросмотр повторений задачи
+// @Description Возвращает ближайшие сроки задачи по её правилу повторения, начиная с текущего due_at
+// @Tags tasks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "ID задачи"
+// @Param limit query int false "Количество сроков (по умолчанию 5, не больше 100)"
+// @Success 200 {object} models.OccurrencesResponse
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Router /tasks/{id}/occurrences [get]
+func (h *TodoHandler) GetOccurrences(c *gin.Context) {
+	id := c.Param("id")
+
+	limit := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if value, err := strconv.Atoi(limitStr); err == nil && value > 0 {
+			limit = value
+		}
+	}
+
+	response, err := h.service.GetOccurrences(c.Request.Context(), currentUserID(c), id, limit)
+	if err != nil {
+		respondServiceError(c, err, "Failed to get occurrences")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// timeQuery разбирает необязательный параметр запроса в формате RFC 3339.
+// При ошибке отвечает 400 и возвращает false.
+func timeQuery(c *gin.Context, name string) (*time.Time, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, true
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		respondValidationError(c, apperr.Field(name, "must be an RFC 3339 timestamp"))
+		return nil, false
+	}
+
+	return &t, true
+}
+
+// tagsQuery разбирает параметры tags и tags_mode в query.
+// При ошибке отвечает 400 и возвращает false.
+func tagsQuery(c *gin.Context, query *models.TaskQuery) bool {
+	switch mode := c.DefaultQuery("tags_mode", "any"); mode {
+	case "any":
+	case "all":
+		query.AllTags = true
+	default:
+		respondValidationError(c, apperr.Field("tags_mode", "must be any or all"))
+		return false
+	}
+
+	for _, tag := range strings.Split(c.Query("tags"), ",") {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			query.Tags = append(query.Tags, tag)
+		}
+	}
+
+	return true
+}
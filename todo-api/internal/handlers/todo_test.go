@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"todo-api/internal/auth"
+	"todo-api/internal/models"
+	"todo-api/internal/service"
+	"todo-api/internal/storage"
+)
+
+// testAPI - маршруты задач поверх MemoryStorage с настоящей проверкой токенов
+type testAPI struct {
+	t      *testing.T
+	router *gin.Engine
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	keyset, err := auth.GenerateKeyset()
+	if err != nil {
+		t.Fatalf("GenerateKeyset() error = %v", err)
+	}
+	store := storage.NewMemoryStorage()
+	authHandler := NewAuthHandler(service.NewAuthService(store, keyset, service.TokenConfig{
+		AccessTTL:  time.Minute,
+		RefreshTTL: time.Hour,
+	}))
+	todoHandler := NewTodoHandler(service.NewTodoService(store))
+
+	router := gin.New()
+	router.POST("/auth/register", authHandler.Register)
+	router.POST("/auth/login", authHandler.Login)
+	tasks := router.Group("/tasks", authHandler.BearerAuth())
+	tasks.GET("", todoHandler.GetTasks)
+	tasks.POST("", todoHandler.CreateTask)
+	tasks.GET("/:id", todoHandler.GetTask)
+	tasks.PUT("/:id", todoHandler.UpdateTask)
+	tasks.PATCH("/:id", todoHandler.PatchTask)
+	tasks.DELETE("/:id", todoHandler.DeleteTask)
+	tasks.PATCH("/:id/complete", todoHandler.CompleteTask)
+
+	return &testAPI{t: t, router: router}
+}
+
+// do выполняет запрос с токеном token и разбирает JSON ответа в out, если он не nil
+func (api *testAPI) do(method, path, token, contentType, body string, out any) int {
+	api.t.Helper()
+
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	api.router.ServeHTTP(w, req)
+
+	if out != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			api.t.Fatalf("%s %s: decode %s: %v", method, path, w.Body, err)
+		}
+	}
+	return w.Code
+}
+
+// signUp регистрирует пользователя и возвращает его токен доступа
+func (api *testAPI) signUp(username string) string {
+	api.t.Helper()
+
+	credentials := `{"username": "` + username + `", "password": "password-` + username + `"}`
+	if status := api.do(http.MethodPost, "/auth/register", "", "application/json", credentials, nil); status != http.StatusCreated {
+		api.t.Fatalf("register %s: status = %d", username, status)
+	}
+	var tokens models.TokenResponse
+	if status := api.do(http.MethodPost, "/auth/login", "", "application/json", credentials, &tokens); status != http.StatusOK {
+		api.t.Fatalf("login %s: status = %d", username, status)
+	}
+	return tokens.AccessToken
+}
+
+func TestTaskOwnership(t *testing.T) {
+	api := newTestAPI(t)
+	alice := api.signUp("alice")
+	bob := api.signUp("bob")
+
+	var task models.Task
+	if status := api.do(http.MethodPost, "/tasks", alice, "application/json", `{"title": "Задача Алисы"}`, &task); status != http.StatusCreated {
+		t.Fatalf("create task: status = %d", status)
+	}
+	path := "/tasks/" + task.ID
+
+	// Чужая задача для Боба не существует: ответ не выдает, что она есть
+	for _, tc := range []struct {
+		method, path, contentType, body string
+	}{
+		{http.MethodGet, path, "", ""},
+		{http.MethodPut, path, "application/json", `{"title": "Задача Боба"}`},
+		{http.MethodPatch, path, "application/merge-patch+json", `{"title": "Задача Боба"}`},
+		{http.MethodPatch, path + "/complete", "", ""},
+		{http.MethodDelete, path, "", ""},
+	} {
+		if status := api.do(tc.method, tc.path, bob, tc.contentType, tc.body, nil); status != http.StatusNotFound {
+			t.Errorf("%s %s by another user: status = %d, want 404", tc.method, tc.path, status)
+		}
+	}
+
+	var list models.TasksResponse
+	if status := api.do(http.MethodGet, "/tasks", bob, "", "", &list); status != http.StatusOK || list.Total != 0 {
+		t.Errorf("GET /tasks by another user: status = %d, total = %d, want 200 and no tasks", status, list.Total)
+	}
+
+	// Попытки Боба не изменили задачу
+	var got models.Task
+	if status := api.do(http.MethodGet, path, alice, "", "", &got); status != http.StatusOK {
+		t.Fatalf("GET %s by owner: status = %d", path, status)
+	}
+	if got.Title != task.Title || got.Completed || got.Version != task.Version {
+		t.Errorf("task after another user's requests = %+v, want %+v", got, task)
+	}
+	if status := api.do(http.MethodGet, "/tasks", alice, "", "", &list); status != http.StatusOK || list.Total != 1 {
+		t.Errorf("GET /tasks by owner: status = %d, total = %d, want 1", status, list.Total)
+	}
+
+	if status := api.do(http.MethodGet, path, "", "", "", nil); status != http.StatusUnauthorized {
+		t.Errorf("GET %s without a token: status = %d, want 401", path, status)
+	}
+}
+
+func TestGetTasksLimit(t *testing.T) {
+	api := newTestAPI(t)
+	token := api.signUp("alice")
+
+	for limit, want := range map[string]int{
+		"":                    10,
+		"5":                   5,
+		"500":                 service.MaxTaskLimit,
+		"9223372036854775807": service.MaxTaskLimit,
+	} {
+		var response models.TasksResponse
+		if status := api.do(http.MethodGet, "/tasks?limit="+limit, token, "", "", &response); status != http.StatusOK {
+			t.Fatalf("GET /tasks?limit=%s: status = %d", limit, status)
+		}
+		if response.Limit != want {
+			t.Errorf("GET /tasks?limit=%s: limit = %d, want %d", limit, response.Limit, want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"todo-api/internal/storage"
+)
+
+// GetTrash возвращает задачи из корзины
+// @Summary Получить корзину
+// @Description Возвращает удаленные задачи, которые еще можно восстановить. Параметры пагинации, фильтрации,
+// @Description поиска и сортировки те же, что у списка задач; у каждой задачи заполнено deleted_at
+// @Tags tasks
+// @Security BearerAuth
+// @Produce json
+// @Param limit query int false "Лимит (по умолчанию 10, не больше 100)"
+// @Param offset query int false "Смещение (по умолчанию 0)"
+// @Param cursor query string false "Курсор страницы из next_cursor или prev_cursor предыдущего ответа"
+// @Param completed query bool false "Фильтр по статусу выполнения"
+// @Param list_id query string false "Только задачи указанного списка"
+// @Param tags query string false "Теги через запятую"
+// @Param search query string false "Полнотекстовый поиск по заголовку и описанию"
+// @Param sort_by query string false "Ключи сортировки через запятую, как у списка задач"
+// @Param sort_order query string false "Направление для ключей без суффикса (asc, desc)"
+// @Success 200 {object} models.TasksResponse
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /tasks/trash [get]
+func (h *TodoHandler) GetTrash(c *gin.Context) {
+	query, ok := taskQuery(c)
+	if !ok {
+		return
+	}
+	query.Deleted = true
+
+	h.listTasks(c, query)
+}
+
+// RestoreTask восстанавливает задачу из корзины
+// @Summary Восстановить задачу
+// @Description Возвращает задачу из корзины вместе с подзадачами, удаленными одновременно с ней.
+// @Description Подзадачу нельзя восстановить, пока ее родитель в корзине: ответ 409
+// @Tags tasks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "ID задачи"
+// @Success 200 {object} models.Task
+// @Header 200 {string} ETag "Новая версия задачи"
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 403 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /tasks/{id}/restore [post]
+func (h *TodoHandler) RestoreTask(c *gin.Context) {
+	task, err := h.service.RestoreTask(c.Request.Context(), currentUserID(c), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, storage.ErrTaskNotFound) {
+			respondError(c, http.StatusNotFound, "Task not found in trash")
+		} else {
+			respondServiceError(c, err, "Failed to restore task")
+		}
+		return
+	}
+
+	setETag(c, task)
+	c.JSON(http.StatusOK, task)
+}
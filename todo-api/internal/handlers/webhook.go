@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"todo-api/internal/models"
+	"todo-api/internal/service"
+)
+
+type WebhookHandler struct {
+	service *service.WebhookService
+}
+
+func NewWebhookHandler(service *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		service: service,
+	}
+}
+
+// GetWebhooks возвращает вебхуки пользователя
+// @Summary Получить вебхуки
+// @Description Возвращает вебхуки пользователя в порядке создания. Ключ подписи не отдается
+// @Tags webhooks
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.Webhook
+// @Failure 401 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /webhooks [get]
+func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
+	webhooks, err := h.service.GetWebhooks(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		respondInternalError(c, err, "Failed to get webhooks")
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// CreateWebhook создает вебхук
+// @Summary Создать вебхук
+// @Description Подписывает URL на события задач пользователя и задач списков, в которых он участвует:
+// @Description task.created, task.updated, task.completed, task.deleted (без events - на все). Сервер
+// @Description отправляет POST с JSON-телом и заголовками X-Webhook-Event, X-Webhook-Delivery и
+// @Description X-Webhook-Signature: "sha256=" и HMAC-SHA256 тела в hex с ключом secret. Ключ отдается только
+// @Description в этом ответе; если он не передан, сервер создает его сам. Ответ не 2xx считается неудачей:
+// @Description попытка повторяется с растущей вдвое задержкой, а после последней доставка попадает в список
+// @Description недоставленных
+// @Tags webhooks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param webhook body models.CreateWebhookRequest true "Данные вебхука"
+// @Success 201 {object} models.Webhook
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req models.CreateWebhookRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	webhook, err := h.service.CreateWebhook(c.Request.Context(), currentUserID(c), req)
+	if err != nil {
+		respondServiceError(c, err, "Failed to create webhook")
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// GetWebhook возвращает вебхук по ID
+// @Summary Получить вебхук
+// @Description Возвращает вебхук пользователя по его ID
+// @Tags webhooks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "ID вебхука"
+// @Success 200 {object} models.Webhook
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /webhooks/{id} [get]
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	webhook, err := h.service.GetWebhook(c.Request.Context(), currentUserID(c), c.Param("id"))
+	if err != nil {
+		respondServiceError(c, err, "Failed to get webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// UpdateWebhook обновляет вебхук
+// @Summary Обновить вебхук
+// @Description Меняет URL, события или активность вебхука; не переданные поля не меняются. Неактивный
+// @Description вебхук не получает новых событий, а уже поставленные в очередь попадают в список недоставленных
+// @Tags webhooks
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "ID вебхука"
+// @Param webhook body models.UpdateWebhookRequest true "Данные для обновления"
+// @Success 200 {object} models.Webhook
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /webhooks/{id} [put]
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	var req models.UpdateWebhookRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	webhook, err := h.service.UpdateWebhook(c.Request.Context(), currentUserID(c), c.Param("id"), req)
+	if err != nil {
+		respondServiceError(c, err, "Failed to update webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteWebhook удаляет вебхук
+// @Summary Удалить вебхук
+// @Description Удаляет вебхук вместе с журналом его доставок
+// @Tags webhooks
+// @Security BearerAuth
+// @Param id path string true "ID вебхука"
+// @Success 204
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	if err := h.service.DeleteWebhook(c.Request.Context(), currentUserID(c), c.Param("id")); err != nil {
+		respondServiceError(c, err, "Failed to delete webhook")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetDeliveries возвращает журнал доставок вебхука
+// @Summary Журнал доставок вебхука
+// @Description Возвращает доставки событий вебхуку от новых к старым: состояние, число попыток, статус
+// @Description и ошибку последней попытки, время следующей
+// @Tags webhooks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "ID вебхука"
+// @Param status query string false "Только доставки в состоянии" Enums(pending, delivered, dead)
+// @Param limit query int false "Лимит (по умолчанию 20, не больше 100)"
+// @Param offset query int false "Смещение (по умолчанию 0)"
+// @Success 200 {object} models.DeliveriesResponse
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	status := models.DeliveryStatus(c.Query("status"))
+	switch status {
+	case "", models.DeliveryPending, models.DeliveryDelivered, models.DeliveryDead:
+	default:
+		respondError(c, http.StatusBadRequest, "status must be one of: pending, delivered, dead")
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+
+	response, err := h.service.GetDeliveries(c.Request.Context(), currentUserID(c), c.Param("id"), status, limit, offset)
+	if err != nil {
+		respondServiceError(c, err, "Failed to get webhook deliveries")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetDeadLetters возвращает недоставленные события
+// @Summary Недоставленные события
+// @Description Возвращает доставки всех вебхуков пользователя, для которых исчерпаны попытки, от новых к старым
+// @Tags webhooks
+// @Security BearerAuth
+// @Produce json
+// @Param limit query int false "Лимит (по умолчанию 20, не больше 100)"
+// @Param offset query int false "Смещение (по умолчанию 0)"
+// @Success 200 {object} models.DeliveriesResponse
+// @Failure 401 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /webhooks/dead-letters [get]
+func (h *WebhookHandler) GetDeadLetters(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+
+	response, err := h.service.GetDeadLetters(c.Request.Context(), currentUserID(c), limit, offset)
+	if err != nil {
+		respondInternalError(c, err, "Failed to get dead letters")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RetryDelivery повторяет недоставленное событие
+// @Summary Повторить доставку
+// @Description Возвращает недоставленное событие в очередь с обнуленным счетчиком попыток
+// @Tags webhooks
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "ID вебхука"
+// @Param delivery_id path string true "ID доставки"
+// @Success 200 {object} models.WebhookDelivery
+// @Failure 400 {object} models.Problem
+// @Failure 401 {object} models.Problem
+// @Failure 404 {object} models.Problem
+// @Failure 409 {object} models.Problem
+// @Failure 500 {object} models.Problem
+// @Router /webhooks/{id}/deliveries/{delivery_id}/retry [post]
+func (h *WebhookHandler) RetryDelivery(c *gin.Context) {
+	delivery, err := h.service.RetryDelivery(c.Request.Context(), currentUserID(c), c.Param("id"), c.Param("delivery_id"))
+	if err != nil {
+		respondServiceError(c, err, "Failed to retry delivery")
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
@@ -0,0 +1,109 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// newTestRouter возвращает роутер, который пишет JSON-лог в out; обработчик
+// пишет свою запись с контекстом запроса и возвращает ID запроса из него
+func newTestRouter(t *testing.T, out *bytes.Buffer) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	logger, err := New(out, FormatJSON, 0)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	router := gin.New()
+	router.Use(Middleware(logger))
+	router.GET("/tasks/:id", func(c *gin.Context) {
+		logger.InfoContext(c.Request.Context(), "handler")
+		c.String(http.StatusNotFound, RequestID(c.Request.Context()))
+	})
+	return router
+}
+
+// logRecords разбирает строки JSON-лога
+func logRecords(t *testing.T, out *bytes.Buffer) []map[string]any {
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("log line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestMiddlewareRequestID(t *testing.T) {
+	for name, tc := range map[string]struct {
+		header   string
+		generate bool
+	}{
+		"propagated":   {header: "req-42"},
+		"missing":      {generate: true},
+		"control char": {header: "req\n{\"level\":\"ERROR\"}", generate: true},
+		"too long":     {header: strings.Repeat("x", maxRequestIDLength+1), generate: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			router := newTestRouter(t, &out)
+
+			req := httptest.NewRequest(http.MethodGet, "/tasks/1", nil)
+			if tc.header != "" {
+				req.Header.Set(RequestIDHeader, tc.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			id := w.Header().Get(RequestIDHeader)
+			if tc.generate {
+				if _, err := uuid.Parse(id); err != nil {
+					t.Fatalf("generated request id %q is not a UUID", id)
+				}
+			} else if id != tc.header {
+				t.Fatalf("request id = %q, want %q", id, tc.header)
+			}
+			if w.Body.String() != id {
+				t.Errorf("request id in context = %q, want %q", w.Body.String(), id)
+			}
+
+			// Запись обработчика и строка о запросе несут один и тот же ID
+			records := logRecords(t, &out)
+			if len(records) != 2 {
+				t.Fatalf("log records = %v, want 2", records)
+			}
+			for _, record := range records {
+				if record["request_id"] != id {
+					t.Errorf("log record %v, want request_id %q", record, id)
+				}
+			}
+
+			access := records[1]
+			if access["msg"] != "Request" || access["level"] != "WARN" || access["route"] != "/tasks/:id" || access["status"] != float64(http.StatusNotFound) {
+				t.Errorf("request log record = %v", access)
+			}
+		})
+	}
+}
+
+func TestNewAndParseLevel(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "xml", 0); err == nil {
+		t.Error("New(xml) error = nil")
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("ParseLevel(verbose) error = nil")
+	}
+	if level, err := ParseLevel("debug"); err != nil || level.String() != "DEBUG" {
+		t.Errorf("ParseLevel(debug) = %v, %v", level, err)
+	}
+}
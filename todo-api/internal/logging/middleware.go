@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader - заголовок с ID запроса в запросе и ответе
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength ограничивает ID, пришедший от клиента
+const maxRequestIDLength = 128
+
+// Middleware назначает запросу ID и пишет строку лога о каждом запросе.
+// ID берется из заголовка X-Request-ID, если клиент или прокси его передал
+// и он состоит из допустимых символов, иначе создается новый. ID
+// возвращается в заголовке ответа и передается дальше в контексте запроса.
+func Middleware(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.New().String()
+		}
+		c.Request = c.Request.WithContext(WithRequestID(c.Request.Context(), id))
+		c.Header(RequestIDHeader, id)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+			slog.Int("size", c.Writer.Size()),
+		}
+		// Ошибки, которые обработчики приложили к ответу через c.Error
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.Any("errors", c.Errors.Errors()))
+		}
+		logger.LogAttrs(c.Request.Context(), level, "Request", attrs...)
+	}
+}
+
+// validRequestID допускает непустые ID до 128 печатных ASCII-символов без
+// пробелов, чтобы ID клиента нельзя было использовать для подделки строк лога
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
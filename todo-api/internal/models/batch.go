@@ -0,0 +1,59 @@
+package models
+
+// BatchMode определяет, как выполняется пакет операций
+type BatchMode string
+
+const (
+	// BatchAtomic применяет все операции пакета или ни одной
+	BatchAtomic BatchMode = "atomic"
+	// BatchBestEffort выполняет операции независимо друг от друга
+	BatchBestEffort BatchMode = "best_effort"
+)
+
+// BatchOperationType - вид операции пакета
+type BatchOperationType string
+
+const (
+	BatchCreate BatchOperationType = "create"
+	BatchUpdate BatchOperationType = "update"
+	BatchDelete BatchOperationType = "delete"
+)
+
+type BatchRequest struct {
+	// Mode - режим выполнения, по умолчанию atomic
+	Mode       BatchMode        `json:"mode,omitempty" binding:"omitempty,oneof=atomic best_effort"`
+	Operations []BatchOperation `json:"operations" binding:"required,min=1,max=1000,dive"`
+}
+
+// BatchOperation - одна операция пакета: создание, обновление или удаление
+// задачи с той же проверкой, что и у одиночного запроса
+type BatchOperation struct {
+	Op BatchOperationType `json:"op" binding:"required,oneof=create update delete"`
+	// ID задачи для update и delete
+	ID string `json:"id,omitempty"`
+	// Version - условие, как в заголовке If-Match, для update и delete
+	Version int64 `json:"version,omitempty"`
+	// Create - данные новой задачи для create
+	Create *CreateTaskRequest `json:"create,omitempty"`
+	// Update - изменения задачи для update
+	Update *UpdateTaskRequest `json:"update,omitempty"`
+	// Children - что сделать с подзадачами при delete
+	Children ChildrenPolicy `json:"children,omitempty" binding:"omitempty,oneof=delete detach"`
+}
+
+// BatchResult - результат операции пакета с тем же кодом, который вернул бы
+// одиночный запрос
+type BatchResult struct {
+	Index     int      `json:"index"`
+	Status    int      `json:"status"`
+	Task      *Task    `json:"task,omitempty"`
+	Error     string   `json:"error,omitempty"`
+	BlockedBy []string `json:"blocked_by,omitempty"`
+}
+
+type BatchResponse struct {
+	Mode      BatchMode     `json:"mode"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Results   []BatchResult `json:"results"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// ChangeType - вид изменения задачи в ленте изменений
+type ChangeType string
+
+const (
+	ChangeCreated ChangeType = "created"
+	ChangeUpdated ChangeType = "updated"
+	// ChangeDeleted - задача перенесена в корзину
+	ChangeDeleted  ChangeType = "deleted"
+	ChangeRestored ChangeType = "restored"
+	// ChangePurged - задача удалена окончательно
+	ChangePurged ChangeType = "purged"
+	// ChangeReset сообщает, что часть событий после last-event-id потеряна
+	// и состояние задач нужно запросить заново
+	ChangeReset ChangeType = "reset"
+)
+
+// ChangeEvent - событие ленты изменений задач
+type ChangeEvent struct {
+	// ID растёт с каждым событием; его передают в last-event-id, чтобы
+	// продолжить ленту после переподключения
+	ID   int64      `json:"id"`
+	Type ChangeType `json:"type"`
+	// Task - задача после изменения (для purged - перед удалением); нет у reset
+	Task *Task `json:"task,omitempty"`
+	// Previous - задача до изменения, нужна фильтрам, чтобы подписчик узнал
+	// и о том, что задача перестала под них подходить
+	Previous *Task     `json:"-"`
+	At       time.Time `json:"at"`
+}
+
+// ChangeFilter отбирает события ленты по состоянию задачи до или после изменения
+type ChangeFilter struct {
+	Completed *bool
+	Tag       string
+	ListID    string
+}
+
+// Match проверяет, подходит ли задача под фильтр
+func (f ChangeFilter) Match(task Task) bool {
+	if f.Completed != nil && task.Completed != *f.Completed {
+		return false
+	}
+	if f.Tag != "" && !slices.Contains(task.Tags, f.Tag) {
+		return false
+	}
+	if f.ListID != "" && task.ListID != f.ListID {
+		return false
+	}
+	return true
+}
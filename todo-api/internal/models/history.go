@@ -0,0 +1,42 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// HistoryAction - вид изменения задачи в истории
+type HistoryAction string
+
+const (
+	HistoryCreated  HistoryAction = "created"
+	HistoryUpdated  HistoryAction = "updated"
+	HistoryDeleted  HistoryAction = "deleted"
+	HistoryRestored HistoryAction = "restored"
+)
+
+// HistoryEntry - неизменяемая запись об одном изменении задачи
+type HistoryEntry struct {
+	ID      string        `json:"id"`
+	TaskID  string        `json:"task_id"`
+	ActorID string        `json:"actor_id"`
+	Action  HistoryAction `json:"action"`
+	// Changes - изменённые поля задачи; пуст для deleted и restored
+	Changes   []FieldChange `json:"changes"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// FieldChange - значение поля задачи до и после изменения в том виде, в
+// каком его отдаёт API. Отсутствующее значение означает пустое поле.
+type FieldChange struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from,omitempty" swaggertype:"object"`
+	To    json.RawMessage `json:"to,omitempty" swaggertype:"object"`
+}
+
+type HistoryResponse struct {
+	Entries []HistoryEntry `json:"entries"`
+	Total   int            `json:"total"`
+	Limit   int            `json:"limit"`
+	Offset  int            `json:"offset"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+)
+
+// Role - роль участника в общем списке задач
+type Role string
+
+const (
+	RoleViewer Role = "viewer"
+	RoleEditor Role = "editor"
+	RoleOwner  Role = "owner"
+)
+
+var roleLevels = map[Role]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleOwner:  3,
+}
+
+// Allows сообщает, даёт ли роль права не меньше, чем required
+func (r Role) Allows(required Role) bool {
+	return roleLevels[r] >= roleLevels[required] && roleLevels[r] > 0
+}
+
+type List struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	OwnerID     string    `json:"owner_id"`
+	Role        Role      `json:"role,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type ListMember struct {
+	ListID    string    `json:"list_id"`
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username,omitempty"`
+	Role      Role      `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateListRequest struct {
+	Name        string `json:"name" binding:"required,max=200"`
+	Description string `json:"description,omitempty"`
+}
+
+type UpdateListRequest struct {
+	Name        string `json:"name" binding:"max=200"`
+	Description string `json:"description,omitempty"`
+}
+
+type AddMemberRequest struct {
+	Username string `json:"username" binding:"required"`
+	Role     Role   `json:"role" binding:"required,oneof=editor viewer"`
+}
+
+type UpdateMemberRequest struct {
+	Role Role `json:"role" binding:"required,oneof=editor viewer"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "todo-api/internal/apperr"
+
+// ProblemContentType - тип содержимого ответов об ошибках
+const ProblemContentType = "application/problem+json"
+
+// Problem - ответ об ошибке в формате RFC 7807 (problem details)
+type Problem struct {
+	// Type - URI типа ошибки; about:blank означает, что тип задан кодом ответа
+	Type   string `json:"type" example:"about:blank"`
+	Title  string `json:"title" example:"Not Found"`
+	Status int    `json:"status" example:"404"`
+	// Detail - описание ошибки для человека
+	Detail string `json:"detail,omitempty" example:"task not found"`
+	// Instance - путь запроса, на который дан ответ
+	Instance string `json:"instance,omitempty" example:"/api/v1/tasks/00000000-0000-0000-0000-000000000000"`
+	// RequestID совпадает с заголовком X-Request-ID и записями лога
+	RequestID string `json:"request_id,omitempty"`
+	// Errors - ошибки отдельных полей запроса
+	Errors []apperr.FieldError `json:"errors,omitempty"`
+	// BlockedBy - невыполненные задачи, которые блокируют задачу
+	BlockedBy []string `json:"blocked_by,omitempty"`
+}
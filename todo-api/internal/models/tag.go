@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// Tag - тег из каталога пользователя. Задачи ссылаются на теги по имени.
+type Tag struct {
+	Name      string    `json:"name"`
+	Color     string    `json:"color,omitempty"`
+	OwnerID   string    `json:"owner_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateTagRequest struct {
+	Name  string `json:"name" binding:"required,max=50"`
+	Color string `json:"color,omitempty" binding:"omitempty,hexcolor"`
+}
+
+type UpdateTagRequest struct {
+	Name  string `json:"name" binding:"max=50"`
+	Color string `json:"color,omitempty" binding:"omitempty,hexcolor"`
+}
+
+// TagCount - число задач с тегом
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
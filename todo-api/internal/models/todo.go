@@ -4,38 +4,178 @@ import (
 	"time"
 )
 
+// Priority - уровень приоритета задачи: 0 - нет, 1 - низкий, 2 - средний, 3 - высокий
+type Priority int
+
+const (
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+)
+
 type Task struct {
-	ID          string    `json:"id"`
-	Title       string    `json:"title" binding:"required"`
-	Description string    `json:"description,omitempty"`
-	Completed   bool      `json:"completed"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          string     `json:"id"`
+	Title       string     `json:"title" binding:"required"`
+	Description string     `json:"description,omitempty"`
+	Completed   bool       `json:"completed"`
+	Priority    Priority   `json:"priority"`
+	OwnerID     string     `json:"owner_id"`
+	ListID      string     `json:"list_id,omitempty"`
+	ParentID    string     `json:"parent_id,omitempty"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	RemindAt    *time.Time `json:"remind_at,omitempty"`
+	RemindedAt  *time.Time `json:"reminded_at,omitempty"`
+	Recurrence  string     `json:"recurrence,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	// DeletedAt задан у задач в корзине
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// Version растёт при каждом изменении задачи и отдаётся в заголовке ETag
+	Version int64 `json:"version"`
+	// Score - релевантность задачи поисковому запросу, заполняется только при поиске
+	Score float64 `json:"score,omitempty"`
+	// Children заполняется только по запросу include=children
+	Children []Task `json:"children,omitempty"`
 }
 
 type CreateTaskRequest struct {
-	Title       string `json:"title" binding:"required,max=200"`
-	Description string `json:"description,omitempty"`
+	Title       string     `json:"title" binding:"required,max=200"`
+	Description string     `json:"description,omitempty"`
+	Priority    Priority   `json:"priority,omitempty" binding:"min=0,max=3"`
+	ListID      string     `json:"list_id,omitempty"`
+	ParentID    string     `json:"parent_id,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	RemindAt    *time.Time `json:"remind_at,omitempty"`
+	// Recurrence - правило повторения RFC 5545 (например, "FREQ=WEEKLY;BYDAY=MO"),
+	// отсчитываемое от due_at
+	Recurrence string `json:"recurrence,omitempty" example:"FREQ=WEEKLY;BYDAY=MO"`
 }
 
 type UpdateTaskRequest struct {
-	Title       string `json:"title" binding:"max=200"`
-	Description string `json:"description,omitempty"`
-	Completed   *bool  `json:"completed,omitempty"`
+	Title       string     `json:"title" binding:"max=200"`
+	Description string     `json:"description,omitempty"`
+	Completed   *bool      `json:"completed,omitempty"`
+	Priority    *Priority  `json:"priority,omitempty" binding:"omitempty,min=0,max=3"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	RemindAt    *time.Time `json:"remind_at,omitempty"`
+	// Пустая строка отменяет повторение
+	Recurrence *string `json:"recurrence,omitempty"`
+	// Пустая строка делает подзадачу задачей верхнего уровня
+	ParentID *string `json:"parent_id,omitempty"`
+	// Заменяет все теги задачи; пустой массив удаляет их
+	Tags *[]string `json:"tags,omitempty"`
 }
 
+type AddBlockerRequest struct {
+	BlockerID string `json:"blocker_id" binding:"required"`
+}
+
+// ChildrenPolicy определяет, что происходит с подзадачами при удалении задачи
+type ChildrenPolicy string
+
+const (
+	// ChildrenDelete удаляет подзадачи вместе с задачей
+	ChildrenDelete ChildrenPolicy = "delete"
+	// ChildrenDetach делает подзадачи задачами верхнего уровня
+	ChildrenDetach ChildrenPolicy = "detach"
+)
+
 type TasksResponse struct {
 	Tasks  []Task `json:"tasks"`
 	Total  int    `json:"total"`
 	Limit  int    `json:"limit"`
 	Offset int    `json:"offset"`
+	// NextCursor и PrevCursor - непрозрачные токены соседних страниц;
+	// отсутствуют, если страницы нет
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
 }
 
 type TaskQuery struct {
+	// Если заданы OwnerID или ListIDs, возвращаются только личные задачи
+	// OwnerID и задачи из списков ListIDs
+	OwnerID   string
+	ListIDs   []string
+	ListID    string
 	Limit     int
 	Offset    int
 	Completed *bool
-	Search    string
-	SortBy    string
-	SortOrder string
+	// DueAfter (включительно) и DueBefore (не включительно) ограничивают
+	// срок выполнения; задачи без срока при этом не возвращаются.
+	DueAfter  *time.Time
+	DueBefore *time.Time
+	// Overdue - только невыполненные задачи с истёкшим сроком
+	Overdue bool
+	// Tags - задачи хотя бы с одним из тегов или, если AllTags, со всеми
+	Tags    []string
+	AllTags bool
+	// Search - полнотекстовый запрос: все слова должны встречаться в
+	// заголовке или описании с учётом словоформ, фразы в кавычках - подряд
+	Search string
+	// Deleted - только задачи из корзины; без него задачи из корзины не
+	// возвращаются
+	Deleted bool
+	// Sort - ключи сортировки по убыванию значимости; пустой Sort
+	// означает DefaultSort
+	Sort []SortKey
+	// Cursor, если задан, заменяет Offset: страница начинается сразу после
+	// (или, если Cursor.Backward, заканчивается сразу перед) задачей курсора
+	Cursor *Cursor
+}
+
+// SortKeys возвращает ключи сортировки запроса с учётом значения по умолчанию
+func (q TaskQuery) SortKeys() []SortKey {
+	if len(q.Sort) == 0 {
+		return DefaultSort
+	}
+	return q.Sort
+}
+
+// Поля, по которым можно сортировать задачи
+const (
+	SortCreatedAt = "created_at"
+	SortUpdatedAt = "updated_at"
+	SortCompleted = "completed"
+	SortDueAt     = "due_at"
+	SortPriority  = "priority"
+	SortTitle     = "title"
+	// SortRelevance доступна только вместе с поисковым запросом
+	SortRelevance = "relevance"
+)
+
+// SortKey - ключ сортировки задач
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// DefaultSort - порядок задач, если ключи сортировки не заданы
+var DefaultSort = []SortKey{{Field: SortCreatedAt, Desc: true}}
+
+// Cursor - позиция в упорядоченном списке задач. Task содержит ID и
+// значения полей, по которым отсортирован список.
+type Cursor struct {
+	Task     Task
+	Backward bool
+}
+
+// OccurrencesResponse - ближайшие сроки повторяющейся задачи, начиная с текущего due_at
+type OccurrencesResponse struct {
+	TaskID      string      `json:"task_id"`
+	Recurrence  string      `json:"recurrence,omitempty"`
+	Occurrences []time.Time `json:"occurrences"`
+}
+
+// ReminderEvent - напоминание о задаче, наступившее в момент RemindAt
+type ReminderEvent struct {
+	TaskID   string     `json:"task_id"`
+	OwnerID  string     `json:"owner_id"`
+	ListID   string     `json:"list_id,omitempty"`
+	Title    string     `json:"title"`
+	DueAt    *time.Time `json:"due_at,omitempty"`
+	RemindAt time.Time  `json:"remind_at"`
+	FiredAt  time.Time  `json:"fired_at"`
 }
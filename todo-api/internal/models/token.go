@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken - запись о выданном токене обновления. Сам токен не хранится,
+// только его SHA-256. Токены одной цепочки ротаций имеют общий FamilyID.
+type RefreshToken struct {
+	TokenHash string     `json:"token_hash"`
+	UserID    string     `json:"user_id"`
+	FamilyID  string     `json:"family_id"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
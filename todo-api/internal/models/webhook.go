@@ -0,0 +1,113 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebhookEvent - событие задачи, о котором сообщает вебхук
+type WebhookEvent string
+
+const (
+	WebhookTaskCreated WebhookEvent = "task.created"
+	WebhookTaskUpdated WebhookEvent = "task.updated"
+	// WebhookTaskCompleted отправляется вместо task.updated, когда задачу
+	// отмечают выполненной
+	WebhookTaskCompleted WebhookEvent = "task.completed"
+	// WebhookTaskDeleted - задача перенесена в корзину
+	WebhookTaskDeleted WebhookEvent = "task.deleted"
+)
+
+// WebhookEvents - все события вебхуков; подписка без событий получает их все
+var WebhookEvents = []WebhookEvent{
+	WebhookTaskCreated,
+	WebhookTaskUpdated,
+	WebhookTaskCompleted,
+	WebhookTaskDeleted,
+}
+
+// Webhook - подписка пользователя на события его задач и задач списков, в
+// которых он участвует
+type Webhook struct {
+	ID      string         `json:"id"`
+	OwnerID string         `json:"owner_id"`
+	URL     string         `json:"url"`
+	Events  []WebhookEvent `json:"events"`
+	// Secret - ключ подписи HMAC-SHA256; отдается только при создании
+	Secret    string    `json:"secret,omitempty"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CreateWebhookRequest struct {
+	URL    string         `json:"url" binding:"required,url,max=2000"`
+	Events []WebhookEvent `json:"events,omitempty" binding:"dive,oneof=task.created task.updated task.completed task.deleted"`
+	// Secret - ключ подписи; если не задан, сервер сгенерирует его сам
+	Secret string `json:"secret,omitempty" binding:"omitempty,min=16,max=200"`
+}
+
+// UpdateWebhookRequest меняет только переданные поля
+type UpdateWebhookRequest struct {
+	URL    string         `json:"url,omitempty" binding:"omitempty,url,max=2000"`
+	Events []WebhookEvent `json:"events,omitempty" binding:"dive,oneof=task.created task.updated task.completed task.deleted"`
+	Active *bool          `json:"active,omitempty"`
+}
+
+// DeliveryStatus - состояние доставки события вебхуку
+type DeliveryStatus string
+
+const (
+	// DeliveryPending - доставка ждет первой или повторной попытки
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// DeliveryDead - попытки исчерпаны, доставка лежит в списке недоставленных
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+// WebhookDelivery - доставка одного события одному вебхуку
+type WebhookDelivery struct {
+	ID        string `json:"id"`
+	WebhookID string `json:"webhook_id"`
+	// EventID одинаков у доставок одного события разным вебхукам
+	EventID string       `json:"event_id"`
+	Event   WebhookEvent `json:"event"`
+	TaskID  string       `json:"task_id"`
+	// Payload - тело запроса, одинаковое во всех попытках
+	Payload  json.RawMessage `json:"payload" swaggertype:"object"`
+	Status   DeliveryStatus  `json:"status"`
+	Attempts int             `json:"attempts"`
+	// LastStatusCode - HTTP-статус ответа на последнюю попытку, 0 если ответа не было
+	LastStatusCode int    `json:"last_status_code,omitempty"`
+	LastError      string `json:"last_error,omitempty"`
+	// NextAttemptAt - время следующей попытки, есть только у pending
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// WebhookPayload - тело запроса, которое получает вебхук
+type WebhookPayload struct {
+	ID         string       `json:"id"`
+	Event      WebhookEvent `json:"event"`
+	ActorID    string       `json:"actor_id"`
+	Task       Task         `json:"task"`
+	OccurredAt time.Time    `json:"occurred_at"`
+}
+
+// DeliveryQuery отбирает доставки вебхука WebhookID или всех вебхуков
+// пользователя OwnerID
+type DeliveryQuery struct {
+	WebhookID string
+	OwnerID   string
+	Status    DeliveryStatus
+	Limit     int
+	Offset    int
+}
+
+type DeliveriesResponse struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	Total      int               `json:"total"`
+	Limit      int               `json:"limit"`
+	Offset     int               `json:"offset"`
+}
@@ -0,0 +1,69 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/kljensen/snowball/english"
+	"github.com/kljensen/snowball/russian"
+)
+
+// Веса вхождений: совпадение в заголовке важнее совпадения в описании
+const (
+	TitleWeight       = 2
+	DescriptionWeight = 1
+)
+
+// Token - основа слова текста задачи с его позицией и весом
+type Token struct {
+	Term     string
+	Position int
+	Weight   int
+}
+
+// Analyze разбивает заголовок и описание задачи на основы слов. Позиции
+// описания отделены от заголовка промежутком, чтобы фраза не могла начаться
+// в заголовке и закончиться в описании.
+func Analyze(title, description string) []Token {
+	var tokens []Token
+	for _, term := range Terms(title) {
+		tokens = append(tokens, Token{Term: term, Position: len(tokens), Weight: TitleWeight})
+	}
+
+	offset := len(tokens) + 1
+	for i, term := range Terms(description) {
+		tokens = append(tokens, Token{Term: term, Position: offset + i, Weight: DescriptionWeight})
+	}
+
+	return tokens
+}
+
+// Terms разбивает текст на слова и приводит каждое к основе
+func Terms(text string) []string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, len(words))
+	for i, word := range words {
+		terms[i] = stem(word)
+	}
+	return terms
+}
+
+// stem приводит слово к нижнему регистру и отбрасывает окончание по
+// правилам Snowball для русского или английского языка, смотря по алфавиту
+// слова. Слова на других алфавитах и числа не меняются.
+func stem(word string) string {
+	word = strings.ReplaceAll(strings.ToLower(word), "ё", "е")
+
+	for _, r := range word {
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			return russian.Stem(word, true)
+		case r < unicode.MaxASCII && unicode.IsLetter(r):
+			return english.Stem(word, true)
+		}
+	}
+	return word
+}
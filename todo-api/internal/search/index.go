@@ -0,0 +1,158 @@
+package search
+
+import (
+	"math"
+	"slices"
+)
+
+// IDF - вес слова, которое встречается в df из n задач: чем реже слово,
+// тем выше вес
+func IDF(df, n int) float64 {
+	if df == 0 {
+		return 0
+	}
+	return math.Log(1 + float64(n)/float64(df))
+}
+
+// ScorePrecision - число знаков после запятой в релевантности. Округление
+// делает результат независимым от порядка сложения, поэтому хранилища,
+// считающие релевантность в SQL, упорядочивают задачи так же, как Index.
+const ScorePrecision = 6
+
+// Score - релевантность задачи: сумма по всем вхождениям слов запроса
+// произведений веса вхождения на IDF слова
+func Score(weights map[string]int, idf map[string]float64) float64 {
+	var score float64
+	for term, weight := range weights {
+		score += float64(weight) * idf[term]
+	}
+	return Round(score)
+}
+
+// Round округляет релевантность до ScorePrecision знаков
+func Round(score float64) float64 {
+	scale := math.Pow10(ScorePrecision)
+	return math.Round(score*scale) / scale
+}
+
+type document struct {
+	positions map[string][]int
+	// weights - сумма весов вхождений каждого слова
+	weights map[string]int
+}
+
+// Index - инвертированный индекс текста задач в памяти. Не потокобезопасен:
+// синхронизацию обеспечивает владелец индекса.
+type Index struct {
+	documents map[string]document
+	postings  map[string]map[string]struct{}
+}
+
+func NewIndex() *Index {
+	return &Index{
+		documents: make(map[string]document),
+		postings:  make(map[string]map[string]struct{}),
+	}
+}
+
+// Put индексирует задачу id, заменяя её прежний текст
+func (i *Index) Put(id, title, description string) {
+	i.Remove(id)
+
+	doc := document{
+		positions: make(map[string][]int),
+		weights:   make(map[string]int),
+	}
+	for _, token := range Analyze(title, description) {
+		doc.positions[token.Term] = append(doc.positions[token.Term], token.Position)
+		doc.weights[token.Term] += token.Weight
+	}
+
+	for term := range doc.positions {
+		if i.postings[term] == nil {
+			i.postings[term] = make(map[string]struct{})
+		}
+		i.postings[term][id] = struct{}{}
+	}
+	i.documents[id] = doc
+}
+
+func (i *Index) Remove(id string) {
+	doc, exists := i.documents[id]
+	if !exists {
+		return
+	}
+
+	for term := range doc.positions {
+		delete(i.postings[term], id)
+		if len(i.postings[term]) == 0 {
+			delete(i.postings, term)
+		}
+	}
+	delete(i.documents, id)
+}
+
+// Search возвращает релевантность каждой задачи, подходящей под запрос
+func (i *Index) Search(query Query) map[string]float64 {
+	if query.Empty() {
+		return nil
+	}
+
+	terms := query.AllTerms()
+	idf := make(map[string]float64, len(terms))
+	for _, term := range terms {
+		idf[term] = IDF(len(i.postings[term]), len(i.documents))
+	}
+
+	// Перебираем задачи с самым редким словом: остальные заведомо не подходят
+	rarest := slices.MinFunc(terms, func(a, b string) int {
+		return len(i.postings[a]) - len(i.postings[b])
+	})
+
+	results := make(map[string]float64)
+	for id := range i.postings[rarest] {
+		doc := i.documents[id]
+		if !doc.matches(terms, query.Phrases) {
+			continue
+		}
+
+		weights := make(map[string]int, len(terms))
+		for _, term := range terms {
+			weights[term] = doc.weights[term]
+		}
+		results[id] = Score(weights, idf)
+	}
+
+	return results
+}
+
+func (d document) matches(terms []string, phrases [][]string) bool {
+	for _, term := range terms {
+		if len(d.positions[term]) == 0 {
+			return false
+		}
+	}
+	for _, phrase := range phrases {
+		if !d.containsPhrase(phrase) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsPhrase сообщает, идут ли слова phrase в тексте подряд
+func (d document) containsPhrase(phrase []string) bool {
+	for _, start := range d.positions[phrase[0]] {
+		found := true
+		for offset, term := range phrase[1:] {
+			if !slices.Contains(d.positions[term], start+offset+1) {
+				found = false
+				break
+			}
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
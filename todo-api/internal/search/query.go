@@ -0,0 +1,56 @@
+package search
+
+import (
+	"slices"
+	"strings"
+)
+
+// Query - разобранный поисковый запрос. Задача подходит, если содержит все
+// слова Terms и все фразы Phrases (слова фразы идут подряд).
+type Query struct {
+	Terms   []string
+	Phrases [][]string
+}
+
+// ParseQuery разбирает запрос: слова в двойных кавычках образуют фразу,
+// остальные ищутся по отдельности. Незакрытая кавычка закрывается в конце
+// запроса.
+func ParseQuery(text string) Query {
+	var query Query
+
+	for i, part := range strings.Split(text, `"`) {
+		terms := Terms(part)
+		// Нечётные части находятся внутри кавычек
+		if i%2 == 1 && len(terms) > 1 {
+			query.Phrases = append(query.Phrases, terms)
+			continue
+		}
+		for _, term := range terms {
+			if !slices.Contains(query.Terms, term) {
+				query.Terms = append(query.Terms, term)
+			}
+		}
+	}
+
+	return query
+}
+
+// Empty сообщает, что в запросе нет ни одного слова. Пустому запросу не
+// соответствует ни одна задача.
+func (q Query) Empty() bool {
+	return len(q.Terms) == 0 && len(q.Phrases) == 0
+}
+
+// AllTerms возвращает без повторов все слова запроса, включая слова фраз.
+// Релевантность считается по ним.
+func (q Query) AllTerms() []string {
+	terms := slices.Clone(q.Terms)
+	for _, phrase := range q.Phrases {
+		for _, term := range phrase {
+			if !slices.Contains(terms, term) {
+				terms = append(terms, term)
+			}
+		}
+	}
+	return terms
+}
@@ -0,0 +1,92 @@
+package search
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Купить молоко", "[куп молок]"},
+		{"МОЛОКА, молоку и молоком!", "[молок молок и молок]"},
+		{"Ёлка и елки", "[елк и елк]"},
+		{"Writing reports, wrote report", "[write report wrote report]"},
+		{"v2 2024-01-15", "[v2 2024 01 15]"},
+	}
+
+	for _, tt := range tests {
+		if got := fmt.Sprint(Terms(tt.text)); got != tt.want {
+			t.Errorf("Terms(%q) = %s, want %s", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		text    string
+		terms   string
+		phrases string
+	}{
+		{"молоко хлеб", "[молок хлеб]", "[]"},
+		{`"купить молоко" хлеб молоко`, "[хлеб молок]", "[[куп молок]]"},
+		{`"молоко" "купить хлеб`, "[молок]", "[[куп хлеб]]"},
+		{`!!! ""`, "[]", "[]"},
+	}
+
+	for _, tt := range tests {
+		query := ParseQuery(tt.text)
+		if got := fmt.Sprint(query.Terms); got != tt.terms {
+			t.Errorf("ParseQuery(%q).Terms = %s, want %s", tt.text, got, tt.terms)
+		}
+		if got := fmt.Sprint(query.Phrases); got != tt.phrases {
+			t.Errorf("ParseQuery(%q).Phrases = %s, want %s", tt.text, got, tt.phrases)
+		}
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	index := NewIndex()
+	index.Put("milk", "Купить молоко", "")
+	index.Put("bread", "Хлеб", "купить молоко и хлеб")
+	index.Put("reverse", "Молоко", "не забыть купить")
+	index.Put("report", "Write reports", "")
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"молока", "[bread milk reverse]"},
+		{`"купить молоко"`, "[bread milk]"},
+		{`"молоко купить"`, "[]"},
+		{"купить хлеба", "[bread]"},
+		{"REPORT writing", "[report]"},
+		{"...", "[]"},
+	}
+
+	for _, tt := range tests {
+		results := index.Search(ParseQuery(tt.query))
+		ids := make([]string, 0, len(results))
+		for _, id := range []string{"bread", "milk", "report", "reverse"} {
+			if _, ok := results[id]; ok {
+				ids = append(ids, id)
+			}
+		}
+		if got := fmt.Sprint(ids); got != tt.want {
+			t.Errorf("Search(%q) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+
+	// Совпадение в заголовке весит больше, чем в описании
+	results := index.Search(ParseQuery("молоко"))
+	if results["milk"] <= results["bread"] {
+		t.Errorf("title match score %v <= description match score %v", results["milk"], results["bread"])
+	}
+
+	index.Remove("milk")
+	if _, ok := index.Search(ParseQuery("молоко"))["milk"]; ok {
+		t.Error("removed task is still found")
+	}
+}
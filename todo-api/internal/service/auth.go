@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"todo-api/internal/apperr"
+	"todo-api/internal/auth"
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+var (
+	ErrInvalidCredentials = apperr.Unauthorized("invalid username or password")
+	ErrInvalidToken       = apperr.Unauthorized("invalid or expired token")
+)
+
+// dummyPasswordHash сверяется с паролем, когда пользователя нет: ответ для
+// неизвестного имени занимает столько же, сколько для неверного пароля, и по
+// времени ответа нельзя подбирать имена пользователей
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
+const tokenIssuer = "todo-api"
+
+// authStorage - хранилища, которые нужны AuthService
+type authStorage interface {
+	storage.UserStorage
+	storage.TokenStorage
+}
+
+// TokenConfig задаёт время жизни выдаваемых токенов.
+type TokenConfig struct {
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+type AuthService struct {
+	storage authStorage
+	keyset  *auth.Keyset
+	config  TokenConfig
+}
+
+func NewAuthService(storage authStorage, keyset *auth.Keyset, config TokenConfig) *AuthService {
+	return &AuthService{
+		storage: storage,
+		keyset:  keyset,
+		config:  config,
+	}
+}
+
+func (s *AuthService) Register(ctx context.Context, req models.RegisterRequest) (models.User, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.Register")
+	defer span.End()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user := models.User{
+		Username:     strings.TrimSpace(req.Username),
+		PasswordHash: string(hash),
+	}
+
+	return s.storage.CreateUser(ctx, user)
+}
+
+// Authenticate проверяет имя и пароль. Для неизвестного пользователя и
+// неверного пароля возвращается одна и та же ошибка.
+func (s *AuthService) Authenticate(ctx context.Context, username, password string) (models.User, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.Authenticate")
+	defer span.End()
+
+	user, err := s.storage.GetUserByUsername(ctx, strings.TrimSpace(username))
+	if errors.Is(err, storage.ErrUserNotFound) {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+		return models.User{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return models.User{}, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+// Login проверяет учётные данные и выдаёт пару токенов, начинающую
+// новую цепочку ротаций.
+func (s *AuthService) Login(ctx context.Context, req models.LoginRequest) (models.TokenResponse, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.Login")
+	defer span.End()
+
+	user, err := s.Authenticate(ctx, req.Username, req.Password)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	return s.issueTokens(ctx, user.ID, uuid.New().String())
+}
+
+// Refresh обменивает токен обновления на новую пару токенов. Каждый токен
+// обновления одноразовый: повторное предъявление уже использованного токена
+// означает его утечку, и вся цепочка отзывается.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (models.TokenResponse, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.Refresh")
+	defer span.End()
+
+	hash := hashToken(refreshToken)
+
+	token, err := s.storage.GetRefreshToken(ctx, hash)
+	if errors.Is(err, storage.ErrTokenNotFound) {
+		return models.TokenResponse{}, ErrInvalidToken
+	}
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	if token.RevokedAt != nil || time.Now().After(token.ExpiresAt) {
+		return models.TokenResponse{}, ErrInvalidToken
+	}
+
+	if err := s.storage.UseRefreshToken(ctx, hash); err != nil {
+		if errors.Is(err, storage.ErrTokenUsed) {
+			if err := s.storage.RevokeTokenFamily(ctx, token.FamilyID); err != nil {
+				return models.TokenResponse{}, err
+			}
+			return models.TokenResponse{}, ErrInvalidToken
+		}
+		return models.TokenResponse{}, err
+	}
+
+	return s.issueTokens(ctx, token.UserID, token.FamilyID)
+}
+
+// Logout отзывает токен доступа и, если передан, цепочку токена обновления.
+func (s *AuthService) Logout(ctx context.Context, claims auth.Claims, refreshToken string) error {
+	ctx, span := tracer.Start(ctx, "AuthService.Logout")
+	defer span.End()
+
+	if err := s.storage.RevokeAccessToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return err
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	token, err := s.storage.GetRefreshToken(ctx, hashToken(refreshToken))
+	if errors.Is(err, storage.ErrTokenNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if token.UserID != claims.Subject {
+		return nil
+	}
+
+	return s.storage.RevokeTokenFamily(ctx, token.FamilyID)
+}
+
+// ValidateAccessToken проверяет подпись, срок действия и отзыв токена доступа.
+func (s *AuthService) ValidateAccessToken(ctx context.Context, accessToken string) (auth.Claims, error) {
+	ctx, span := tracer.Start(ctx, "AuthService.ValidateAccessToken")
+	defer span.End()
+
+	var claims auth.Claims
+	if err := s.keyset.Parse(accessToken, &claims); err != nil {
+		return auth.Claims{}, ErrInvalidToken
+	}
+	if claims.Subject == "" || claims.ID == "" || claims.Issuer != tokenIssuer {
+		return auth.Claims{}, ErrInvalidToken
+	}
+
+	revoked, err := s.storage.IsAccessTokenRevoked(ctx, claims.ID)
+	if err != nil {
+		return auth.Claims{}, err
+	}
+	if revoked {
+		return auth.Claims{}, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+func (s *AuthService) issueTokens(ctx context.Context, userID, familyID string) (models.TokenResponse, error) {
+	now := time.Now()
+
+	accessToken, err := s.keyset.Sign(auth.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Subject:   userID,
+			Issuer:    tokenIssuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.config.AccessTTL)),
+		},
+	})
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	refreshToken, err := randomToken()
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	err = s.storage.CreateRefreshToken(ctx, models.RefreshToken{
+		TokenHash: hashToken(refreshToken),
+		UserID:    userID,
+		FamilyID:  familyID,
+		ExpiresAt: now.Add(s.config.RefreshTTL),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	return models.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.config.AccessTTL.Seconds()),
+	}, nil
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
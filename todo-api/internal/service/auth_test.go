@@ -0,0 +1,146 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"todo-api/internal/auth"
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+func newTestAuthService(t *testing.T) *AuthService {
+	t.Helper()
+
+	keyset, err := auth.GenerateKeyset()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewAuthService(storage.NewMemoryStorage(), keyset, TokenConfig{
+		AccessTTL:  time.Minute,
+		RefreshTTL: time.Hour,
+	})
+	if _, err := s.Register(t.Context(), models.RegisterRequest{Username: "alice", Password: "password"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	return s
+}
+
+func login(t *testing.T, s *AuthService) models.TokenResponse {
+	t.Helper()
+
+	tokens, err := s.Login(t.Context(), models.LoginRequest{Username: "alice", Password: "password"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	return tokens
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	s := newTestAuthService(t)
+	first := login(t, s)
+
+	second, err := s.Refresh(t.Context(), first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Errorf("Refresh() returned the same refresh token")
+	}
+	if _, err := s.ValidateAccessToken(t.Context(), second.AccessToken); err != nil {
+		t.Errorf("ValidateAccessToken() error = %v", err)
+	}
+
+	if _, err := s.Refresh(t.Context(), second.RefreshToken); err != nil {
+		t.Errorf("Refresh() of rotated token error = %v", err)
+	}
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	s := newTestAuthService(t)
+	first := login(t, s)
+
+	second, err := s.Refresh(t.Context(), first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	// Повторное использование первого токена - признак утечки
+	if _, err := s.Refresh(t.Context(), first.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("Refresh() of reused token error = %v, want %v", err, ErrInvalidToken)
+	}
+	if _, err := s.Refresh(t.Context(), second.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Refresh() after reuse error = %v, want %v", err, ErrInvalidToken)
+	}
+
+	// Другие сессии пользователя не затрагиваются
+	other := login(t, s)
+	if _, err := s.Refresh(t.Context(), other.RefreshToken); err != nil {
+		t.Errorf("Refresh() of independent session error = %v", err)
+	}
+}
+
+func TestLogoutRevokesTokens(t *testing.T) {
+	s := newTestAuthService(t)
+	tokens := login(t, s)
+
+	claims, err := s.ValidateAccessToken(t.Context(), tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken() error = %v", err)
+	}
+
+	if err := s.Logout(t.Context(), claims, tokens.RefreshToken); err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+
+	if _, err := s.ValidateAccessToken(t.Context(), tokens.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("ValidateAccessToken() after logout error = %v, want %v", err, ErrInvalidToken)
+	}
+	if _, err := s.Refresh(t.Context(), tokens.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Refresh() after logout error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestLoginInvalidCredentials(t *testing.T) {
+	s := newTestAuthService(t)
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+	}{
+		{"Wrong password", "alice", "wrong-password"},
+		{"Unknown user", "bob", "password"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Login(t.Context(), models.LoginRequest{Username: tt.username, Password: tt.password})
+			if !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("Login() error = %v, want %v", err, ErrInvalidCredentials)
+			}
+		})
+	}
+}
+
+func TestAuthenticateUnknownUserTiming(t *testing.T) {
+	s := newTestAuthService(t)
+	// Первый вызов еще и вычисляет хеш для неизвестных пользователей
+	s.Authenticate(t.Context(), "bob", "password")
+
+	elapsed := func(username string) time.Duration {
+		start := time.Now()
+		if _, err := s.Authenticate(t.Context(), username, "wrong-password"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("Authenticate(%s) error = %v, want %v", username, err, ErrInvalidCredentials)
+		}
+		return time.Since(start)
+	}
+
+	// В обоих случаях пароль сверяется с хешем bcrypt одной стоимости
+	known, unknown := elapsed("alice"), elapsed("bob")
+	if unknown < known/2 {
+		t.Errorf("Authenticate() of unknown user took %s, of known user %s", unknown, known)
+	}
+}
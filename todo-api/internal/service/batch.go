@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"todo-api/internal/apperr"
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+var (
+	ErrInvalidBatchOperation = apperr.Validation("invalid batch operation")
+	ErrBatchAborted          = errors.New("not applied: another operation in the batch failed")
+)
+
+// BatchItemResult - результат операции пакета: задача (при удалении пустая)
+// или ошибка
+type BatchItemResult struct {
+	Task models.Task
+	Err  error
+}
+
+// batchChange - изменение хранилища, выполняющее часть операции атомарного
+// пакета, и задача до него для записи в историю
+type batchChange struct {
+	op     storage.BatchOp
+	before models.Task
+}
+
+// Batch выполняет операции пакета и возвращает результаты в их порядке.
+//
+// В режиме BatchAtomic все операции сначала проверяются так же, как
+// одиночные запросы, а затем применяются одним вызовом ApplyBatch. Если
+// какая-то операция не прошла, не применяется ни одна: у неё будет своя
+// ошибка, у остальных - ErrBatchAborted. Одна задача может встречаться в
+// таком пакете только один раз. Новые теги попадают в каталог владельца уже
+// при проверке, даже если пакет затем не применён. В режиме BatchBestEffort
+// операции выполняются по очереди независимо друг от друга.
+func (s *TodoService) Batch(ctx context.Context, userID string, req models.BatchRequest) ([]BatchItemResult, error) {
+	ctx, span := tracer.Start(ctx, "TodoService.Batch")
+	defer span.End()
+
+	if req.Mode == models.BatchBestEffort {
+		results := make([]BatchItemResult, len(req.Operations))
+		for i, op := range req.Operations {
+			results[i].Task, results[i].Err = s.applyOperation(ctx, userID, op)
+		}
+		return results, nil
+	}
+
+	var (
+		changes []batchChange
+		// items[j] - номер операции пакета, породившей изменение changes[j]
+		items []int
+		// primary[i] - изменение, результат которого возвращается для операции i
+		primary = make([]int, len(req.Operations))
+		seen    = make(map[string]bool)
+	)
+	for i, op := range req.Operations {
+		if op.Op != models.BatchCreate {
+			if seen[op.ID] {
+				return abortBatch(len(req.Operations), i, fmt.Errorf("%w: task %s appears more than once", ErrInvalidBatchOperation, op.ID)), nil
+			}
+			seen[op.ID] = true
+		}
+
+		prepared, main, err := s.prepareOperation(ctx, userID, op)
+		if err != nil {
+			return abortBatch(len(req.Operations), i, err), nil
+		}
+		primary[i] = len(changes) + main
+		for range prepared {
+			items = append(items, i)
+		}
+		changes = append(changes, prepared...)
+	}
+
+	tasks, err := s.storage.ApplyBatch(ctx, batchOps(changes))
+	var batchErr *storage.BatchError
+	if errors.As(err, &batchErr) {
+		i := items[batchErr.Index]
+		return abortBatch(len(req.Operations), i, versionError(batchErr.Err, versionCondition(req.Operations[i].Version))), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordChanges(ctx, userID, changes, tasks)
+
+	results := make([]BatchItemResult, len(req.Operations))
+	for i := range results {
+		results[i].Task = tasks[primary[i]]
+	}
+	return results, nil
+}
+
+// batchOps возвращает изменения хранилища для ApplyBatch
+func batchOps(changes []batchChange) []storage.BatchOp {
+	ops := make([]storage.BatchOp, len(changes))
+	for j, change := range changes {
+		ops[j] = change.op
+	}
+	return ops
+}
+
+// recordChanges записывает в историю изменения, которые применил
+// ApplyBatch; tasks - его результат
+func (s *TodoService) recordChanges(ctx context.Context, userID string, changes []batchChange, tasks []models.Task) {
+	for j, change := range changes {
+		switch change.op.Kind {
+		case storage.BatchCreate:
+			s.record(ctx, userID, models.HistoryCreated, models.Task{}, tasks[j])
+		case storage.BatchUpdate:
+			s.record(ctx, userID, models.HistoryUpdated, change.before, tasks[j])
+		case storage.BatchDelete:
+			s.record(ctx, userID, models.HistoryDeleted, change.before, change.before)
+		}
+	}
+}
+
+// abortBatch возвращает результаты атомарного пакета из n операций, который
+// не применён из-за ошибки err операции failed
+func abortBatch(n, failed int, err error) []BatchItemResult {
+	results := make([]BatchItemResult, n)
+	for i := range results {
+		results[i].Err = ErrBatchAborted
+	}
+	results[failed].Err = err
+	return results
+}
+
+// applyOperation выполняет операцию пакета как одиночный запрос
+func (s *TodoService) applyOperation(ctx context.Context, userID string, op models.BatchOperation) (models.Task, error) {
+	if err := checkOperation(op); err != nil {
+		return models.Task{}, err
+	}
+
+	ifMatch := versionCondition(op.Version)
+	switch op.Op {
+	case models.BatchCreate:
+		return s.CreateTask(ctx, userID, *op.Create)
+	case models.BatchUpdate:
+		return s.UpdateTask(ctx, userID, op.ID, *op.Update, ifMatch)
+	default:
+		return models.Task{}, s.DeleteTask(ctx, userID, op.ID, childrenPolicy(op), ifMatch)
+	}
+}
+
+// prepareOperation проверяет операцию пакета и возвращает изменения
+// хранилища, которые её выполняют, и номер изменения с её результатом.
+// Кроме самой задачи операция может менять другие: выполнение повторяющейся
+// задачи создаёт следующее вхождение, удаление с detach отвязывает подзадачи.
+func (s *TodoService) prepareOperation(ctx context.Context, userID string, op models.BatchOperation) ([]batchChange, int, error) {
+	if err := checkOperation(op); err != nil {
+		return nil, 0, err
+	}
+
+	ifMatch := versionCondition(op.Version)
+	switch op.Op {
+	case models.BatchCreate:
+		task, err := s.prepareCreate(ctx, userID, *op.Create)
+		if err != nil {
+			return nil, 0, err
+		}
+		return []batchChange{{op: storage.BatchOp{Kind: storage.BatchCreate, Task: task}}}, 0, nil
+
+	case models.BatchUpdate:
+		existing, updated, err := s.prepareUpdate(ctx, userID, op.ID, *op.Update, ifMatch)
+		if err != nil {
+			return nil, 0, err
+		}
+		changes := []batchChange{{op: storage.BatchOp{Kind: storage.BatchUpdate, Task: updated}, before: existing}}
+		if !existing.Completed && updated.Completed {
+			next, ok, err := nextOccurrenceTask(updated)
+			if err != nil {
+				return nil, 0, err
+			}
+			if ok {
+				changes = append(changes, batchChange{op: storage.BatchOp{Kind: storage.BatchCreate, Task: next}})
+			}
+		}
+		return changes, 0, nil
+
+	default:
+		changes, err := s.prepareDelete(ctx, userID, op.ID, childrenPolicy(op), ifMatch)
+		if err != nil {
+			return nil, 0, err
+		}
+		return changes, len(changes) - 1, nil
+	}
+}
+
+// checkOperation проверяет, что у операции есть нужные ей поля
+func checkOperation(op models.BatchOperation) error {
+	switch {
+	case op.Op == models.BatchCreate && op.Create == nil:
+		return fmt.Errorf("%w: create requires create", ErrInvalidBatchOperation)
+	case op.Op == models.BatchUpdate && op.Update == nil:
+		return fmt.Errorf("%w: update requires update", ErrInvalidBatchOperation)
+	case op.Op != models.BatchCreate && op.ID == "":
+		return fmt.Errorf("%w: %s requires id", ErrInvalidBatchOperation, op.Op)
+	}
+	return nil
+}
+
+// versionCondition переводит версию операции пакета в условие ifMatch
+func versionCondition(version int64) []int64 {
+	if version == 0 {
+		return nil
+	}
+	return []int64{version}
+}
+
+func childrenPolicy(op models.BatchOperation) models.ChildrenPolicy {
+	if op.Children == "" {
+		return models.ChildrenDelete
+	}
+	return op.Children
+}
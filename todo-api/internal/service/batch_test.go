@@ -0,0 +1,92 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+func TestBatchAtomic(t *testing.T) {
+	todos := NewTodoService(storage.NewMemoryStorage())
+	due := time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC)
+	daily := createTask(t, todos, "user", models.CreateTaskRequest{Title: "Зарядка", DueAt: &due, Recurrence: "FREQ=DAILY"})
+	old := createTask(t, todos, "user", models.CreateTaskRequest{Title: "Старая"})
+	foreign := createTask(t, todos, "other", models.CreateTaskRequest{Title: "Чужая"})
+
+	completed := true
+	operations := []models.BatchOperation{
+		{Op: models.BatchCreate, Create: &models.CreateTaskRequest{Title: "Новая"}},
+		{Op: models.BatchUpdate, ID: daily.ID, Update: &models.UpdateTaskRequest{Completed: &completed}},
+		{Op: models.BatchDelete, ID: old.ID, Version: old.Version},
+	}
+
+	// Чужая задача не найдена, поэтому не применяется ни одна операция
+	results, err := todos.Batch(t.Context(), "user", models.BatchRequest{
+		Mode:       models.BatchAtomic,
+		Operations: append(operations, models.BatchOperation{Op: models.BatchDelete, ID: foreign.ID}),
+	})
+	if err != nil {
+		t.Fatalf("Batch() error = %v", err)
+	}
+	if !errors.Is(results[3].Err, storage.ErrTaskNotFound) {
+		t.Errorf("failed operation error = %v, want %v", results[3].Err, storage.ErrTaskNotFound)
+	}
+	for _, result := range results[:3] {
+		if !errors.Is(result.Err, ErrBatchAborted) {
+			t.Errorf("other operation error = %v, want %v", result.Err, ErrBatchAborted)
+		}
+	}
+	if response, _ := todos.GetAllTasks(t.Context(), "user", models.TaskQuery{}); response.Total != 2 {
+		t.Errorf("tasks after aborted batch = %d, want 2", response.Total)
+	}
+
+	results, err = todos.Batch(t.Context(), "user", models.BatchRequest{Mode: models.BatchAtomic, Operations: operations})
+	if err != nil {
+		t.Fatalf("Batch() error = %v", err)
+	}
+	for i, result := range results {
+		if result.Err != nil {
+			t.Errorf("operation %d error = %v", i, result.Err)
+		}
+	}
+	if !results[1].Task.Completed {
+		t.Errorf("updated task = %+v, want completed", results[1].Task)
+	}
+
+	// Новая задача, выполненная и следующее вхождение серии
+	response, _ := todos.GetAllTasks(t.Context(), "user", models.TaskQuery{})
+	if response.Total != 3 {
+		t.Errorf("tasks after batch = %d, want 3", response.Total)
+	}
+}
+
+func TestBatchBestEffort(t *testing.T) {
+	todos := NewTodoService(storage.NewMemoryStorage())
+	task := createTask(t, todos, "user", models.CreateTaskRequest{Title: "Отчёт"})
+
+	results, err := todos.Batch(t.Context(), "user", models.BatchRequest{
+		Mode: models.BatchBestEffort,
+		Operations: []models.BatchOperation{
+			{Op: models.BatchUpdate, ID: task.ID, Update: &models.UpdateTaskRequest{Title: "Первый"}},
+			{Op: models.BatchUpdate, ID: task.ID, Version: task.Version, Update: &models.UpdateTaskRequest{Title: "Второй"}},
+			{Op: models.BatchUpdate, ID: task.ID},
+			{Op: models.BatchCreate, Create: &models.CreateTaskRequest{Title: "Новая"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Batch() error = %v", err)
+	}
+
+	wantErrs := []error{nil, ErrPreconditionFailed, ErrInvalidBatchOperation, nil}
+	for i, want := range wantErrs {
+		if !errors.Is(results[i].Err, want) {
+			t.Errorf("operation %d error = %v, want %v", i, results[i].Err, want)
+		}
+	}
+	if got, _ := todos.GetTask(t.Context(), "user", task.ID); got.Title != "Первый" {
+		t.Errorf("title = %q, want %q", got.Title, "Первый")
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"todo-api/internal/apperr"
+	"todo-api/internal/models"
+)
+
+var ErrInvalidCursor = apperr.Validation("invalid cursor")
+
+// cursorToken - содержимое непрозрачного курсора: задача, на которой
+// закончилась страница, и порядок, для которого курсор выдан. Сохраняются
+// только поля, участвующие в сортировке.
+type cursorToken struct {
+	Sort      string          `json:"s"`
+	Backward  bool            `json:"b,omitempty"`
+	ID        string          `json:"id"`
+	CreatedAt *time.Time      `json:"c,omitempty"`
+	UpdatedAt *time.Time      `json:"u,omitempty"`
+	Completed bool            `json:"d,omitempty"`
+	DueAt     *time.Time      `json:"due,omitempty"`
+	Priority  models.Priority `json:"p,omitempty"`
+	Title     string          `json:"t,omitempty"`
+}
+
+// encodeCursor возвращает курсор страницы после задачи task или, если
+// backward, перед ней
+func encodeCursor(task models.Task, keys []models.SortKey, backward bool) string {
+	token := cursorToken{Sort: formatSort(keys), Backward: backward, ID: task.ID}
+	for _, key := range keys {
+		switch key.Field {
+		case models.SortCreatedAt:
+			token.CreatedAt = &task.CreatedAt
+		case models.SortUpdatedAt:
+			token.UpdatedAt = &task.UpdatedAt
+		case models.SortCompleted:
+			token.Completed = task.Completed
+		case models.SortDueAt:
+			token.DueAt = task.DueAt
+		case models.SortPriority:
+			token.Priority = task.Priority
+		case models.SortTitle:
+			token.Title = task.Title
+		}
+	}
+
+	data, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseCursor разбирает курсор из параметра cursor. Курсор действителен
+// только для того порядка сортировки, для которого он был выдан.
+func ParseCursor(value string, keys []models.SortKey) (*models.Cursor, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var token cursorToken
+	if err := json.Unmarshal(data, &token); err != nil || token.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	if len(keys) == 0 {
+		keys = models.DefaultSort
+	}
+	if sortsByRelevance(keys) {
+		return nil, fmt.Errorf("%w: cursors are not available with sort_by=relevance", ErrInvalidCursor)
+	}
+	if token.Sort != formatSort(keys) {
+		return nil, fmt.Errorf("%w: it was issued for sort_by=%s", ErrInvalidCursor, token.Sort)
+	}
+
+	cursor := &models.Cursor{
+		Backward: token.Backward,
+		Task: models.Task{
+			ID:        token.ID,
+			Completed: token.Completed,
+			DueAt:     token.DueAt,
+			Priority:  token.Priority,
+			Title:     token.Title,
+		},
+	}
+	if token.CreatedAt != nil {
+		cursor.Task.CreatedAt = *token.CreatedAt
+	}
+	if token.UpdatedAt != nil {
+		cursor.Task.UpdatedAt = *token.UpdatedAt
+	}
+
+	return cursor, nil
+}
+
+// sortsByRelevance сообщает, есть ли среди ключей релевантность. Она зависит
+// от запроса и от всех задач сразу, поэтому курсор не может её сохранить.
+func sortsByRelevance(keys []models.SortKey) bool {
+	for _, key := range keys {
+		if key.Field == models.SortRelevance {
+			return true
+		}
+	}
+	return false
+}
+
+// formatSort записывает ключи сортировки в виде параметра sort_by
+func formatSort(keys []models.SortKey) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key.Field + ":asc"
+		if key.Desc {
+			parts[i] = key.Field + ":desc"
+		}
+	}
+	return strings.Join(parts, ",")
+}
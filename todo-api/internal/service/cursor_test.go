@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+func TestCursorPagesAreStable(t *testing.T) {
+	todos := NewTodoService(storage.NewMemoryStorage())
+	for i := range 5 {
+		createTask(t, todos, "user", models.CreateTaskRequest{Title: fmt.Sprintf("Задача %d", i)})
+	}
+
+	first, err := todos.GetAllTasks(t.Context(), "user", models.TaskQuery{Limit: 2})
+	if err != nil {
+		t.Fatalf("GetAllTasks() error = %v", err)
+	}
+	if first.NextCursor == "" || first.PrevCursor != "" {
+		t.Fatalf("first page cursors = %q, %q", first.NextCursor, first.PrevCursor)
+	}
+
+	// Новые задачи попадают в начало списка и сдвинули бы страницы по offset
+	createTask(t, todos, "user", models.CreateTaskRequest{Title: "Новая"})
+
+	seen := titlesOf(first.Tasks)
+	response := first
+	for response.NextCursor != "" {
+		cursor, err := ParseCursor(response.NextCursor, nil)
+		if err != nil {
+			t.Fatalf("ParseCursor() error = %v", err)
+		}
+		if response, err = todos.GetAllTasks(t.Context(), "user", models.TaskQuery{Limit: 2, Cursor: cursor}); err != nil {
+			t.Fatalf("GetAllTasks(cursor) error = %v", err)
+		}
+		seen = append(seen, titlesOf(response.Tasks)...)
+	}
+	if want := "[Задача 4 Задача 3 Задача 2 Задача 1 Задача 0]"; fmt.Sprint(seen) != want {
+		t.Errorf("pages = %v, want %s", seen, want)
+	}
+
+	// С последней страницы назад: prev_cursor ведёт к предыдущей странице
+	cursor, _ := ParseCursor(response.PrevCursor, nil)
+	previous, err := todos.GetAllTasks(t.Context(), "user", models.TaskQuery{Limit: 2, Cursor: cursor})
+	if err != nil {
+		t.Fatalf("GetAllTasks(prev cursor) error = %v", err)
+	}
+	if got := fmt.Sprint(titlesOf(previous.Tasks)); got != "[Задача 2 Задача 1]" {
+		t.Errorf("previous page = %v, want [Задача 2 Задача 1]", got)
+	}
+	if previous.NextCursor == "" || previous.PrevCursor == "" {
+		t.Errorf("middle page cursors = %q, %q", previous.NextCursor, previous.PrevCursor)
+	}
+}
+
+func TestGetAllTasksLimit(t *testing.T) {
+	todos := NewTodoService(storage.NewMemoryStorage())
+	for i := range 3 {
+		createTask(t, todos, "user", models.CreateTaskRequest{Title: fmt.Sprintf("Задача %d", i)})
+	}
+
+	// Слишком большой limit не переполняется при запросе лишней задачи
+	for _, cursor := range []*models.Cursor{nil, {Task: models.Task{ID: "id"}, Backward: true}} {
+		response, err := todos.GetAllTasks(t.Context(), "user", models.TaskQuery{Limit: math.MaxInt, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("GetAllTasks() error = %v", err)
+		}
+		if response.Limit != MaxTaskLimit {
+			t.Errorf("limit = %d, want %d", response.Limit, MaxTaskLimit)
+		}
+	}
+}
+
+func TestParseCursorErrors(t *testing.T) {
+	token := encodeCursor(models.Task{ID: "id", Title: "Задача"}, []models.SortKey{{Field: models.SortTitle}}, false)
+
+	if _, err := ParseCursor(token, []models.SortKey{{Field: models.SortTitle}}); err != nil {
+		t.Errorf("ParseCursor() error = %v", err)
+	}
+	if _, err := ParseCursor(token, nil); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("ParseCursor() with other sort error = %v, want %v", err, ErrInvalidCursor)
+	}
+	if _, err := ParseCursor("не base64", nil); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("ParseCursor() of garbage error = %v, want %v", err, ErrInvalidCursor)
+	}
+}
+
+func titlesOf(tasks []models.Task) []string {
+	result := make([]string, len(tasks))
+	for i, task := range tasks {
+		result[i] = task.Title
+	}
+	return result
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"todo-api/internal/apperr"
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+var (
+	ErrRelatedTaskNotFound = apperr.Validation("related task not found")
+	ErrTaskScope           = apperr.Validation("related tasks must belong to the same list")
+	ErrDependencyCycle     = apperr.Conflict("relation would create a cycle")
+	ErrTaskBlocked         = apperr.Conflict("task is blocked by open tasks")
+)
+
+// BlockedError возвращается при попытке выполнить задачу, у которой есть
+// невыполненные блокирующие задачи.
+type BlockedError struct {
+	BlockerIDs []string
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrTaskBlocked, strings.Join(e.BlockerIDs, ", "))
+}
+
+func (e *BlockedError) Unwrap() error {
+	return ErrTaskBlocked
+}
+
+// GetTaskWithChildren возвращает задачу со всеми уровнями подзадач
+func (s *TodoService) GetTaskWithChildren(ctx context.Context, userID, id string) (models.Task, error) {
+	ctx, span := tracer.Start(ctx, "TodoService.GetTaskWithChildren")
+	defer span.End()
+
+	task, err := s.GetTask(ctx, userID, id)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	if err := s.loadChildren(ctx, &task); err != nil {
+		return models.Task{}, err
+	}
+
+	return task, nil
+}
+
+// loadChildren заполняет task.Children рекурсивно. Подзадачи всегда
+// находятся в том же списке, что и родитель, поэтому доступны тем же
+// пользователям; циклов в дереве нет благодаря проверке в setParent.
+func (s *TodoService) loadChildren(ctx context.Context, task *models.Task) error {
+	children, err := s.storage.GetChildren(ctx, task.ID)
+	if err != nil {
+		return err
+	}
+
+	for i := range children {
+		if err := s.loadChildren(ctx, &children[i]); err != nil {
+			return err
+		}
+	}
+	task.Children = children
+
+	return nil
+}
+
+func (s *TodoService) GetBlockers(ctx context.Context, userID, id string) ([]models.Task, error) {
+	ctx, span := tracer.Start(ctx, "TodoService.GetBlockers")
+	defer span.End()
+
+	if err := validateUUID(id); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.getTask(ctx, userID, id, models.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	blockers, err := s.storage.GetBlockers(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if blockers == nil {
+		blockers = []models.Task{}
+	}
+
+	return blockers, nil
+}
+
+// AddBlocker делает задачу id заблокированной задачей blockerID
+func (s *TodoService) AddBlocker(ctx context.Context, userID, id, blockerID string) error {
+	ctx, span := tracer.Start(ctx, "TodoService.AddBlocker")
+	defer span.End()
+
+	if err := validateUUID(id); err != nil {
+		return err
+	}
+
+	task, err := s.getTask(ctx, userID, id, models.RoleEditor)
+	if err != nil {
+		return err
+	}
+	blocker, err := s.relatedTask(ctx, userID, blockerID)
+	if err != nil {
+		return err
+	}
+	if !sameScope(task, blocker) {
+		return ErrTaskScope
+	}
+
+	// Новая зависимость замыкает цикл, если blocker уже (транзитивно) ждёт задачу
+	cycle, err := s.dependsOn(ctx, blocker.ID, task.ID)
+	if err != nil {
+		return err
+	}
+	if cycle {
+		return ErrDependencyCycle
+	}
+
+	// Повторное добавление ничего не меняет и не попадает в историю
+	blockers, err := s.storage.GetBlockers(ctx, task.ID)
+	if err != nil {
+		return err
+	}
+	for _, existing := range blockers {
+		if existing.ID == blocker.ID {
+			return nil
+		}
+	}
+
+	if err := s.storage.AddDependency(ctx, task.ID, blocker.ID); err != nil {
+		return err
+	}
+	s.recordBlocker(ctx, userID, task.ID, blocker.ID, true)
+	return nil
+}
+
+func (s *TodoService) RemoveBlocker(ctx context.Context, userID, id, blockerID string) error {
+	ctx, span := tracer.Start(ctx, "TodoService.RemoveBlocker")
+	defer span.End()
+
+	if err := validateUUID(id); err != nil {
+		return err
+	}
+
+	if _, err := s.getTask(ctx, userID, id, models.RoleEditor); err != nil {
+		return err
+	}
+
+	if err := s.storage.RemoveDependency(ctx, id, blockerID); err != nil {
+		return err
+	}
+	s.recordBlocker(ctx, userID, id, blockerID, false)
+	return nil
+}
+
+// dependsOn сообщает, заблокирована ли задача from задачей target
+// напрямую или через цепочку других задач
+func (s *TodoService) dependsOn(ctx context.Context, from, target string) (bool, error) {
+	visited := make(map[string]bool)
+	stack := []string{from}
+
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if id == target {
+			return true, nil
+		}
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+
+		blockers, err := s.storage.GetBlockers(ctx, id)
+		if err != nil {
+			return false, err
+		}
+		for _, blocker := range blockers {
+			stack = append(stack, blocker.ID)
+		}
+	}
+
+	return false, nil
+}
+
+// setParent делает parentID родителем задачи task. Пустой parentID
+// делает задачу задачей верхнего уровня.
+func (s *TodoService) setParent(ctx context.Context, userID string, task *models.Task, parentID string) error {
+	if parentID == "" {
+		task.ParentID = ""
+		return nil
+	}
+
+	parent, err := s.relatedTask(ctx, userID, parentID)
+	if err != nil {
+		return err
+	}
+	if !sameScope(*task, parent) {
+		return ErrTaskScope
+	}
+
+	// Задача не может стать подзадачей самой себя или своего потомка
+	for ancestor := parent; ; {
+		if ancestor.ID == task.ID {
+			return ErrDependencyCycle
+		}
+		if ancestor.ParentID == "" {
+			break
+		}
+		if ancestor, err = s.storage.GetByID(ctx, ancestor.ParentID); err != nil {
+			return err
+		}
+	}
+
+	task.ParentID = parent.ID
+	return nil
+}
+
+// checkBlockers возвращает BlockedError, если у задачи есть невыполненные блокирующие задачи
+func (s *TodoService) checkBlockers(ctx context.Context, id string) error {
+	blockers, err := s.storage.GetBlockers(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	var open []string
+	for _, blocker := range blockers {
+		if !blocker.Completed {
+			open = append(open, blocker.ID)
+		}
+	}
+	if len(open) > 0 {
+		return &BlockedError{BlockerIDs: open}
+	}
+
+	return nil
+}
+
+// relatedTask возвращает задачу, на которую ссылается запрос (родитель или
+// блокирующая задача). Связывать можно только задачи, которые пользователь
+// может изменять.
+func (s *TodoService) relatedTask(ctx context.Context, userID, id string) (models.Task, error) {
+	if err := validateUUID(id); err != nil {
+		return models.Task{}, err
+	}
+
+	task, err := s.getTask(ctx, userID, id, models.RoleEditor)
+	if errors.Is(err, storage.ErrTaskNotFound) {
+		return models.Task{}, ErrRelatedTaskNotFound
+	}
+
+	return task, err
+}
+
+// sameScope сообщает, находятся ли задачи в одном списке или обе являются
+// личными задачами одного пользователя
+func sameScope(a, b models.Task) bool {
+	if a.ListID != b.ListID {
+		return false
+	}
+	return a.ListID != "" || a.OwnerID == b.OwnerID
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+func createTask(t *testing.T, s *TodoService, userID string, req models.CreateTaskRequest) models.Task {
+	t.Helper()
+
+	task, err := s.CreateTask(t.Context(), userID, req)
+	if err != nil {
+		t.Fatalf("CreateTask(%q) error = %v", req.Title, err)
+	}
+	return task
+}
+
+func TestCompleteBlockedTask(t *testing.T) {
+	todos := NewTodoService(storage.NewMemoryStorage())
+
+	task := createTask(t, todos, "user", models.CreateTaskRequest{Title: "Покрасить стены"})
+	blocker := createTask(t, todos, "user", models.CreateTaskRequest{Title: "Купить краску"})
+
+	if err := todos.AddBlocker(t.Context(), "user", task.ID, blocker.ID); err != nil {
+		t.Fatalf("AddBlocker() error = %v", err)
+	}
+
+	_, err := todos.CompleteTask(t.Context(), "user", task.ID, nil)
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) || len(blocked.BlockerIDs) != 1 || blocked.BlockerIDs[0] != blocker.ID {
+		t.Fatalf("CompleteTask() of blocked task error = %v, want BlockedError with %s", err, blocker.ID)
+	}
+	completed := true
+	if _, err := todos.UpdateTask(t.Context(), "user", task.ID, models.UpdateTaskRequest{Completed: &completed}, nil); !errors.Is(err, ErrTaskBlocked) {
+		t.Errorf("UpdateTask(completed) of blocked task error = %v, want %v", err, ErrTaskBlocked)
+	}
+
+	if _, err := todos.CompleteTask(t.Context(), "user", blocker.ID, nil); err != nil {
+		t.Fatalf("CompleteTask() of blocker error = %v", err)
+	}
+	if _, err := todos.CompleteTask(t.Context(), "user", task.ID, nil); err != nil {
+		t.Errorf("CompleteTask() after blocker is done error = %v", err)
+	}
+}
+
+func TestDependencyCycles(t *testing.T) {
+	todos := NewTodoService(storage.NewMemoryStorage())
+
+	a := createTask(t, todos, "user", models.CreateTaskRequest{Title: "A"})
+	b := createTask(t, todos, "user", models.CreateTaskRequest{Title: "B"})
+	c := createTask(t, todos, "user", models.CreateTaskRequest{Title: "C"})
+
+	if err := todos.AddBlocker(t.Context(), "user", a.ID, b.ID); err != nil {
+		t.Fatalf("AddBlocker(a, b) error = %v", err)
+	}
+	if err := todos.AddBlocker(t.Context(), "user", b.ID, c.ID); err != nil {
+		t.Fatalf("AddBlocker(b, c) error = %v", err)
+	}
+	if err := todos.AddBlocker(t.Context(), "user", c.ID, a.ID); !errors.Is(err, ErrDependencyCycle) {
+		t.Errorf("AddBlocker(c, a) error = %v, want %v", err, ErrDependencyCycle)
+	}
+	if err := todos.AddBlocker(t.Context(), "user", a.ID, a.ID); !errors.Is(err, ErrDependencyCycle) {
+		t.Errorf("AddBlocker(a, a) error = %v, want %v", err, ErrDependencyCycle)
+	}
+
+	child := createTask(t, todos, "user", models.CreateTaskRequest{Title: "Подзадача", ParentID: a.ID})
+	grandchild := createTask(t, todos, "user", models.CreateTaskRequest{Title: "Вложенная", ParentID: child.ID})
+
+	if _, err := todos.UpdateTask(t.Context(), "user", a.ID, models.UpdateTaskRequest{ParentID: &grandchild.ID}, nil); !errors.Is(err, ErrDependencyCycle) {
+		t.Errorf("UpdateTask() making a task its own descendant error = %v, want %v", err, ErrDependencyCycle)
+	}
+	if _, err := todos.UpdateTask(t.Context(), "user", a.ID, models.UpdateTaskRequest{ParentID: &a.ID}, nil); !errors.Is(err, ErrDependencyCycle) {
+		t.Errorf("UpdateTask() making a task its own parent error = %v, want %v", err, ErrDependencyCycle)
+	}
+	if _, err := todos.UpdateTask(t.Context(), "user", grandchild.ID, models.UpdateTaskRequest{ParentID: &b.ID}, nil); err != nil {
+		t.Errorf("UpdateTask() moving a subtask error = %v", err)
+	}
+}
+
+func TestRelationsScope(t *testing.T) {
+	todos := NewTodoService(storage.NewMemoryStorage())
+
+	own := createTask(t, todos, "alice", models.CreateTaskRequest{Title: "Своя"})
+	other := createTask(t, todos, "bob", models.CreateTaskRequest{Title: "Чужая"})
+
+	if err := todos.AddBlocker(t.Context(), "alice", own.ID, other.ID); !errors.Is(err, ErrRelatedTaskNotFound) {
+		t.Errorf("AddBlocker() with another user's task error = %v, want %v", err, ErrRelatedTaskNotFound)
+	}
+	if _, err := todos.CreateTask(t.Context(), "alice", models.CreateTaskRequest{Title: "Подзадача", ParentID: other.ID}); !errors.Is(err, ErrRelatedTaskNotFound) {
+		t.Errorf("CreateTask() under another user's task error = %v, want %v", err, ErrRelatedTaskNotFound)
+	}
+}
+
+func TestDeleteTaskChildrenPolicy(t *testing.T) {
+	todos := NewTodoService(storage.NewMemoryStorage())
+
+	parent := createTask(t, todos, "user", models.CreateTaskRequest{Title: "Родитель"})
+	child := createTask(t, todos, "user", models.CreateTaskRequest{Title: "Подзадача", ParentID: parent.ID})
+
+	tree, err := todos.GetTaskWithChildren(t.Context(), "user", parent.ID)
+	if err != nil {
+		t.Fatalf("GetTaskWithChildren() error = %v", err)
+	}
+	if len(tree.Children) != 1 || tree.Children[0].ID != child.ID {
+		t.Errorf("GetTaskWithChildren() children = %v, want [%s]", tree.Children, child.ID)
+	}
+
+	if err := todos.DeleteTask(t.Context(), "user", parent.ID, models.ChildrenDetach, nil); err != nil {
+		t.Fatalf("DeleteTask(detach) error = %v", err)
+	}
+	detached, err := todos.GetTask(t.Context(), "user", child.ID)
+	if err != nil {
+		t.Fatalf("GetTask() of detached child error = %v", err)
+	}
+	if detached.ParentID != "" {
+		t.Errorf("detached child ParentID = %q, want empty", detached.ParentID)
+	}
+
+	parent = createTask(t, todos, "user", models.CreateTaskRequest{Title: "Родитель"})
+	child = createTask(t, todos, "user", models.CreateTaskRequest{Title: "Подзадача", ParentID: parent.ID})
+	if err := todos.DeleteTask(t.Context(), "user", parent.ID, models.ChildrenDelete, nil); err != nil {
+		t.Fatalf("DeleteTask(delete) error = %v", err)
+	}
+	if _, err := todos.GetTask(t.Context(), "user", child.ID); !errors.Is(err, storage.ErrTaskNotFound) {
+		t.Errorf("GetTask() of deleted child error = %v, want %v", err, storage.ErrTaskNotFound)
+	}
+}
+
+// racingDeleteStorage меняет родителя, пока сервис читает его подзадачи, как
+// параллельный запрос между проверкой удаления и самим удалением
+type racingDeleteStorage struct {
+	*storage.MemoryStorage
+}
+
+func (s racingDeleteStorage) GetChildren(ctx context.Context, parentID string) ([]models.Task, error) {
+	children, err := s.MemoryStorage.GetChildren(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := s.GetByID(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	parent.Title += " (изменено)"
+	if _, err := s.Update(ctx, parentID, parent); err != nil {
+		return nil, err
+	}
+	return children, nil
+}
+
+func TestDeleteTaskDetachFailure(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	todos := NewTodoService(racingDeleteStorage{store})
+
+	parent := createTask(t, todos, "user", models.CreateTaskRequest{Title: "Родитель"})
+	child := createTask(t, todos, "user", models.CreateTaskRequest{Title: "Подзадача", ParentID: parent.ID})
+
+	err := todos.DeleteTask(t.Context(), "user", parent.ID, models.ChildrenDetach, []int64{parent.Version})
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("DeleteTask() error = %v, want %v", err, ErrPreconditionFailed)
+	}
+
+	// Удаление не прошло, поэтому подзадача не отвязана и в ее истории
+	// только создание
+	if _, err := store.GetByID(t.Context(), parent.ID); err != nil {
+		t.Errorf("GetByID() of parent error = %v", err)
+	}
+	got, err := store.GetByID(t.Context(), child.ID)
+	if err != nil || got.ParentID != parent.ID || got.Version != child.Version {
+		t.Errorf("child after failed delete = %+v, %v, want ParentID %s and version %d", got, err, parent.ID, child.Version)
+	}
+	if history, total, err := store.GetHistory(t.Context(), child.ID, 10, 0); err != nil || total != 1 || history[0].Action != models.HistoryCreated {
+		t.Errorf("child history = %+v, %v", history, err)
+	}
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"todo-api/internal/feed"
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+// ErrFeedClosed - подписка закрыта, потому что подписчик не успевал читать события
+var ErrFeedClosed = errors.New("change feed subscription closed, reconnect with last-event-id")
+
+// FeedService отдаёт пользователям ленту изменений задач, которые они видят
+type FeedService struct {
+	hub     *feed.Hub
+	storage storage.ListStorage
+}
+
+func NewFeedService(hub *feed.Hub, storage storage.ListStorage) *FeedService {
+	return &FeedService{
+		hub:     hub,
+		storage: storage,
+	}
+}
+
+// FeedSubscription - подписка пользователя на ленту с фильтром
+type FeedSubscription struct {
+	service *FeedService
+	sub     *feed.Subscription
+	userID  string
+	filter  models.ChangeFilter
+}
+
+// Subscribe подписывает пользователя на изменения видимых ему задач после
+// события lastEventID (0 - только на новые), подходящих под filter.
+func (s *FeedService) Subscribe(ctx context.Context, userID string, filter models.ChangeFilter, lastEventID int64) (*FeedSubscription, error) {
+	ctx, span := tracer.Start(ctx, "FeedService.Subscribe")
+	defer span.End()
+
+	if filter.ListID != "" {
+		if err := validateUUID(filter.ListID); err != nil {
+			return nil, err
+		}
+		if err := requireListRole(ctx, s.storage, userID, filter.ListID, models.RoleViewer); err != nil {
+			return nil, err
+		}
+	}
+	filter.Tag = strings.ToLower(strings.TrimSpace(filter.Tag))
+
+	return &FeedSubscription{
+		service: s,
+		sub:     s.hub.Subscribe(lastEventID),
+		userID:  userID,
+		filter:  filter,
+	}, nil
+}
+
+// Next ждёт следующее подходящее событие. Возвращает ErrFeedClosed, если
+// подписка отключена из-за отставания, и ошибку ctx при его отмене.
+func (f *FeedSubscription) Next(ctx context.Context) (models.ChangeEvent, error) {
+	for {
+		select {
+		case event, ok := <-f.sub.Events():
+			if !ok {
+				return models.ChangeEvent{}, ErrFeedClosed
+			}
+			if f.matches(ctx, event) {
+				return event, nil
+			}
+		case <-ctx.Done():
+			return models.ChangeEvent{}, ctx.Err()
+		}
+	}
+}
+
+// Close отменяет подписку.
+func (f *FeedSubscription) Close() {
+	f.sub.Close()
+}
+
+// matches проверяет, что задача события видна пользователю и подходит под
+// фильтр до или после изменения
+func (f *FeedSubscription) matches(ctx context.Context, event models.ChangeEvent) bool {
+	if event.Type == models.ChangeReset {
+		return true
+	}
+	if !f.visible(ctx, *event.Task) {
+		return false
+	}
+	return f.filter.Match(*event.Task) || (event.Previous != nil && f.filter.Match(*event.Previous))
+}
+
+// visible проверяет доступ к задаче по правилам getTask с ролью viewer
+func (f *FeedSubscription) visible(ctx context.Context, task models.Task) bool {
+	if task.ListID == "" {
+		return task.OwnerID == f.userID
+	}
+	return requireListRole(ctx, f.service.storage, f.userID, task.ListID, models.RoleViewer) == nil
+}
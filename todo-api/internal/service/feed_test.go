@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"todo-api/internal/feed"
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+// nextChange ждёт событие подписки; события публикуются синхронно, поэтому
+// короткого ожидания достаточно
+func nextChange(t *testing.T, sub *FeedSubscription) (models.ChangeEvent, error) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	return sub.Next(ctx)
+}
+
+func TestFeedSubscription(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	hub := feed.NewHub(100)
+	store.OnChange(hub.Publish)
+	todos := NewTodoService(store)
+	feeds := NewFeedService(hub, store)
+
+	completed := false
+	active, err := feeds.Subscribe(t.Context(), "user", models.ChangeFilter{Completed: &completed}, 0)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	defer active.Close()
+	other, err := feeds.Subscribe(t.Context(), "other", models.ChangeFilter{}, 0)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	defer other.Close()
+
+	task := createTask(t, todos, "user", models.CreateTaskRequest{Title: "Задача"})
+	if _, err := todos.CompleteTask(t.Context(), "user", task.ID, nil); err != nil {
+		t.Fatalf("CompleteTask() error = %v", err)
+	}
+	if err := todos.DeleteTask(t.Context(), "user", task.ID, models.ChildrenDelete, nil); err != nil {
+		t.Fatalf("DeleteTask() error = %v", err)
+	}
+
+	// Завершение проходит фильтр по состоянию до изменения, удаление
+	// выполненной задачи - уже нет
+	for _, want := range []models.ChangeType{models.ChangeCreated, models.ChangeUpdated} {
+		event, err := nextChange(t, active)
+		if err != nil || event.Type != want || event.Task.ID != task.ID {
+			t.Fatalf("Next() = %s, %v, want %s of task", event.Type, err, want)
+		}
+	}
+	if event, err := nextChange(t, active); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Next() = %s, %v, want no more events", event.Type, err)
+	}
+
+	if event, err := nextChange(t, other); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Next() for other user = %s, %v, want no events", event.Type, err)
+	}
+
+	resumed, err := feeds.Subscribe(t.Context(), "user", models.ChangeFilter{}, 1)
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	defer resumed.Close()
+	for _, want := range []models.ChangeType{models.ChangeUpdated, models.ChangeDeleted} {
+		if event, err := nextChange(t, resumed); err != nil || event.Type != want {
+			t.Errorf("resumed Next() = %s, %v, want %s", event.Type, err, want)
+		}
+	}
+}
+
+func TestFeedSubscribeList(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	feeds := NewFeedService(feed.NewHub(10), store)
+
+	if _, err := feeds.Subscribe(t.Context(), "user", models.ChangeFilter{ListID: "not-a-uuid"}, 0); !errors.Is(err, ErrInvalidUUID) {
+		t.Errorf("Subscribe() with invalid list error = %v, want %v", err, ErrInvalidUUID)
+	}
+
+	list, err := NewListService(store).CreateList(t.Context(), "owner", models.CreateListRequest{Name: "Дом"})
+	if err != nil {
+		t.Fatalf("CreateList() error = %v", err)
+	}
+	if _, err := feeds.Subscribe(t.Context(), "user", models.ChangeFilter{ListID: list.ID}, 0); err == nil {
+		t.Error("Subscribe() to foreign list error = nil, want error")
+	}
+	sub, err := feeds.Subscribe(t.Context(), "owner", models.ChangeFilter{ListID: list.ID}, 0)
+	if err != nil {
+		t.Fatalf("Subscribe() to own list error = %v", err)
+	}
+	sub.Close()
+}
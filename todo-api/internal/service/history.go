@@ -0,0 +1,190 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+// historyFields - поля задачи, изменения которых попадают в историю, в
+// порядке вывода. Служебные поля (версия, время изменения, отметка об
+// отправленном напоминании) в историю не пишутся.
+var historyFields = []string{
+	"title",
+	"description",
+	"completed",
+	"priority",
+	"parent_id",
+	"due_at",
+	"remind_at",
+	"recurrence",
+	"tags",
+}
+
+// Размер страницы истории по умолчанию и наибольший
+const (
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 100
+)
+
+// blockedByField - поле изменения в истории при добавлении и снятии блокировки
+const blockedByField = "blocked_by"
+
+// GetTaskHistory возвращает страницу истории задачи от новых записей к
+// старым. История доступна всем, кто видит задачу, в том числе пока задача
+// лежит в корзине.
+func (s *TodoService) GetTaskHistory(ctx context.Context, userID, id string, limit, offset int) (models.HistoryResponse, error) {
+	ctx, span := tracer.Start(ctx, "TodoService.GetTaskHistory")
+	defer span.End()
+
+	if err := validateUUID(id); err != nil {
+		return models.HistoryResponse{}, err
+	}
+
+	task, err := s.storage.GetByID(ctx, id)
+	if errors.Is(err, storage.ErrTaskNotFound) {
+		task, err = s.storage.GetDeleted(ctx, id)
+	}
+	if err != nil {
+		return models.HistoryResponse{}, err
+	}
+	if err := s.checkTaskAccess(ctx, userID, task, models.RoleViewer); err != nil {
+		return models.HistoryResponse{}, err
+	}
+
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	limit = min(limit, maxHistoryLimit)
+	offset = max(offset, 0)
+
+	entries, total, err := s.storage.GetHistory(ctx, id, limit, offset)
+	if err != nil {
+		return models.HistoryResponse{}, err
+	}
+	if entries == nil {
+		entries = []models.HistoryEntry{}
+	}
+
+	return models.HistoryResponse{
+		Entries: entries,
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+	}, nil
+}
+
+// record записывает в историю задачи after действие пользователя actorID и
+// ставит в очередь вебхуки об этом изменении. before - задача до изменения,
+// при создании - пустая задача.
+//
+// Изменение к этому моменту уже сохранено, поэтому ошибки истории и очереди
+// вебхуков его не отменяют: они попадают в журнал, а клиент получает ответ
+// об успехе. По той же причине отмена запроса не прерывает эти записи.
+func (s *TodoService) record(ctx context.Context, actorID string, action models.HistoryAction, before, after models.Task) {
+	ctx = context.WithoutCancel(ctx)
+
+	changes, err := taskChanges(before, after)
+	if err == nil {
+		err = s.addHistory(ctx, actorID, after.ID, action, changes)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to record task history",
+			slog.String("task_id", after.ID),
+			slog.String("action", string(action)),
+			slog.Any("error", err),
+		)
+	}
+	// Запись журнала изменений несет ID запроса, который их сделал
+	slog.InfoContext(ctx, "Task changed",
+		slog.String("task_id", after.ID),
+		slog.String("action", string(action)),
+		slog.String("actor_id", actorID),
+	)
+	if err := s.notifyWebhooks(ctx, actorID, action, before, after); err != nil {
+		slog.ErrorContext(ctx, "Failed to queue webhook deliveries",
+			slog.String("task_id", after.ID),
+			slog.String("action", string(action)),
+			slog.Any("error", err),
+		)
+	}
+}
+
+func (s *TodoService) addHistory(ctx context.Context, actorID, taskID string, action models.HistoryAction, changes []models.FieldChange) error {
+	if changes == nil {
+		changes = []models.FieldChange{}
+	}
+
+	_, err := s.storage.AddHistory(ctx, models.HistoryEntry{
+		TaskID:  taskID,
+		ActorID: actorID,
+		Action:  action,
+		Changes: changes,
+	})
+	return err
+}
+
+// recordBlocker записывает в историю задачи taskID добавление (added) или
+// снятие блокировки задачей blockerID. Ошибки, как и в record, только
+// попадают в журнал.
+func (s *TodoService) recordBlocker(ctx context.Context, actorID, taskID, blockerID string, added bool) {
+	ctx = context.WithoutCancel(ctx)
+
+	value, err := json.Marshal(blockerID)
+	if err == nil {
+		change := models.FieldChange{Field: blockedByField}
+		if added {
+			change.To = value
+		} else {
+			change.From = value
+		}
+		err = s.addHistory(ctx, actorID, taskID, models.HistoryUpdated, []models.FieldChange{change})
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to record task history",
+			slog.String("task_id", taskID),
+			slog.String("action", string(models.HistoryUpdated)),
+			slog.Any("error", err),
+		)
+	}
+}
+
+// taskChanges сравнивает поля historyFields в JSON задач before и after
+func taskChanges(before, after models.Task) ([]models.FieldChange, error) {
+	from, err := taskFields(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := taskFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []models.FieldChange{}
+	for _, field := range historyFields {
+		if !bytes.Equal(from[field], to[field]) {
+			changes = append(changes, models.FieldChange{Field: field, From: from[field], To: to[field]})
+		}
+	}
+	return changes, nil
+}
+
+// taskFields возвращает поля задачи в том виде, в каком их отдаёт API;
+// пустых полей с omitempty в результате нет
+func taskFields(task models.Task) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
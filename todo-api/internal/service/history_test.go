@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+func TestTaskHistory(t *testing.T) {
+	todos := NewTodoService(storage.NewMemoryStorage())
+	task := createTask(t, todos, "user", models.CreateTaskRequest{Title: "Задача", Tags: []string{"дом"}})
+	blocker := createTask(t, todos, "user", models.CreateTaskRequest{Title: "Блокирует"})
+
+	if _, err := todos.UpdateTask(t.Context(), "user", task.ID, models.UpdateTaskRequest{Title: "Новый заголовок"}, nil); err != nil {
+		t.Fatalf("UpdateTask() error = %v", err)
+	}
+	if err := todos.AddBlocker(t.Context(), "user", task.ID, blocker.ID); err != nil {
+		t.Fatalf("AddBlocker() error = %v", err)
+	}
+	if err := todos.AddBlocker(t.Context(), "user", task.ID, blocker.ID); err != nil {
+		t.Fatalf("AddBlocker() twice error = %v", err)
+	}
+	if err := todos.DeleteTask(t.Context(), "user", task.ID, models.ChildrenDelete, nil); err != nil {
+		t.Fatalf("DeleteTask() error = %v", err)
+	}
+
+	// История задачи в корзине по-прежнему доступна
+	history, err := todos.GetTaskHistory(t.Context(), "user", task.ID, 0, 0)
+	if err != nil {
+		t.Fatalf("GetTaskHistory() error = %v", err)
+	}
+	if history.Total != 4 || history.Limit != defaultHistoryLimit {
+		t.Fatalf("GetTaskHistory() total = %d, limit = %d", history.Total, history.Limit)
+	}
+
+	want := []struct {
+		action  models.HistoryAction
+		changes string
+	}{
+		{models.HistoryDeleted, `[]`},
+		{models.HistoryUpdated, `[{"field":"blocked_by","to":"` + blocker.ID + `"}]`},
+		{models.HistoryUpdated, `[{"field":"title","from":"Задача","to":"Новый заголовок"}]`},
+		{models.HistoryCreated, `[{"field":"title","from":"","to":"Задача"},{"field":"tags","to":["дом"]}]`},
+	}
+	for i, entry := range history.Entries {
+		changes, _ := json.Marshal(entry.Changes)
+		if entry.Action != want[i].action || string(changes) != want[i].changes || entry.ActorID != "user" {
+			t.Errorf("entry %d = %s %s by %s, want %s %s", i, entry.Action, changes, entry.ActorID, want[i].action, want[i].changes)
+		}
+	}
+
+	if _, err := todos.GetTaskHistory(t.Context(), "other", task.ID, 0, 0); !errors.Is(err, storage.ErrTaskNotFound) {
+		t.Errorf("GetTaskHistory() by other user error = %v, want %v", err, storage.ErrTaskNotFound)
+	}
+}
+
+// brokenHistoryStorage не может записать историю и найти вебхуки
+type brokenHistoryStorage struct {
+	*storage.MemoryStorage
+}
+
+func (s brokenHistoryStorage) AddHistory(ctx context.Context, entry models.HistoryEntry) (models.HistoryEntry, error) {
+	return models.HistoryEntry{}, errors.New("history is unavailable")
+}
+
+func (s brokenHistoryStorage) GetWebhooks(ctx context.Context, ownerID string) ([]models.Webhook, error) {
+	return nil, errors.New("webhooks are unavailable")
+}
+
+func TestRecordFailureKeepsChange(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	todos := NewTodoService(brokenHistoryStorage{store})
+
+	// Изменения уже сохранены, поэтому ошибки истории и вебхуков не
+	// превращаются в ошибку запроса
+	task := createTask(t, todos, "user", models.CreateTaskRequest{Title: "Задача"})
+	blocker := createTask(t, todos, "user", models.CreateTaskRequest{Title: "Блокирует"})
+	if _, err := todos.CompleteTask(t.Context(), "user", blocker.ID, nil); err != nil {
+		t.Errorf("CompleteTask() error = %v", err)
+	}
+	if err := todos.AddBlocker(t.Context(), "user", task.ID, blocker.ID); err != nil {
+		t.Errorf("AddBlocker() error = %v", err)
+	}
+	if err := todos.DeleteTask(t.Context(), "user", task.ID, models.ChildrenDelete, nil); err != nil {
+		t.Errorf("DeleteTask() error = %v", err)
+	}
+
+	if _, err := store.GetByID(t.Context(), task.ID); !errors.Is(err, storage.ErrTaskNotFound) {
+		t.Errorf("GetByID() of deleted task error = %v, want %v", err, storage.ErrTaskNotFound)
+	}
+	if completed, err := store.GetByID(t.Context(), blocker.ID); err != nil || !completed.Completed {
+		t.Errorf("GetByID() of completed task = %+v, %v", completed, err)
+	}
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"todo-api/internal/apperr"
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+var (
+	ErrOwnerRole = apperr.Conflict("list owner cannot be changed or removed")
+)
+
+// listStorage - хранилища, которые нужны ListService
+type listStorage interface {
+	storage.ListStorage
+	storage.UserStorage
+}
+
+type ListService struct {
+	storage listStorage
+}
+
+func NewListService(storage listStorage) *ListService {
+	return &ListService{
+		storage: storage,
+	}
+}
+
+func (s *ListService) CreateList(ctx context.Context, userID string, req models.CreateListRequest) (models.List, error) {
+	ctx, span := tracer.Start(ctx, "ListService.CreateList")
+	defer span.End()
+
+	list := models.List{
+		Name:        req.Name,
+		Description: req.Description,
+		OwnerID:     userID,
+	}
+
+	created, err := s.storage.CreateList(ctx, list)
+	if err != nil {
+		return models.List{}, err
+	}
+
+	created.Role = models.RoleOwner
+	return created, nil
+}
+
+// GetLists возвращает списки, в которых участвует пользователь, с его ролью
+func (s *ListService) GetLists(ctx context.Context, userID string) ([]models.List, error) {
+	ctx, span := tracer.Start(ctx, "ListService.GetLists")
+	defer span.End()
+
+	lists, err := s.storage.GetListsByMember(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range lists {
+		member, err := s.storage.GetMember(ctx, lists[i].ID, userID)
+		if err != nil {
+			return nil, err
+		}
+		lists[i].Role = member.Role
+	}
+
+	return lists, nil
+}
+
+func (s *ListService) GetList(ctx context.Context, userID, id string) (models.List, error) {
+	ctx, span := tracer.Start(ctx, "ListService.GetList")
+	defer span.End()
+
+	return s.getList(ctx, userID, id, models.RoleViewer)
+}
+
+func (s *ListService) UpdateList(ctx context.Context, userID, id string, req models.UpdateListRequest) (models.List, error) {
+	ctx, span := tracer.Start(ctx, "ListService.UpdateList")
+	defer span.End()
+
+	existing, err := s.getList(ctx, userID, id, models.RoleOwner)
+	if err != nil {
+		return models.List{}, err
+	}
+
+	// Обновляем только переданные поля
+	if req.Name != "" {
+		existing.Name = req.Name
+	}
+	if req.Description != "" {
+		existing.Description = req.Description
+	}
+
+	updated, err := s.storage.UpdateList(ctx, id, existing)
+	if err != nil {
+		return models.List{}, err
+	}
+
+	updated.Role = models.RoleOwner
+	return updated, nil
+}
+
+// DeleteList удаляет список вместе со всеми его задачами
+func (s *ListService) DeleteList(ctx context.Context, userID, id string) error {
+	ctx, span := tracer.Start(ctx, "ListService.DeleteList")
+	defer span.End()
+
+	if _, err := s.getList(ctx, userID, id, models.RoleOwner); err != nil {
+		return err
+	}
+
+	return s.storage.DeleteList(ctx, id)
+}
+
+func (s *ListService) GetMembers(ctx context.Context, userID, listID string) ([]models.ListMember, error) {
+	ctx, span := tracer.Start(ctx, "ListService.GetMembers")
+	defer span.End()
+
+	if _, err := s.getList(ctx, userID, listID, models.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	members, err := s.storage.GetMembers(ctx, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range members {
+		user, err := s.storage.GetUserByID(ctx, members[i].UserID)
+		if err != nil && !errors.Is(err, storage.ErrUserNotFound) {
+			return nil, err
+		}
+		members[i].Username = user.Username
+	}
+
+	return members, nil
+}
+
+// AddMember добавляет пользователя в список или меняет роль существующего участника
+func (s *ListService) AddMember(ctx context.Context, userID, listID string, req models.AddMemberRequest) (models.ListMember, error) {
+	ctx, span := tracer.Start(ctx, "ListService.AddMember")
+	defer span.End()
+
+	if _, err := s.getList(ctx, userID, listID, models.RoleOwner); err != nil {
+		return models.ListMember{}, err
+	}
+
+	user, err := s.storage.GetUserByUsername(ctx, req.Username)
+	if err != nil {
+		return models.ListMember{}, err
+	}
+
+	return s.putMember(ctx, listID, user, req.Role)
+}
+
+func (s *ListService) UpdateMember(ctx context.Context, userID, listID, memberID string, req models.UpdateMemberRequest) (models.ListMember, error) {
+	ctx, span := tracer.Start(ctx, "ListService.UpdateMember")
+	defer span.End()
+
+	if _, err := s.getList(ctx, userID, listID, models.RoleOwner); err != nil {
+		return models.ListMember{}, err
+	}
+
+	if _, err := s.storage.GetMember(ctx, listID, memberID); err != nil {
+		return models.ListMember{}, err
+	}
+
+	user, err := s.storage.GetUserByID(ctx, memberID)
+	if err != nil {
+		return models.ListMember{}, err
+	}
+
+	return s.putMember(ctx, listID, user, req.Role)
+}
+
+// RemoveMember исключает участника из списка. Владелец может исключить любого
+// участника, кроме себя; остальные участники могут только выйти сами.
+func (s *ListService) RemoveMember(ctx context.Context, userID, listID, memberID string) error {
+	ctx, span := tracer.Start(ctx, "ListService.RemoveMember")
+	defer span.End()
+
+	required := models.RoleOwner
+	if memberID == userID {
+		required = models.RoleViewer
+	}
+
+	list, err := s.getList(ctx, userID, listID, required)
+	if err != nil {
+		return err
+	}
+	if memberID == list.OwnerID {
+		return ErrOwnerRole
+	}
+
+	return s.storage.RemoveMember(ctx, listID, memberID)
+}
+
+func (s *ListService) putMember(ctx context.Context, listID string, user models.User, role models.Role) (models.ListMember, error) {
+	list, err := s.storage.GetList(ctx, listID)
+	if err != nil {
+		return models.ListMember{}, err
+	}
+	if user.ID == list.OwnerID {
+		return models.ListMember{}, ErrOwnerRole
+	}
+
+	member, err := s.storage.PutMember(ctx, models.ListMember{
+		ListID: listID,
+		UserID: user.ID,
+		Role:   role,
+	})
+	if err != nil {
+		return models.ListMember{}, err
+	}
+
+	member.Username = user.Username
+	return member, nil
+}
+
+// getList возвращает список с ролью пользователя, если она не ниже required
+func (s *ListService) getList(ctx context.Context, userID, id string, required models.Role) (models.List, error) {
+	if err := validateUUID(id); err != nil {
+		return models.List{}, err
+	}
+
+	if err := requireListRole(ctx, s.storage, userID, id, required); err != nil {
+		return models.List{}, err
+	}
+
+	list, err := s.storage.GetList(ctx, id)
+	if err != nil {
+		return models.List{}, err
+	}
+
+	member, err := s.storage.GetMember(ctx, id, userID)
+	if err != nil {
+		return models.List{}, err
+	}
+	list.Role = member.Role
+
+	return list, nil
+}
+
+// requireListRole проверяет роль пользователя в списке. Для не-участника
+// возвращается ErrListNotFound, чтобы не раскрывать существование списка,
+// для недостаточной роли - ErrForbidden.
+func requireListRole(ctx context.Context, lists storage.ListStorage, userID, listID string, required models.Role) error {
+	member, err := lists.GetMember(ctx, listID, userID)
+	if errors.Is(err, storage.ErrMemberNotFound) {
+		return storage.ErrListNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if !member.Role.Allows(required) {
+		return ErrForbidden
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+// listTestStores возвращает хранилища, на которых проверяются права в
+// списках: роли участников читаются из хранилища
+func listTestStores(t *testing.T) map[string]storage.Storage {
+	t.Helper()
+
+	sqlite, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage() error = %v", err)
+	}
+	t.Cleanup(func() { sqlite.Close() })
+
+	return map[string]storage.Storage{
+		"memory": storage.NewMemoryStorage(),
+		"sqlite": sqlite,
+	}
+}
+
+// newTestListServices создаёт сервисы над хранилищем store и трёх
+// пользователей: владельца, редактора и читателя одного списка
+func newTestListServices(t *testing.T, store storage.Storage) (*ListService, *TodoService, models.List, map[string]models.User) {
+	t.Helper()
+
+	users := make(map[string]models.User)
+	for _, name := range []string{"owner", "editor", "viewer", "stranger"} {
+		user, err := store.CreateUser(t.Context(), models.User{Username: name, PasswordHash: "hash"})
+		if err != nil {
+			t.Fatalf("CreateUser() error = %v", err)
+		}
+		users[name] = user
+	}
+
+	lists := NewListService(store)
+	list, err := lists.CreateList(t.Context(), users["owner"].ID, models.CreateListRequest{Name: "Дом"})
+	if err != nil {
+		t.Fatalf("CreateList() error = %v", err)
+	}
+	for name, role := range map[string]models.Role{"editor": models.RoleEditor, "viewer": models.RoleViewer} {
+		if _, err := lists.AddMember(t.Context(), users["owner"].ID, list.ID, models.AddMemberRequest{Username: name, Role: role}); err != nil {
+			t.Fatalf("AddMember(%s) error = %v", name, err)
+		}
+	}
+
+	return lists, NewTodoService(store), list, users
+}
+
+func TestListTaskPermissions(t *testing.T) {
+	for name, store := range listTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			_, todos, list, users := newTestListServices(t, store)
+
+			task, err := todos.CreateTask(t.Context(), users["editor"].ID, models.CreateTaskRequest{Title: "Купить хлеб", ListID: list.ID})
+			if err != nil {
+				t.Fatalf("CreateTask() by editor error = %v", err)
+			}
+
+			if _, err := todos.CreateTask(t.Context(), users["viewer"].ID, models.CreateTaskRequest{Title: "Нельзя", ListID: list.ID}); !errors.Is(err, ErrForbidden) {
+				t.Errorf("CreateTask() by viewer error = %v, want %v", err, ErrForbidden)
+			}
+			if _, err := todos.GetTask(t.Context(), users["viewer"].ID, task.ID); err != nil {
+				t.Errorf("GetTask() by viewer error = %v", err)
+			}
+			if _, err := todos.CompleteTask(t.Context(), users["viewer"].ID, task.ID, nil); !errors.Is(err, ErrForbidden) {
+				t.Errorf("CompleteTask() by viewer error = %v, want %v", err, ErrForbidden)
+			}
+			if _, err := todos.GetTask(t.Context(), users["stranger"].ID, task.ID); !errors.Is(err, storage.ErrTaskNotFound) {
+				t.Errorf("GetTask() by stranger error = %v, want %v", err, storage.ErrTaskNotFound)
+			}
+			if _, err := todos.CompleteTask(t.Context(), users["owner"].ID, task.ID, nil); err != nil {
+				t.Errorf("CompleteTask() by owner error = %v", err)
+			}
+
+			response, err := todos.GetAllTasks(t.Context(), users["viewer"].ID, models.TaskQuery{Limit: 10})
+			if err != nil {
+				t.Fatalf("GetAllTasks() error = %v", err)
+			}
+			if response.Total != 1 {
+				t.Errorf("GetAllTasks() by viewer total = %d, want 1", response.Total)
+			}
+		})
+	}
+}
+
+func TestListMemberManagement(t *testing.T) {
+	for name, store := range listTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			lists, _, list, users := newTestListServices(t, store)
+
+			if _, err := lists.AddMember(t.Context(), users["editor"].ID, list.ID, models.AddMemberRequest{Username: "stranger", Role: models.RoleViewer}); !errors.Is(err, ErrForbidden) {
+				t.Errorf("AddMember() by editor error = %v, want %v", err, ErrForbidden)
+			}
+			if _, err := lists.GetList(t.Context(), users["stranger"].ID, list.ID); !errors.Is(err, storage.ErrListNotFound) {
+				t.Errorf("GetList() by stranger error = %v, want %v", err, storage.ErrListNotFound)
+			}
+			if err := lists.RemoveMember(t.Context(), users["owner"].ID, list.ID, users["owner"].ID); !errors.Is(err, ErrOwnerRole) {
+				t.Errorf("RemoveMember() of owner error = %v, want %v", err, ErrOwnerRole)
+			}
+			if err := lists.RemoveMember(t.Context(), users["viewer"].ID, list.ID, users["editor"].ID); !errors.Is(err, ErrForbidden) {
+				t.Errorf("RemoveMember() of other member by viewer error = %v, want %v", err, ErrForbidden)
+			}
+			if err := lists.RemoveMember(t.Context(), users["viewer"].ID, list.ID, users["viewer"].ID); err != nil {
+				t.Errorf("RemoveMember() of self error = %v", err)
+			}
+			if _, err := lists.GetList(t.Context(), users["viewer"].ID, list.ID); !errors.Is(err, storage.ErrListNotFound) {
+				t.Errorf("GetList() after leaving error = %v, want %v", err, storage.ErrListNotFound)
+			}
+
+			member, err := lists.UpdateMember(t.Context(), users["owner"].ID, list.ID, users["editor"].ID, models.UpdateMemberRequest{Role: models.RoleViewer})
+			if err != nil {
+				t.Fatalf("UpdateMember() error = %v", err)
+			}
+			if member.Role != models.RoleViewer {
+				t.Errorf("UpdateMember() role = %s, want %s", member.Role, models.RoleViewer)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+
+	"todo-api/internal/apperr"
+	"todo-api/internal/models"
+)
+
+// Типы тела запроса PATCH /tasks/:id
+const (
+	// MergePatchType - RFC 7396: поля объекта заменяют поля задачи, null очищает поле
+	MergePatchType = "application/merge-patch+json"
+	// JSONPatchType - RFC 6902: список операций add, remove, replace, move, copy и test
+	JSONPatchType = "application/json-patch+json"
+)
+
+// maxTitleLength совпадает с ограничением title в CreateTaskRequest
+const maxTitleLength = 200
+
+var (
+	ErrUnsupportedPatch = errors.New("unsupported patch media type")
+	ErrInvalidPatch     = apperr.Validation("invalid patch")
+	ErrPatchTestFailed  = apperr.Conflict("patch test operation failed")
+)
+
+// patchDefaults - значения изменяемых полей, которые JSON задачи опускает,
+// когда они пусты. Они добавляются в документ, чтобы операции JSON Patch
+// могли обращаться к ним (например, add /tags/-).
+var patchDefaults = map[string]json.RawMessage{
+	"description": json.RawMessage(`""`),
+	"parent_id":   json.RawMessage(`""`),
+	"due_at":      json.RawMessage(`null`),
+	"remind_at":   json.RawMessage(`null`),
+	"recurrence":  json.RawMessage(`""`),
+	"tags":        json.RawMessage(`[]`),
+}
+
+// PatchTask применяет к задаче патч типа mediaType. Патч применяется к JSON
+// задачи в том виде, в каком его возвращает GetTask; менять можно только
+// title, description, completed, priority, parent_id, due_at, remind_at,
+// recurrence и tags, остальные поля доступны для операций test. Условие
+// ifMatch проверяется так же, как в UpdateTask.
+func (s *TodoService) PatchTask(ctx context.Context, userID, id, mediaType string, patch []byte, ifMatch []int64) (models.Task, error) {
+	ctx, span := tracer.Start(ctx, "TodoService.PatchTask")
+	defer span.End()
+
+	if mediaType != MergePatchType && mediaType != JSONPatchType {
+		return models.Task{}, ErrUnsupportedPatch
+	}
+	if err := validateUUID(id); err != nil {
+		return models.Task{}, err
+	}
+
+	existing, err := s.getTask(ctx, userID, id, models.RoleEditor)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if err := checkIfMatch(existing, ifMatch); err != nil {
+		return models.Task{}, err
+	}
+
+	updated, err := applyPatch(existing, mediaType, patch)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if err := s.checkUpdate(ctx, userID, existing, &updated); err != nil {
+		return models.Task{}, err
+	}
+
+	return s.saveTask(ctx, userID, existing, updated, ifMatch)
+}
+
+// applyPatch возвращает задачу, полученную применением патча к task
+func applyPatch(task models.Task, mediaType string, patch []byte) (models.Task, error) {
+	task.Children = nil
+	task.Score = 0
+
+	doc, err := taskDocument(task)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	switch mediaType {
+	case MergePatchType:
+		doc, err = jsonpatch.MergePatch(doc, patch)
+	case JSONPatchType:
+		var operations jsonpatch.Patch
+		if operations, err = jsonpatch.DecodePatch(patch); err == nil {
+			doc, err = operations.Apply(doc)
+		}
+	}
+	if errors.Is(err, jsonpatch.ErrTestFailed) {
+		return models.Task{}, ErrPatchTestFailed
+	}
+	if err != nil {
+		return models.Task{}, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	var patched models.Task
+	decoder := json.NewDecoder(bytes.NewReader(doc))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patched); err != nil {
+		return models.Task{}, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	if field := changedReadOnlyField(task, patched); field != "" {
+		return models.Task{}, fmt.Errorf("%w: %s is read-only", ErrInvalidPatch, field)
+	}
+	if err := validatePatchedTask(patched); err != nil {
+		return models.Task{}, err
+	}
+
+	// Поля, которые нельзя менять патчем, берутся из исходной задачи без
+	// потерь точности при разборе JSON
+	updated := task
+	updated.Title = patched.Title
+	updated.Description = patched.Description
+	updated.Completed = patched.Completed
+	updated.Priority = patched.Priority
+	updated.ParentID = patched.ParentID
+	updated.DueAt = patched.DueAt
+	updated.RemindAt = patched.RemindAt
+	updated.Recurrence = patched.Recurrence
+	updated.Tags = patched.Tags
+
+	return updated, nil
+}
+
+// taskDocument возвращает JSON задачи, в котором присутствуют все
+// изменяемые поля
+func taskDocument(task models.Task) ([]byte, error) {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range patchDefaults {
+		if _, ok := fields[name]; !ok {
+			fields[name] = value
+		}
+	}
+
+	return json.Marshal(fields)
+}
+
+// changedReadOnlyField возвращает имя поля, которое патч менять не может, но
+// изменил, или пустую строку
+func changedReadOnlyField(before, after models.Task) string {
+	switch {
+	case after.ID != before.ID:
+		return "id"
+	case after.OwnerID != before.OwnerID:
+		return "owner_id"
+	case after.ListID != before.ListID:
+		return "list_id"
+	case after.Version != before.Version:
+		return "version"
+	case !after.CreatedAt.Equal(before.CreatedAt):
+		return "created_at"
+	case !after.UpdatedAt.Equal(before.UpdatedAt):
+		return "updated_at"
+	case !equalTime(after.RemindedAt, before.RemindedAt):
+		return "reminded_at"
+	case !equalTime(after.DeletedAt, before.DeletedAt):
+		return "deleted_at"
+	case after.Score != 0:
+		return "score"
+	case after.Children != nil:
+		return "children"
+	}
+	return ""
+}
+
+// validatePatchedTask проверяет поля, которые для PUT проверяет привязка запроса
+func validatePatchedTask(task models.Task) error {
+	if task.Title == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidPatch)
+	}
+	if utf8.RuneCountInString(task.Title) > maxTitleLength {
+		return fmt.Errorf("%w: title must be at most %d characters", ErrInvalidPatch, maxTitleLength)
+	}
+	if task.Priority < models.PriorityNone || task.Priority > models.PriorityHigh {
+		return fmt.Errorf("%w: priority must be between %d and %d", ErrInvalidPatch, models.PriorityNone, models.PriorityHigh)
+	}
+	return nil
+}
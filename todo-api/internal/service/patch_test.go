@@ -0,0 +1,77 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+func TestPatchTask(t *testing.T) {
+	todos := NewTodoService(storage.NewMemoryStorage())
+	due := time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC)
+	task := createTask(t, todos, "user", models.CreateTaskRequest{
+		Title:       "Отчёт",
+		Description: "Квартальный",
+		Tags:        []string{"работа"},
+		DueAt:       &due,
+	})
+
+	tests := []struct {
+		name      string
+		mediaType string
+		patch     string
+		wantErr   error
+		check     func(task models.Task) bool
+	}{
+		{
+			"Merge patch clears description and due_at", MergePatchType,
+			`{"description": null, "due_at": null, "priority": 2}`, nil,
+			func(task models.Task) bool {
+				return task.Description == "" && task.DueAt == nil && task.Priority == models.PriorityMedium
+			},
+		},
+		{
+			"JSON patch appends tag", JSONPatchType,
+			`[{"op": "test", "path": "/title", "value": "Отчёт"}, {"op": "add", "path": "/tags/-", "value": "Срочно"}]`, nil,
+			func(task models.Task) bool { return fmt.Sprint(task.Tags) == "[работа срочно]" },
+		},
+		{
+			"JSON patch removes tags", JSONPatchType,
+			`[{"op": "remove", "path": "/tags"}]`, nil,
+			func(task models.Task) bool { return len(task.Tags) == 0 },
+		},
+		{
+			"Failed test", JSONPatchType,
+			`[{"op": "test", "path": "/title", "value": "Другое"}, {"op": "replace", "path": "/title", "value": "Новое"}]`,
+			ErrPatchTestFailed, nil,
+		},
+		{"Read-only field", MergePatchType, `{"owner_id": "other"}`, ErrInvalidPatch, nil},
+		{"Unknown field", MergePatchType, `{"colour": "red"}`, ErrInvalidPatch, nil},
+		{"Empty title", JSONPatchType, `[{"op": "remove", "path": "/title"}]`, ErrInvalidPatch, nil},
+		{"Invalid priority", MergePatchType, `{"priority": 7}`, ErrInvalidPatch, nil},
+		{"Malformed patch", JSONPatchType, `{"op": "remove"}`, ErrInvalidPatch, nil},
+		{"Recurrence without due", MergePatchType, `{"recurrence": "FREQ=DAILY"}`, ErrRecurrenceNeedsDue, nil},
+		{"Unsupported media type", "application/json", `{}`, ErrUnsupportedPatch, nil},
+	}
+
+	// Патчи применяются по очереди к одной и той же задаче
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := todos.PatchTask(t.Context(), "user", task.ID, tt.mediaType, []byte(tt.patch), nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PatchTask() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.check != nil && !tt.check(got) {
+				t.Errorf("PatchTask() = %+v", got)
+			}
+		})
+	}
+
+	if _, err := todos.PatchTask(t.Context(), "user", task.ID, MergePatchType, []byte(`{}`), []int64{task.Version}); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("PatchTask() with stale If-Match error = %v, want %v", err, ErrPreconditionFailed)
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/teambition/rrule-go"
+
+	"todo-api/internal/apperr"
+)
+
+// maxOccurrences ограничивает предпросмотр повторений
+const maxOccurrences = 100
+
+var (
+	ErrInvalidRecurrence    = apperr.Validation("invalid recurrence rule")
+	ErrRecurrenceNeedsDue   = apperr.Validation("recurring task must have due_at")
+	ErrRecurrenceHasDTStart = apperr.Validation("recurrence rule must not contain DTSTART, it is taken from due_at")
+)
+
+// Правило повторения хранится без DTSTART: его началом всегда служит due_at
+// задачи. При выполнении задачи создаётся следующее вхождение со своим due_at,
+// а COUNT уменьшается на единицу, поэтому правило каждой задачи описывает
+// оставшуюся часть серии, начиная с неё самой. Правила вычисляются в UTC.
+
+// normalizeRecurrence проверяет правило и возвращает его каноническую запись
+func normalizeRecurrence(rule string) (string, error) {
+	option, err := rrule.StrToROption(rule)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+	}
+	if !option.Dtstart.IsZero() {
+		return "", ErrRecurrenceHasDTStart
+	}
+	if _, err := rrule.NewRRule(*option); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+	}
+
+	return option.RRuleString(), nil
+}
+
+// newRecurrence строит правило, начинающееся с due
+func newRecurrence(rule string, due time.Time) (*rrule.RRule, *rrule.ROption, error) {
+	option, err := rrule.StrToROption(rule)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+	}
+	option.Dtstart = due.UTC().Truncate(time.Second)
+
+	r, err := rrule.NewRRule(*option)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+	}
+
+	return r, option, nil
+}
+
+// nextOccurrence возвращает срок следующего после due вхождения и правило
+// для задачи с этим сроком. ok = false, если серия закончилась.
+func nextOccurrence(rule string, due time.Time) (next time.Time, nextRule string, ok bool, err error) {
+	r, option, err := newRecurrence(rule, due)
+	if err != nil {
+		return time.Time{}, "", false, err
+	}
+	// Текущая задача - последнее вхождение серии
+	if option.Count == 1 {
+		return time.Time{}, "", false, nil
+	}
+
+	next = r.After(option.Dtstart, false)
+	if next.IsZero() {
+		return time.Time{}, "", false, nil
+	}
+
+	if option.Count > 0 {
+		option.Count--
+	}
+	option.Dtstart = time.Time{}
+
+	return next, option.RRuleString(), true, nil
+}
+
+// occurrences возвращает до limit сроков серии, начиная с due. Сам due
+// всегда считается первым вхождением, как DTSTART в RFC 5545.
+func occurrences(rule string, due time.Time, limit int) ([]time.Time, error) {
+	r, option, err := newRecurrence(rule, due)
+	if err != nil {
+		return nil, err
+	}
+	if option.Count > 0 && option.Count < limit {
+		limit = option.Count
+	}
+
+	result := []time.Time{option.Dtstart}
+	next := r.Iterator()
+	for len(result) < limit {
+		t, ok := next()
+		if !ok {
+			break
+		}
+		if t.After(option.Dtstart) {
+			result = append(result, t)
+		}
+	}
+
+	return result, nil
+}
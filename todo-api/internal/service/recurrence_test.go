@@ -0,0 +1,126 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+func TestNextOccurrence(t *testing.T) {
+	// Понедельник
+	due := time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		rule     string
+		wantDue  time.Time
+		wantRule string
+		wantOK   bool
+	}{
+		{"Weekly", "FREQ=WEEKLY", due.AddDate(0, 0, 7), "FREQ=WEEKLY", true},
+		{"By day", "FREQ=WEEKLY;BYDAY=MO,TH", due.AddDate(0, 0, 3), "FREQ=WEEKLY;BYDAY=MO,TH", true},
+		{"Interval", "FREQ=DAILY;INTERVAL=3", due.AddDate(0, 0, 3), "FREQ=DAILY;INTERVAL=3", true},
+		{"Count decrements", "FREQ=DAILY;COUNT=3", due.AddDate(0, 0, 1), "FREQ=DAILY;COUNT=2", true},
+		{"Last of count", "FREQ=DAILY;COUNT=1", time.Time{}, "", false},
+		{"Until reached", "FREQ=WEEKLY;UNTIL=20240605T000000Z", time.Time{}, "", false},
+		{"Last day of month", "FREQ=MONTHLY;BYMONTHDAY=-1", time.Date(2024, 6, 30, 9, 0, 0, 0, time.UTC), "FREQ=MONTHLY;BYMONTHDAY=-1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotDue, gotRule, ok, err := nextOccurrence(tt.rule, due)
+			if err != nil {
+				t.Fatalf("nextOccurrence() error = %v", err)
+			}
+			if ok != tt.wantOK || !gotDue.Equal(tt.wantDue) || gotRule != tt.wantRule {
+				t.Errorf("nextOccurrence() = %v, %q, %v, want %v, %q, %v", gotDue, gotRule, ok, tt.wantDue, tt.wantRule, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	due := time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC)
+
+	got, err := occurrences("FREQ=WEEKLY;BYDAY=MO,FR;COUNT=3", due, 10)
+	if err != nil {
+		t.Fatalf("occurrences() error = %v", err)
+	}
+	want := []time.Time{due, due.AddDate(0, 0, 4), due.AddDate(0, 0, 7)}
+	if len(got) != len(want) {
+		t.Fatalf("occurrences() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("occurrences()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestNormalizeRecurrence(t *testing.T) {
+	if _, err := normalizeRecurrence("FREQ=SOMETIMES"); !errors.Is(err, ErrInvalidRecurrence) {
+		t.Errorf("normalizeRecurrence() of invalid rule error = %v, want %v", err, ErrInvalidRecurrence)
+	}
+	if _, err := normalizeRecurrence("DTSTART=20240101T000000Z;FREQ=DAILY"); !errors.Is(err, ErrRecurrenceHasDTStart) {
+		t.Errorf("normalizeRecurrence() with DTSTART error = %v, want %v", err, ErrRecurrenceHasDTStart)
+	}
+	if got, err := normalizeRecurrence("RRULE:BYDAY=MO;FREQ=WEEKLY"); err != nil || got != "FREQ=WEEKLY;BYDAY=MO" {
+		t.Errorf("normalizeRecurrence() = %q, %v", got, err)
+	}
+}
+
+func TestCompleteRecurringTask(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	todos := NewTodoService(store)
+
+	due := time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC)
+	remindAt := due.Add(-time.Hour)
+
+	if _, err := todos.CreateTask(t.Context(), "user", models.CreateTaskRequest{Title: "Вынести мусор", Recurrence: "FREQ=WEEKLY"}); !errors.Is(err, ErrRecurrenceNeedsDue) {
+		t.Errorf("CreateTask() without due_at error = %v, want %v", err, ErrRecurrenceNeedsDue)
+	}
+
+	task, err := todos.CreateTask(t.Context(), "user", models.CreateTaskRequest{
+		Title:      "Вынести мусор",
+		DueAt:      &due,
+		RemindAt:   &remindAt,
+		Recurrence: "FREQ=WEEKLY;COUNT=2",
+	})
+	if err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+
+	if _, err := todos.CompleteTask(t.Context(), "user", task.ID, nil); err != nil {
+		t.Fatalf("CompleteTask() error = %v", err)
+	}
+	// Повторное выполнение не создаёт ещё одно вхождение
+	if _, err := todos.CompleteTask(t.Context(), "user", task.ID, nil); err != nil {
+		t.Fatalf("CompleteTask() error = %v", err)
+	}
+
+	notCompleted := false
+	open, err := todos.GetAllTasks(t.Context(), "user", models.TaskQuery{Completed: &notCompleted})
+	if err != nil {
+		t.Fatalf("GetAllTasks() error = %v", err)
+	}
+	if open.Total != 1 {
+		t.Fatalf("open tasks = %d, want 1", open.Total)
+	}
+
+	next := open.Tasks[0]
+	wantDue := due.AddDate(0, 0, 7)
+	if !next.DueAt.Equal(wantDue) || !next.RemindAt.Equal(wantDue.Add(-time.Hour)) || next.Recurrence != "FREQ=WEEKLY;COUNT=1" {
+		t.Errorf("next occurrence = due %v, remind %v, rule %q", next.DueAt, next.RemindAt, next.Recurrence)
+	}
+
+	// Последнее вхождение серии больше ничего не создаёт
+	if _, err := todos.CompleteTask(t.Context(), "user", next.ID, nil); err != nil {
+		t.Fatalf("CompleteTask() error = %v", err)
+	}
+	if open, _ = todos.GetAllTasks(t.Context(), "user", models.TaskQuery{Completed: &notCompleted}); open.Total != 0 {
+		t.Errorf("open tasks after the series ended = %d, want 0", open.Total)
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+// reminderBatchSize - сколько напоминаний планировщик выбирает за один запрос
+const reminderBatchSize = 100
+
+// ReminderScheduler периодически находит задачи, у которых наступило
+// remind_at, и передаёт напоминания в notify. Задача помечается
+// отправленной до вызова notify, поэтому каждое напоминание доставляется
+// не более одного раза, в том числе при нескольких экземплярах сервера.
+type ReminderScheduler struct {
+	storage  storage.ReminderStorage
+	interval time.Duration
+	notify   func(models.ReminderEvent)
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewReminderScheduler(storage storage.ReminderStorage, interval time.Duration, notify func(models.ReminderEvent)) *ReminderScheduler {
+	return &ReminderScheduler{
+		storage:  storage,
+		interval: interval,
+		notify:   notify,
+	}
+}
+
+// Start запускает фоновую проверку напоминаний раз в interval. Отмена ctx
+// останавливает ее так же, как Stop, и прерывает идущий проход.
+func (s *ReminderScheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.done = make(chan struct{})
+	go s.loop(ctx)
+}
+
+// Stop останавливает фоновую проверку и дожидается ее завершения.
+func (s *ReminderScheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+	s.cancel = nil
+}
+
+func (s *ReminderScheduler) loop(ctx context.Context) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		// Ошибку прохода, прерванного остановкой, не пишем
+		if _, err := s.Run(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			slog.Error("Failed to send reminders", slog.Any("error", err))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Run отправляет все напоминания, наступившие к моменту now, и возвращает их число.
+func (s *ReminderScheduler) Run(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := tracer.Start(ctx, "ReminderScheduler.Run")
+	defer span.End()
+
+	sent := 0
+	for {
+		tasks, err := s.storage.GetDueReminders(ctx, now, reminderBatchSize)
+		if err != nil {
+			return sent, err
+		}
+
+		for _, task := range tasks {
+			marked, err := s.storage.MarkReminded(ctx, task.ID, *task.RemindAt)
+			if err != nil {
+				return sent, err
+			}
+			// Задачу удалили или перенесли напоминание после выборки
+			if !marked {
+				continue
+			}
+
+			s.notify(models.ReminderEvent{
+				TaskID:   task.ID,
+				OwnerID:  task.OwnerID,
+				ListID:   task.ListID,
+				Title:    task.Title,
+				DueAt:    task.DueAt,
+				RemindAt: *task.RemindAt,
+				FiredAt:  now,
+			})
+			sent++
+		}
+
+		if len(tasks) < reminderBatchSize {
+			return sent, nil
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+func TestReminderSchedulerRun(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	todos := NewTodoService(store)
+
+	var events []models.ReminderEvent
+	scheduler := NewReminderScheduler(store, time.Minute, func(event models.ReminderEvent) {
+		events = append(events, event)
+	})
+
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Hour)
+
+	task, err := todos.CreateTask(t.Context(), "user", models.CreateTaskRequest{Title: "Позвонить", RemindAt: &past})
+	if err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+	if _, err := todos.CreateTask(t.Context(), "user", models.CreateTaskRequest{Title: "Позже", RemindAt: &future}); err != nil {
+		t.Fatalf("CreateTask() error = %v", err)
+	}
+
+	if sent, err := scheduler.Run(t.Context(), now); err != nil || sent != 1 {
+		t.Fatalf("Run() = %d, %v, want 1", sent, err)
+	}
+	if events[0].TaskID != task.ID || !events[0].RemindAt.Equal(past) {
+		t.Errorf("event = %+v, want reminder for task %s at %v", events[0], task.ID, past)
+	}
+	if sent, _ := scheduler.Run(t.Context(), now); sent != 0 {
+		t.Errorf("second Run() sent %d reminders, want 0", sent)
+	}
+
+	// Новое время напоминания снова ставит задачу в очередь
+	again := now.Add(-time.Second)
+	if _, err := todos.UpdateTask(t.Context(), "user", task.ID, models.UpdateTaskRequest{RemindAt: &again}, nil); err != nil {
+		t.Fatalf("UpdateTask() error = %v", err)
+	}
+	if sent, _ := scheduler.Run(t.Context(), now); sent != 1 {
+		t.Errorf("Run() after rescheduling sent %d reminders, want 1", sent)
+	}
+
+	if sent, _ := scheduler.Run(t.Context(), future); sent != 1 {
+		t.Errorf("Run() at a later time sent %d reminders, want 1", sent)
+	}
+}
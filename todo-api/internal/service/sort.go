@@ -0,0 +1,69 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"todo-api/internal/apperr"
+	"todo-api/internal/models"
+)
+
+var ErrInvalidSort = apperr.Validation("invalid sort")
+
+// sortFields - поля, по которым можно сортировать задачи
+var sortFields = map[string]bool{
+	models.SortCreatedAt: true,
+	models.SortUpdatedAt: true,
+	models.SortCompleted: true,
+	models.SortDueAt:     true,
+	models.SortPriority:  true,
+	models.SortTitle:     true,
+	models.SortRelevance: true,
+}
+
+// ParseSort разбирает параметр sort_by вида "priority:desc,due_at:asc,title".
+// Направление ключа без суффикса задаёт defaultOrder (asc, если он пуст);
+// relevance без суффикса всегда сортируется по убыванию.
+func ParseSort(sortBy, defaultOrder string) ([]models.SortKey, error) {
+	defaultDesc, err := parseSortOrder(defaultOrder)
+	if err != nil {
+		return nil, err
+	}
+	if sortBy == "" {
+		return nil, nil
+	}
+
+	var keys []models.SortKey
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(sortBy, ",") {
+		field, order, hasOrder := strings.Cut(strings.TrimSpace(part), ":")
+		if !sortFields[field] {
+			return nil, fmt.Errorf("%w: unknown sort key %q", ErrInvalidSort, field)
+		}
+		if seen[field] {
+			return nil, fmt.Errorf("%w: duplicate sort key %q", ErrInvalidSort, field)
+		}
+		seen[field] = true
+
+		key := models.SortKey{Field: field, Desc: defaultDesc || field == models.SortRelevance}
+		if hasOrder {
+			if key.Desc, err = parseSortOrder(order); err != nil {
+				return nil, err
+			}
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func parseSortOrder(order string) (bool, error) {
+	switch order {
+	case "", "asc":
+		return false, nil
+	case "desc":
+		return true, nil
+	default:
+		return false, fmt.Errorf("%w: sort order must be asc or desc, got %q", ErrInvalidSort, order)
+	}
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		name   string
+		sortBy string
+		order  string
+		want   string
+		err    error
+	}{
+		{"Empty", "", "", "[]", nil},
+		{"Single key", "title", "", "[{title false}]", nil},
+		{"Default order applies to keys without suffix", "due_at,title:asc", "desc", "[{due_at true} {title false}]", nil},
+		{"Multiple keys", "priority:desc, due_at:asc,title", "", "[{priority true} {due_at false} {title false}]", nil},
+		{"Relevance is descending by default", "relevance,title", "", "[{relevance true} {title false}]", nil},
+		{"Unknown key", "priority,owner_id", "", "", ErrInvalidSort},
+		{"Unknown order", "priority:down", "", "", ErrInvalidSort},
+		{"Unknown default order", "", "up", "", ErrInvalidSort},
+		{"Duplicate key", "title,title:desc", "", "", ErrInvalidSort},
+		{"Empty key", "title,", "", "", ErrInvalidSort},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSort(tt.sortBy, tt.order)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ParseSort() error = %v, want %v", err, tt.err)
+			}
+			if err == nil && fmt.Sprint(got) != tt.want {
+				t.Errorf("ParseSort() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
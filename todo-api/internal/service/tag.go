@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"todo-api/internal/apperr"
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+const (
+	maxTagLength   = 50
+	maxTagsPerTask = 20
+)
+
+var (
+	ErrInvalidTag  = apperr.Validation("invalid tag")
+	ErrTooManyTags = apperr.Validation(fmt.Sprintf("task cannot have more than %d tags", maxTagsPerTask))
+)
+
+// Теги задачи принадлежат каталогу её владельца: при назначении неизвестного
+// тега он добавляется в каталог автоматически, а переименование и удаление
+// тега в каталоге меняют все задачи владельца.
+
+func (s *TodoService) GetTags(ctx context.Context, userID string) ([]models.Tag, error) {
+	ctx, span := tracer.Start(ctx, "TodoService.GetTags")
+	defer span.End()
+
+	tags, err := s.storage.GetTags(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tags == nil {
+		tags = []models.Tag{}
+	}
+
+	return tags, nil
+}
+
+func (s *TodoService) CreateTag(ctx context.Context, userID string, req models.CreateTagRequest) (models.Tag, error) {
+	ctx, span := tracer.Start(ctx, "TodoService.CreateTag")
+	defer span.End()
+
+	name, err := normalizeTag(req.Name)
+	if err != nil {
+		return models.Tag{}, err
+	}
+
+	return s.storage.CreateTag(ctx, models.Tag{
+		Name:    name,
+		Color:   strings.ToLower(req.Color),
+		OwnerID: userID,
+	})
+}
+
+// UpdateTag меняет цвет тега и, если передано новое имя, переименовывает его.
+// Задачи с тегом переименовываются хранилищем одним запросом, поэтому в их
+// историю это изменение не попадает; так же работает DeleteTag.
+func (s *TodoService) UpdateTag(ctx context.Context, userID, name string, req models.UpdateTagRequest) (models.Tag, error) {
+	ctx, span := tracer.Start(ctx, "TodoService.UpdateTag")
+	defer span.End()
+
+	existing, err := s.storage.GetTag(ctx, userID, strings.ToLower(strings.TrimSpace(name)))
+	if err != nil {
+		return models.Tag{}, err
+	}
+
+	tag := existing
+	if req.Name != "" {
+		if tag.Name, err = normalizeTag(req.Name); err != nil {
+			return models.Tag{}, err
+		}
+	}
+	if req.Color != "" {
+		tag.Color = strings.ToLower(req.Color)
+	}
+
+	return s.storage.UpdateTag(ctx, userID, existing.Name, tag)
+}
+
+func (s *TodoService) DeleteTag(ctx context.Context, userID, name string) error {
+	ctx, span := tracer.Start(ctx, "TodoService.DeleteTag")
+	defer span.End()
+
+	return s.storage.DeleteTag(ctx, userID, strings.ToLower(strings.TrimSpace(name)))
+}
+
+// GetTagCounts считает видимые пользователю задачи по тегам с учётом
+// фильтров query, как GetAllTasks
+func (s *TodoService) GetTagCounts(ctx context.Context, userID string, query models.TaskQuery) ([]models.TagCount, error) {
+	ctx, span := tracer.Start(ctx, "TodoService.GetTagCounts")
+	defer span.End()
+
+	if err := s.scopeQuery(ctx, userID, &query); err != nil {
+		return nil, err
+	}
+
+	return s.storage.CountTags(ctx, query)
+}
+
+// setTags назначает задаче теги и добавляет недостающие в каталог её владельца
+func (s *TodoService) setTags(ctx context.Context, task *models.Task, tags []string) error {
+	normalized, err := normalizeTags(tags)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range normalized {
+		_, err := s.storage.CreateTag(ctx, models.Tag{Name: name, OwnerID: task.OwnerID})
+		if err != nil && !errors.Is(err, storage.ErrTagExists) {
+			return err
+		}
+	}
+
+	task.Tags = normalized
+	return nil
+}
+
+// normalizeTags приводит теги к каноническому виду, убирает повторы и
+// сортирует их
+func normalizeTags(tags []string) ([]string, error) {
+	var result []string
+	for _, tag := range tags {
+		name, err := normalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(result, name) {
+			result = append(result, name)
+		}
+	}
+	if len(result) > maxTagsPerTask {
+		return nil, ErrTooManyTags
+	}
+	slices.Sort(result)
+
+	return result, nil
+}
+
+// normalizeTag приводит имя тега к нижнему регистру без пробелов по краям.
+// Запятая запрещена, так как разделяет теги в параметре tags.
+func normalizeTag(tag string) (string, error) {
+	name := strings.ToLower(strings.TrimSpace(tag))
+	switch {
+	case name == "":
+		return "", fmt.Errorf("%w: name is empty", ErrInvalidTag)
+	case utf8.RuneCountInString(name) > maxTagLength:
+		return "", fmt.Errorf("%w: %q is longer than %d characters", ErrInvalidTag, name, maxTagLength)
+	case strings.Contains(name, ","):
+		return "", fmt.Errorf("%w: %q contains a comma", ErrInvalidTag, name)
+	}
+
+	return name, nil
+}
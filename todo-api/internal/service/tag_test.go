@@ -0,0 +1,87 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    []string
+		want    string
+		wantErr error
+	}{
+		{"Empty", nil, "[]", nil},
+		{"Case, spaces and duplicates", []string{" Работа ", "дом", "работа"}, "[дом работа]", nil},
+		{"Blank tag", []string{"дом", "  "}, "", ErrInvalidTag},
+		{"Comma", []string{"дом,работа"}, "", ErrInvalidTag},
+		{"Too long", []string{strings.Repeat("я", maxTagLength+1)}, "", ErrInvalidTag},
+		{"Too many", strings.Split("a,b,c,d,e,f,g,h,i,j,k,l,m,n,o,p,q,r,s,t,u", ","), "", ErrTooManyTags},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeTags(tt.tags)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("normalizeTags() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && fmt.Sprint(got) != tt.want {
+				t.Errorf("normalizeTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaskTags(t *testing.T) {
+	todos := NewTodoService(storage.NewMemoryStorage())
+
+	task := createTask(t, todos, "user", models.CreateTaskRequest{Title: "Отчёт", Tags: []string{"Работа", "срочно"}})
+	if fmt.Sprint(task.Tags) != "[работа срочно]" {
+		t.Errorf("CreateTask().Tags = %v, want [работа срочно]", task.Tags)
+	}
+
+	// Назначенные теги попадают в каталог владельца
+	tags, err := todos.GetTags(t.Context(), "user")
+	if err != nil {
+		t.Fatalf("GetTags() error = %v", err)
+	}
+	if len(tags) != 2 || tags[0].Name != "работа" || tags[1].Name != "срочно" {
+		t.Errorf("GetTags() = %+v, want [работа срочно]", tags)
+	}
+
+	if _, err := todos.UpdateTag(t.Context(), "user", "Срочно", models.UpdateTagRequest{Name: "важно", Color: "#FF0000"}); err != nil {
+		t.Fatalf("UpdateTag() error = %v", err)
+	}
+	if got, _ := todos.GetTask(t.Context(), "user", task.ID); fmt.Sprint(got.Tags) != "[важно работа]" {
+		t.Errorf("tags after rename = %v, want [важно работа]", got.Tags)
+	}
+	if _, err := todos.CreateTag(t.Context(), "user", models.CreateTagRequest{Name: "Важно"}); !errors.Is(err, storage.ErrTagExists) {
+		t.Errorf("CreateTag() of existing tag error = %v, want %v", err, storage.ErrTagExists)
+	}
+
+	createTask(t, todos, "user", models.CreateTaskRequest{Title: "Звонок", Tags: []string{"работа"}})
+	createTask(t, todos, "other", models.CreateTaskRequest{Title: "Чужая", Tags: []string{"работа"}})
+
+	counts, err := todos.GetTagCounts(t.Context(), "user", models.TaskQuery{})
+	if err != nil {
+		t.Fatalf("GetTagCounts() error = %v", err)
+	}
+	if fmt.Sprint(counts) != "[{работа 2} {важно 1}]" {
+		t.Errorf("GetTagCounts() = %v, want [{работа 2} {важно 1}]", counts)
+	}
+
+	empty := []string{}
+	updated, err := todos.UpdateTask(t.Context(), "user", task.ID, models.UpdateTaskRequest{Tags: &empty}, nil)
+	if err != nil {
+		t.Fatalf("UpdateTask() error = %v", err)
+	}
+	if len(updated.Tags) != 0 {
+		t.Errorf("UpdateTask() with empty tags kept %v", updated.Tags)
+	}
+}
@@ -1,104 +1,589 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"time"
 
+	"go.opentelemetry.io/otel"
+
+	"todo-api/internal/apperr"
 	"todo-api/internal/models"
 	"todo-api/internal/storage"
 )
 
+var (
+	ErrForbidden   = apperr.Forbidden("insufficient permissions")
+	ErrInvalidUUID = apperr.Validation("invalid UUID format")
+)
+
+// Размер страницы задач по умолчанию и наибольший
+const (
+	defaultTaskLimit = 10
+	// MaxTaskLimit - наибольший limit списка задач; больший уменьшается до него
+	MaxTaskLimit = 100
+)
+
+// tracer открывает спаны методов сервисов. Пока провайдер спанов не
+// настроен, спаны ничего не стоят.
+var tracer = otel.Tracer("todo-api/internal/service")
+
+// todoStorage - хранилища, которые нужны TodoService
+type todoStorage interface {
+	storage.TaskStorage
+	storage.TrashStorage
+	storage.HistoryStorage
+	storage.DependencyStorage
+	storage.TagStorage
+	storage.ListStorage
+	storage.WebhookStorage
+}
+
 type TodoService struct {
-	storage *storage.MemoryStorage
+	storage todoStorage
 }
 
-func NewTodoService(storage *storage.MemoryStorage) *TodoService {
+func NewTodoService(storage todoStorage) *TodoService {
 	return &TodoService{
 		storage: storage,
 	}
 }
 
-func (s *TodoService) CreateTask(req models.CreateTaskRequest) (models.Task, error) {
+func (s *TodoService) CreateTask(ctx context.Context, userID string, req models.CreateTaskRequest) (models.Task, error) {
+	ctx, span := tracer.Start(ctx, "TodoService.CreateTask")
+	defer span.End()
+
+	task, err := s.prepareCreate(ctx, userID, req)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	task, err = s.storage.Create(ctx, task)
+	if err != nil {
+		return models.Task{}, err
+	}
+	s.record(ctx, userID, models.HistoryCreated, models.Task{}, task)
+
+	return task, nil
+}
+
+// prepareCreate проверяет запрос и возвращает задачу, которую нужно создать
+func (s *TodoService) prepareCreate(ctx context.Context, userID string, req models.CreateTaskRequest) (models.Task, error) {
+	if req.ListID != "" {
+		if err := validateUUID(req.ListID); err != nil {
+			return models.Task{}, err
+		}
+		if err := requireListRole(ctx, s.storage, userID, req.ListID, models.RoleEditor); err != nil {
+			return models.Task{}, err
+		}
+	}
+
+	if req.Recurrence != "" {
+		if req.DueAt == nil {
+			return models.Task{}, ErrRecurrenceNeedsDue
+		}
+		rule, err := normalizeRecurrence(req.Recurrence)
+		if err != nil {
+			return models.Task{}, err
+		}
+		req.Recurrence = rule
+	}
+
 	task := models.Task{
 		Title:       req.Title,
 		Description: req.Description,
 		Completed:   false,
+		Priority:    req.Priority,
+		OwnerID:     userID,
+		ListID:      req.ListID,
+		DueAt:       utcTime(req.DueAt),
+		RemindAt:    utcTime(req.RemindAt),
+		Recurrence:  req.Recurrence,
+	}
+	if err := s.setParent(ctx, userID, &task, req.ParentID); err != nil {
+		return models.Task{}, err
+	}
+	if err := s.setTags(ctx, &task, req.Tags); err != nil {
+		return models.Task{}, err
 	}
 
-	return s.storage.Create(task)
+	return task, nil
 }
 
-func (s *TodoService) GetTask(id string) (models.Task, error) {
+func (s *TodoService) GetTask(ctx context.Context, userID, id string) (models.Task, error) {
+	ctx, span := tracer.Start(ctx, "TodoService.GetTask")
+	defer span.End()
+
 	if err := validateUUID(id); err != nil {
 		return models.Task{}, err
 	}
 
-	return s.storage.GetByID(id)
+	return s.getTask(ctx, userID, id, models.RoleViewer)
 }
 
-func (s *TodoService) GetAllTasks(query models.TaskQuery) (models.TasksResponse, error) {
+// GetAllTasks возвращает личные задачи пользователя и задачи списков, в
+// которых он участвует, либо, если задан query.ListID, только задачи этого списка.
+func (s *TodoService) GetAllTasks(ctx context.Context, userID string, query models.TaskQuery) (models.TasksResponse, error) {
+	ctx, span := tracer.Start(ctx, "TodoService.GetAllTasks")
+	defer span.End()
+
+	if err := s.scopeQuery(ctx, userID, &query); err != nil {
+		return models.TasksResponse{}, err
+	}
+	if query.Search == "" && sortsByRelevance(query.Sort) {
+		return models.TasksResponse{}, fmt.Errorf("%w: sort by relevance requires search", ErrInvalidSort)
+	}
+
 	if query.Limit <= 0 {
-		query.Limit = 10
+		query.Limit = defaultTaskLimit
 	}
-	if query.Offset < 0 {
+	// Ограничение еще и не дает переполниться query.Limit++ ниже
+	query.Limit = min(query.Limit, MaxTaskLimit)
+	if query.Offset < 0 || query.Cursor != nil {
 		query.Offset = 0
 	}
 
-	tasks, total, err := s.storage.GetAll(query)
+	// Лишняя задача показывает, есть ли страница дальше в направлении выборки
+	limit := query.Limit
+	query.Limit++
+	tasks, total, err := s.storage.GetAll(ctx, query)
 	if err != nil {
 		return models.TasksResponse{}, err
 	}
 
-	return models.TasksResponse{
+	backward := query.Cursor != nil && query.Cursor.Backward
+	hasMore := len(tasks) > limit
+	if hasMore {
+		if backward {
+			tasks = tasks[1:]
+		} else {
+			tasks = tasks[:limit]
+		}
+	}
+	if tasks == nil {
+		tasks = []models.Task{}
+	}
+
+	response := models.TasksResponse{
 		Tasks:  tasks,
 		Total:  total,
-		Limit:  query.Limit,
+		Limit:  limit,
 		Offset: query.Offset,
-	}, nil
+	}
+	if len(tasks) > 0 && !sortsByRelevance(query.Sort) {
+		keys := query.SortKeys()
+		first, last := tasks[0], tasks[len(tasks)-1]
+		if hasMore || backward {
+			response.NextCursor = encodeCursor(last, keys, false)
+		}
+		if (hasMore && backward) || (!backward && (query.Cursor != nil || query.Offset > 0)) {
+			response.PrevCursor = encodeCursor(first, keys, true)
+		}
+	}
+
+	return response, nil
 }
 
-func (s *TodoService) UpdateTask(id string, req models.UpdateTaskRequest) (models.Task, error) {
-	if err := validateUUID(id); err != nil {
-		return models.Task{}, err
+// scopeQuery ограничивает query задачами, видимыми пользователю: его
+// личными задачами и задачами его списков, либо, если задан query.ListID,
+// только задачами этого списка.
+func (s *TodoService) scopeQuery(ctx context.Context, userID string, query *models.TaskQuery) error {
+	if query.ListID != "" {
+		if err := validateUUID(query.ListID); err != nil {
+			return err
+		}
+		if err := requireListRole(ctx, s.storage, userID, query.ListID, models.RoleViewer); err != nil {
+			return err
+		}
+		query.OwnerID = ""
+		query.ListIDs = nil
+		return nil
 	}
 
-	existing, err := s.storage.GetByID(id)
+	lists, err := s.storage.GetListsByMember(ctx, userID)
+	if err != nil {
+		return err
+	}
+	query.OwnerID = userID
+	query.ListIDs = make([]string, len(lists))
+	for i, list := range lists {
+		query.ListIDs[i] = list.ID
+	}
+
+	return nil
+}
+
+// UpdateTask меняет переданные поля задачи. Если задан ifMatch, задача
+// меняется, только если её версия входит в этот список.
+func (s *TodoService) UpdateTask(ctx context.Context, userID, id string, req models.UpdateTaskRequest, ifMatch []int64) (models.Task, error) {
+	ctx, span := tracer.Start(ctx, "TodoService.UpdateTask")
+	defer span.End()
+
+	existing, updated, err := s.prepareUpdate(ctx, userID, id, req, ifMatch)
 	if err != nil {
 		return models.Task{}, err
 	}
 
+	return s.saveTask(ctx, userID, existing, updated, ifMatch)
+}
+
+// prepareUpdate возвращает задачу и её проверенную копию с изменениями из req
+func (s *TodoService) prepareUpdate(ctx context.Context, userID, id string, req models.UpdateTaskRequest, ifMatch []int64) (existing, updated models.Task, err error) {
+	if err := validateUUID(id); err != nil {
+		return models.Task{}, models.Task{}, err
+	}
+
+	existing, err = s.getTask(ctx, userID, id, models.RoleEditor)
+	if err != nil {
+		return models.Task{}, models.Task{}, err
+	}
+	if err := checkIfMatch(existing, ifMatch); err != nil {
+		return models.Task{}, models.Task{}, err
+	}
+
 	// Обновляем только переданные поля
+	updated = existing
 	if req.Title != "" {
-		existing.Title = req.Title
+		updated.Title = req.Title
 	}
 	if req.Description != "" {
-		existing.Description = req.Description
+		updated.Description = req.Description
 	}
 	if req.Completed != nil {
-		existing.Completed = *req.Completed
+		updated.Completed = *req.Completed
+	}
+	if req.Priority != nil {
+		updated.Priority = *req.Priority
+	}
+	if req.Recurrence != nil {
+		updated.Recurrence = *req.Recurrence
+	}
+	if req.DueAt != nil {
+		updated.DueAt = req.DueAt
+	}
+	if req.RemindAt != nil {
+		updated.RemindAt = req.RemindAt
+	}
+	if req.ParentID != nil {
+		updated.ParentID = *req.ParentID
+	}
+	if req.Tags != nil {
+		updated.Tags = *req.Tags
 	}
 
-	return s.storage.Update(id, existing)
+	if err := s.checkUpdate(ctx, userID, existing, &updated); err != nil {
+		return models.Task{}, models.Task{}, err
+	}
+	return existing, updated, nil
 }
 
-func (s *TodoService) DeleteTask(id string) error {
-	if err := validateUUID(id); err != nil {
+// checkUpdate проверяет updated - изменённую копию задачи existing.
+// Изменённые правило повторения, родитель и теги приводятся к каноническому
+// виду, выполнение задачи проверяется по блокирующим задачам.
+func (s *TodoService) checkUpdate(ctx context.Context, userID string, existing models.Task, updated *models.Task) error {
+	if updated.Recurrence != existing.Recurrence && updated.Recurrence != "" {
+		rule, err := normalizeRecurrence(updated.Recurrence)
+		if err != nil {
+			return err
+		}
+		updated.Recurrence = rule
+	}
+	updated.DueAt = utcTime(updated.DueAt)
+	// Новое время напоминания снова ставит его в очередь планировщика
+	if !equalTime(updated.RemindAt, existing.RemindAt) {
+		updated.RemindAt = utcTime(updated.RemindAt)
+		updated.RemindedAt = nil
+	}
+	if updated.Recurrence != "" && updated.DueAt == nil {
+		return ErrRecurrenceNeedsDue
+	}
+	if updated.ParentID != existing.ParentID {
+		if err := s.setParent(ctx, userID, updated, updated.ParentID); err != nil {
+			return err
+		}
+	}
+	if !slices.Equal(updated.Tags, existing.Tags) {
+		if err := s.setTags(ctx, updated, updated.Tags); err != nil {
+			return err
+		}
+	}
+	if !existing.Completed && updated.Completed {
+		if err := s.checkBlockers(ctx, existing.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// saveTask сохраняет проверенную в checkUpdate копию updated задачи
+// existing и записывает изменение в историю. Выполнение повторяющейся задачи
+// порождает следующее вхождение серии.
+func (s *TodoService) saveTask(ctx context.Context, userID string, existing, updated models.Task, ifMatch []int64) (models.Task, error) {
+	// Версия existing защищает от изменений, сделанных после чтения задачи
+	saved, err := s.storage.Update(ctx, existing.ID, updated)
+	if err != nil {
+		return models.Task{}, versionError(err, ifMatch)
+	}
+	s.record(ctx, userID, models.HistoryUpdated, existing, saved)
+
+	if !existing.Completed && saved.Completed {
+		if err := s.spawnNextOccurrence(ctx, userID, saved); err != nil {
+			return models.Task{}, err
+		}
+	}
+
+	return saved, nil
+}
+
+// DeleteTask переносит задачу в корзину. Подзадачи попадают туда вместе с
+// ней или, при policy = ChildrenDetach, становятся задачами верхнего уровня. Условие
+// ifMatch проверяется так же, как в UpdateTask.
+func (s *TodoService) DeleteTask(ctx context.Context, userID, id string, policy models.ChildrenPolicy, ifMatch []int64) error {
+	ctx, span := tracer.Start(ctx, "TodoService.DeleteTask")
+	defer span.End()
+
+	changes, err := s.prepareDelete(ctx, userID, id, policy, ifMatch)
+	if err != nil {
+		return err
+	}
+
+	// Подзадачи отвязываются вместе с удалением: если оно не пройдет, они
+	// останутся у родителя
+	tasks, err := s.storage.ApplyBatch(ctx, batchOps(changes))
+	var batchErr *storage.BatchError
+	if errors.As(err, &batchErr) {
+		// Условие ifMatch относится к самой задаче, а не к подзадачам
+		if batchErr.Index == len(changes)-1 {
+			return versionError(batchErr.Err, ifMatch)
+		}
+		return batchErr.Err
+	}
+	if err != nil {
 		return err
 	}
 
-	return s.storage.Delete(id)
+	s.recordChanges(ctx, userID, changes, tasks)
+	return nil
+}
+
+// prepareDelete проверяет удаление задачи и возвращает изменения хранилища,
+// которые его выполняют. При policy = ChildrenDetach сначала идут подзадачи,
+// отвязанные от родителя; последнее изменение - удаление самой задачи.
+func (s *TodoService) prepareDelete(ctx context.Context, userID, id string, policy models.ChildrenPolicy, ifMatch []int64) ([]batchChange, error) {
+	if err := validateUUID(id); err != nil {
+		return nil, err
+	}
+
+	task, err := s.getTask(ctx, userID, id, models.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkIfMatch(task, ifMatch); err != nil {
+		return nil, err
+	}
+
+	var changes []batchChange
+	if policy == models.ChildrenDetach {
+		children, err := s.storage.GetChildren(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		for _, child := range children {
+			detached := child
+			detached.ParentID = ""
+			changes = append(changes, batchChange{op: storage.BatchOp{Kind: storage.BatchUpdate, Task: detached}, before: child})
+		}
+	}
+
+	return append(changes, batchChange{
+		op:     storage.BatchOp{Kind: storage.BatchDelete, Task: models.Task{ID: task.ID, Version: task.Version}},
+		before: task,
+	}), nil
+}
+
+func (s *TodoService) CompleteTask(ctx context.Context, userID, id string, ifMatch []int64) (models.Task, error) {
+	ctx, span := tracer.Start(ctx, "TodoService.CompleteTask")
+	defer span.End()
+
+	if err := validateUUID(id); err != nil {
+		return models.Task{}, err
+	}
+
+	existing, err := s.getTask(ctx, userID, id, models.RoleEditor)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if err := checkIfMatch(existing, ifMatch); err != nil {
+		return models.Task{}, err
+	}
+
+	if !existing.Completed {
+		if err := s.checkBlockers(ctx, id); err != nil {
+			return models.Task{}, err
+		}
+	}
+
+	task, err := s.storage.CompleteTask(ctx, id, existing.Version)
+	if err != nil {
+		return models.Task{}, versionError(err, ifMatch)
+	}
+	s.record(ctx, userID, models.HistoryUpdated, existing, task)
+
+	// Повторное выполнение не должно порождать лишние вхождения серии
+	if !existing.Completed {
+		if err := s.spawnNextOccurrence(ctx, userID, task); err != nil {
+			return models.Task{}, err
+		}
+	}
+
+	return task, nil
 }
 
-func (s *TodoService) CompleteTask(id string) (models.Task, error) {
+// GetOccurrences возвращает до limit ближайших сроков задачи, начиная с её
+// due_at. У неповторяющейся задачи это только её собственный срок.
+func (s *TodoService) GetOccurrences(ctx context.Context, userID, id string, limit int) (models.OccurrencesResponse, error) {
+	ctx, span := tracer.Start(ctx, "TodoService.GetOccurrences")
+	defer span.End()
+
 	if err := validateUUID(id); err != nil {
+		return models.OccurrencesResponse{}, err
+	}
+
+	task, err := s.getTask(ctx, userID, id, models.RoleViewer)
+	if err != nil {
+		return models.OccurrencesResponse{}, err
+	}
+
+	if limit <= 0 {
+		limit = 5
+	}
+	if limit > maxOccurrences {
+		limit = maxOccurrences
+	}
+
+	response := models.OccurrencesResponse{
+		TaskID:      task.ID,
+		Recurrence:  task.Recurrence,
+		Occurrences: []time.Time{},
+	}
+	switch {
+	case task.DueAt == nil:
+	case task.Recurrence == "":
+		response.Occurrences = append(response.Occurrences, *task.DueAt)
+	default:
+		response.Occurrences, err = occurrences(task.Recurrence, *task.DueAt, limit)
+		if err != nil {
+			return models.OccurrencesResponse{}, err
+		}
+	}
+
+	return response, nil
+}
+
+// spawnNextOccurrence создаёт следующее вхождение повторяющейся задачи,
+// выполненной пользователем userID
+func (s *TodoService) spawnNextOccurrence(ctx context.Context, userID string, task models.Task) error {
+	next, ok, err := nextOccurrenceTask(task)
+	if err != nil || !ok {
+		return err
+	}
+
+	next, err = s.storage.Create(ctx, next)
+	if err != nil {
+		return err
+	}
+	s.record(ctx, userID, models.HistoryCreated, models.Task{}, next)
+	return nil
+}
+
+// nextOccurrenceTask возвращает следующее вхождение повторяющейся задачи.
+// Напоминание сдвигается вместе со сроком. ok = false, если задача не
+// повторяется или её серия закончилась.
+func nextOccurrenceTask(task models.Task) (next models.Task, ok bool, err error) {
+	if task.Recurrence == "" || task.DueAt == nil {
+		return models.Task{}, false, nil
+	}
+
+	due, rule, ok, err := nextOccurrence(task.Recurrence, *task.DueAt)
+	if err != nil || !ok {
+		return models.Task{}, false, err
+	}
+
+	next = models.Task{
+		Title:       task.Title,
+		Description: task.Description,
+		Priority:    task.Priority,
+		OwnerID:     task.OwnerID,
+		ListID:      task.ListID,
+		ParentID:    task.ParentID,
+		Tags:        task.Tags,
+		DueAt:       &due,
+		Recurrence:  rule,
+	}
+	if task.RemindAt != nil {
+		remindAt := due.Add(task.RemindAt.Sub(*task.DueAt))
+		next.RemindAt = &remindAt
+	}
+
+	return next, true, nil
+}
+
+// getTask возвращает задачу, если у пользователя есть на неё права не ниже
+// required. Личные задачи доступны только владельцу, задачи списка - его
+// участникам согласно роли. Недоступная задача неотличима от несуществующей,
+// чтобы не раскрывать её наличие; недостаточная роль даёт ErrForbidden.
+func (s *TodoService) getTask(ctx context.Context, userID, id string, required models.Role) (models.Task, error) {
+	task, err := s.storage.GetByID(ctx, id)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if err := s.checkTaskAccess(ctx, userID, task, required); err != nil {
 		return models.Task{}, err
 	}
 
-	return s.storage.CompleteTask(id)
+	return task, nil
+}
+
+// checkTaskAccess проверяет права пользователя на задачу по правилам getTask
+func (s *TodoService) checkTaskAccess(ctx context.Context, userID string, task models.Task, required models.Role) error {
+	if task.ListID == "" {
+		if task.OwnerID != userID {
+			return storage.ErrTaskNotFound
+		}
+		return nil
+	}
+
+	err := requireListRole(ctx, s.storage, userID, task.ListID, required)
+	if errors.Is(err, storage.ErrListNotFound) {
+		return storage.ErrTaskNotFound
+	}
+	return err
+}
+
+// equalTime сравнивает необязательные метки времени
+func equalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// utcTime приводит необязательную метку времени из запроса к UTC, чтобы
+// все хранилища возвращали её в одном виде
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
 }
 
 func validateUUID(id string) error {
 	if len(id) != 36 { // UUID v4 длина
-		return errors.New("invalid UUID format")
+		return ErrInvalidUUID
 	}
 	return nil
 }
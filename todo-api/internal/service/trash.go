@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"todo-api/internal/apperr"
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+var ErrParentDeleted = apperr.Conflict("parent task is in the trash, restore it first")
+
+// RestoreTask возвращает задачу из корзины вместе с подзадачами, удалёнными
+// одновременно с ней. Подзадачу нельзя восстановить, пока её родитель в корзине.
+func (s *TodoService) RestoreTask(ctx context.Context, userID, id string) (models.Task, error) {
+	ctx, span := tracer.Start(ctx, "TodoService.RestoreTask")
+	defer span.End()
+
+	if err := validateUUID(id); err != nil {
+		return models.Task{}, err
+	}
+
+	task, err := s.storage.GetDeleted(ctx, id)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if err := s.checkTaskAccess(ctx, userID, task, models.RoleEditor); err != nil {
+		return models.Task{}, err
+	}
+
+	if task.ParentID != "" {
+		_, err := s.storage.GetByID(ctx, task.ParentID)
+		if errors.Is(err, storage.ErrTaskNotFound) {
+			return models.Task{}, ErrParentDeleted
+		}
+		if err != nil {
+			return models.Task{}, err
+		}
+	}
+
+	restored, err := s.storage.Restore(ctx, id)
+	if err != nil {
+		return models.Task{}, err
+	}
+	s.record(ctx, userID, models.HistoryRestored, restored, restored)
+
+	return restored, nil
+}
+
+// TrashPurger периодически окончательно удаляет задачи, которые пролежали в
+// корзине дольше retention.
+type TrashPurger struct {
+	storage   storage.TrashStorage
+	retention time.Duration
+	interval  time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewTrashPurger(storage storage.TrashStorage, retention, interval time.Duration) *TrashPurger {
+	return &TrashPurger{
+		storage:   storage,
+		retention: retention,
+		interval:  interval,
+	}
+}
+
+// Start запускает фоновую очистку корзины раз в interval. Отмена ctx
+// останавливает ее так же, как Stop, и прерывает идущий проход.
+func (p *TrashPurger) Start(ctx context.Context) {
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.done = make(chan struct{})
+	go p.loop(ctx)
+}
+
+// Stop останавливает фоновую очистку и дожидается ее завершения.
+func (p *TrashPurger) Stop() {
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+	<-p.done
+	p.cancel = nil
+}
+
+func (p *TrashPurger) loop(ctx context.Context) {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := p.Run(ctx, time.Now())
+		// Ошибку прохода, прерванного остановкой, не пишем
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Failed to purge trash", slog.Any("error", err))
+		} else if err == nil && purged > 0 {
+			slog.Info("Purged tasks from trash", slog.Int("count", purged))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Run удаляет задачи, попавшие в корзину раньше now - retention, и
+// возвращает их число.
+func (p *TrashPurger) Run(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := tracer.Start(ctx, "TrashPurger.Run")
+	defer span.End()
+
+	return p.storage.Purge(ctx, now.Add(-p.retention))
+}
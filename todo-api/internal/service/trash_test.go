@@ -0,0 +1,66 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+func TestRestoreTask(t *testing.T) {
+	todos := NewTodoService(storage.NewMemoryStorage())
+	parent := createTask(t, todos, "user", models.CreateTaskRequest{Title: "Родитель"})
+	child := createTask(t, todos, "user", models.CreateTaskRequest{Title: "Подзадача", ParentID: parent.ID})
+
+	if err := todos.DeleteTask(t.Context(), "user", parent.ID, models.ChildrenDelete, nil); err != nil {
+		t.Fatalf("DeleteTask() error = %v", err)
+	}
+	if _, err := todos.GetTask(t.Context(), "user", child.ID); !errors.Is(err, storage.ErrTaskNotFound) {
+		t.Errorf("GetTask() of deleted subtask error = %v, want %v", err, storage.ErrTaskNotFound)
+	}
+	trash, err := todos.GetAllTasks(t.Context(), "user", models.TaskQuery{Limit: 10, Deleted: true})
+	if err != nil || trash.Total != 2 {
+		t.Errorf("trash = %d tasks, %v, want 2", trash.Total, err)
+	}
+
+	if _, err := todos.RestoreTask(t.Context(), "other", parent.ID); !errors.Is(err, storage.ErrTaskNotFound) {
+		t.Errorf("RestoreTask() by other user error = %v, want %v", err, storage.ErrTaskNotFound)
+	}
+	if _, err := todos.RestoreTask(t.Context(), "user", child.ID); !errors.Is(err, ErrParentDeleted) {
+		t.Errorf("RestoreTask() of subtask error = %v, want %v", err, ErrParentDeleted)
+	}
+
+	restored, err := todos.RestoreTask(t.Context(), "user", parent.ID)
+	if err != nil {
+		t.Fatalf("RestoreTask() error = %v", err)
+	}
+	if restored.DeletedAt != nil {
+		t.Errorf("restored task DeletedAt = %v, want nil", restored.DeletedAt)
+	}
+	if _, err := todos.GetTask(t.Context(), "user", child.ID); err != nil {
+		t.Errorf("GetTask() of restored subtask error = %v", err)
+	}
+}
+
+func TestTrashPurgerRun(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	todos := NewTodoService(store)
+	purger := NewTrashPurger(store, 30*24*time.Hour, time.Hour)
+
+	task := createTask(t, todos, "user", models.CreateTaskRequest{Title: "Старая"})
+	if err := todos.DeleteTask(t.Context(), "user", task.ID, models.ChildrenDelete, nil); err != nil {
+		t.Fatalf("DeleteTask() error = %v", err)
+	}
+
+	if purged, err := purger.Run(t.Context(), time.Now()); err != nil || purged != 0 {
+		t.Errorf("Run() = %d, %v, want 0", purged, err)
+	}
+	if purged, err := purger.Run(t.Context(), time.Now().Add(31*24*time.Hour)); err != nil || purged != 1 {
+		t.Errorf("Run() after retention = %d, %v, want 1", purged, err)
+	}
+	if _, err := todos.RestoreTask(t.Context(), "user", task.ID); !errors.Is(err, storage.ErrTaskNotFound) {
+		t.Errorf("RestoreTask() of purged task error = %v, want %v", err, storage.ErrTaskNotFound)
+	}
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"errors"
+	"slices"
+
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+// ErrPreconditionFailed - текущая версия задачи не входит в условие If-Match
+var ErrPreconditionFailed = errors.New("precondition failed: task has been modified")
+
+// checkIfMatch проверяет условие ifMatch - список версий, с одной из которых
+// клиент готов изменить задачу. nil означает отсутствие условия.
+func checkIfMatch(task models.Task, ifMatch []int64) error {
+	if ifMatch != nil && !slices.Contains(ifMatch, task.Version) {
+		return ErrPreconditionFailed
+	}
+	return nil
+}
+
+// versionError превращает конфликт версий в хранилище в ErrPreconditionFailed,
+// если клиент задал условие: задача изменилась уже после проверки ifMatch.
+func versionError(err error, ifMatch []int64) error {
+	if ifMatch != nil && errors.Is(err, storage.ErrVersionConflict) {
+		return ErrPreconditionFailed
+	}
+	return err
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+func TestIfMatch(t *testing.T) {
+	todos := NewTodoService(storage.NewMemoryStorage())
+	task := createTask(t, todos, "user", models.CreateTaskRequest{Title: "Отчёт"})
+
+	first := models.UpdateTaskRequest{Title: "Первый клиент"}
+	updated, err := todos.UpdateTask(t.Context(), "user", task.ID, first, []int64{task.Version})
+	if err != nil {
+		t.Fatalf("UpdateTask() error = %v", err)
+	}
+	if updated.Version != task.Version+1 {
+		t.Errorf("UpdateTask().Version = %d, want %d", updated.Version, task.Version+1)
+	}
+
+	// Второй клиент читал задачу до первого изменения
+	second := models.UpdateTaskRequest{Title: "Второй клиент"}
+	if _, err := todos.UpdateTask(t.Context(), "user", task.ID, second, []int64{task.Version}); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("UpdateTask() with stale If-Match error = %v, want %v", err, ErrPreconditionFailed)
+	}
+	if _, err := todos.CompleteTask(t.Context(), "user", task.ID, []int64{}); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("CompleteTask() with unknown ETag error = %v, want %v", err, ErrPreconditionFailed)
+	}
+	if err := todos.DeleteTask(t.Context(), "user", task.ID, models.ChildrenDelete, []int64{task.Version}); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("DeleteTask() with stale If-Match error = %v, want %v", err, ErrPreconditionFailed)
+	}
+	if got, _ := todos.GetTask(t.Context(), "user", task.ID); got.Title != "Первый клиент" {
+		t.Errorf("title after rejected updates = %q, want %q", got.Title, "Первый клиент")
+	}
+
+	if err := todos.DeleteTask(t.Context(), "user", task.ID, models.ChildrenDelete, []int64{task.Version, updated.Version}); err != nil {
+		t.Errorf("DeleteTask() with matching If-Match error = %v", err)
+	}
+}
@@ -0,0 +1,386 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"todo-api/internal/apperr"
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+var (
+	ErrInvalidWebhookURL = apperr.Validation("webhook url must be an absolute http or https url")
+	// ErrPrivateWebhookURL - вебхук указывает во внутреннюю сеть, а она не
+	// разрешена в WebhookConfig.AllowPrivateNetworks
+	ErrPrivateWebhookURL = apperr.Validation("webhook url must not point to a loopback, private or link-local address")
+	// ErrDeliveryNotDead - повторить можно только доставку из списка недоставленных
+	ErrDeliveryNotDead = apperr.Conflict("only dead deliveries can be retried")
+)
+
+// Размер страницы журнала доставок по умолчанию и наибольший
+const (
+	defaultDeliveryLimit = 20
+	maxDeliveryLimit     = 100
+)
+
+// WebhookService управляет вебхуками пользователя и журналом их доставок
+type WebhookService struct {
+	storage      storage.WebhookStorage
+	allowPrivate bool
+}
+
+// NewWebhookService проверяет адреса вебхуков по config.AllowPrivateNetworks;
+// остальные поля config нужны только WebhookDispatcher.
+func NewWebhookService(storage storage.WebhookStorage, config WebhookConfig) *WebhookService {
+	return &WebhookService{
+		storage:      storage,
+		allowPrivate: config.AllowPrivateNetworks,
+	}
+}
+
+// CreateWebhook создает активный вебхук. Ключ подписи возвращается только
+// в ответе на создание.
+func (s *WebhookService) CreateWebhook(ctx context.Context, userID string, req models.CreateWebhookRequest) (models.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.CreateWebhook")
+	defer span.End()
+
+	if err := s.validateWebhookURL(req.URL); err != nil {
+		return models.Webhook{}, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		if secret, err = randomToken(); err != nil {
+			return models.Webhook{}, err
+		}
+	}
+
+	return s.storage.CreateWebhook(ctx, models.Webhook{
+		OwnerID: userID,
+		URL:     req.URL,
+		Events:  normalizeWebhookEvents(req.Events),
+		Secret:  secret,
+		Active:  true,
+	})
+}
+
+func (s *WebhookService) GetWebhooks(ctx context.Context, userID string) ([]models.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.GetWebhooks")
+	defer span.End()
+
+	webhooks, err := s.storage.GetWebhooks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	if webhooks == nil {
+		webhooks = []models.Webhook{}
+	}
+	return webhooks, nil
+}
+
+func (s *WebhookService) GetWebhook(ctx context.Context, userID, id string) (models.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.GetWebhook")
+	defer span.End()
+
+	webhook, err := s.getWebhook(ctx, userID, id)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+func (s *WebhookService) UpdateWebhook(ctx context.Context, userID, id string, req models.UpdateWebhookRequest) (models.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.UpdateWebhook")
+	defer span.End()
+
+	webhook, err := s.getWebhook(ctx, userID, id)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	// Обновляем только переданные поля
+	if req.URL != "" {
+		if err := s.validateWebhookURL(req.URL); err != nil {
+			return models.Webhook{}, err
+		}
+		webhook.URL = req.URL
+	}
+	if req.Events != nil {
+		webhook.Events = normalizeWebhookEvents(req.Events)
+	}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+
+	updated, err := s.storage.UpdateWebhook(ctx, id, webhook)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	updated.Secret = ""
+	return updated, nil
+}
+
+// DeleteWebhook удаляет вебхук вместе с журналом его доставок
+func (s *WebhookService) DeleteWebhook(ctx context.Context, userID, id string) error {
+	ctx, span := tracer.Start(ctx, "WebhookService.DeleteWebhook")
+	defer span.End()
+
+	if _, err := s.getWebhook(ctx, userID, id); err != nil {
+		return err
+	}
+
+	return s.storage.DeleteWebhook(ctx, id)
+}
+
+// GetDeliveries возвращает журнал доставок вебхука от новых к старым,
+// при заданном status - только доставки в этом состоянии
+func (s *WebhookService) GetDeliveries(ctx context.Context, userID, id string, status models.DeliveryStatus, limit, offset int) (models.DeliveriesResponse, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.GetDeliveries")
+	defer span.End()
+
+	if _, err := s.getWebhook(ctx, userID, id); err != nil {
+		return models.DeliveriesResponse{}, err
+	}
+
+	return s.deliveries(ctx, models.DeliveryQuery{WebhookID: id, Status: status, Limit: limit, Offset: offset})
+}
+
+// GetDeadLetters возвращает недоставленные события всех вебхуков пользователя
+func (s *WebhookService) GetDeadLetters(ctx context.Context, userID string, limit, offset int) (models.DeliveriesResponse, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.GetDeadLetters")
+	defer span.End()
+
+	return s.deliveries(ctx, models.DeliveryQuery{OwnerID: userID, Status: models.DeliveryDead, Limit: limit, Offset: offset})
+}
+
+// RetryDelivery возвращает недоставленное событие в очередь: счетчик
+// попыток обнуляется, первая попытка - при следующем проходе доставки.
+func (s *WebhookService) RetryDelivery(ctx context.Context, userID, webhookID, deliveryID string) (models.WebhookDelivery, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.RetryDelivery")
+	defer span.End()
+
+	if _, err := s.getWebhook(ctx, userID, webhookID); err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	if err := validateUUID(deliveryID); err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	delivery, err := s.storage.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	if delivery.WebhookID != webhookID {
+		return models.WebhookDelivery{}, storage.ErrDeliveryNotFound
+	}
+	if delivery.Status != models.DeliveryDead {
+		return models.WebhookDelivery{}, ErrDeliveryNotDead
+	}
+
+	next := time.Now()
+	delivery.Status = models.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = &next
+	if err := s.storage.UpdateDelivery(ctx, delivery); err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	return delivery, nil
+}
+
+func (s *WebhookService) deliveries(ctx context.Context, query models.DeliveryQuery) (models.DeliveriesResponse, error) {
+	if query.Limit <= 0 {
+		query.Limit = defaultDeliveryLimit
+	}
+	query.Limit = min(query.Limit, maxDeliveryLimit)
+	query.Offset = max(query.Offset, 0)
+
+	deliveries, total, err := s.storage.GetDeliveries(ctx, query)
+	if err != nil {
+		return models.DeliveriesResponse{}, err
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+
+	return models.DeliveriesResponse{
+		Deliveries: deliveries,
+		Total:      total,
+		Limit:      query.Limit,
+		Offset:     query.Offset,
+	}, nil
+}
+
+// getWebhook возвращает вебхук пользователя. Чужой вебхук не отличается
+// от несуществующего.
+func (s *WebhookService) getWebhook(ctx context.Context, userID, id string) (models.Webhook, error) {
+	if err := validateUUID(id); err != nil {
+		return models.Webhook{}, err
+	}
+
+	webhook, err := s.storage.GetWebhook(ctx, id)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	if webhook.OwnerID != userID {
+		return models.Webhook{}, storage.ErrWebhookNotFound
+	}
+
+	return webhook, nil
+}
+
+// validateWebhookURL отклоняет адреса, которые заведомо ведут во внутреннюю
+// сеть. Имя может разрешиться в такой адрес и позже, поэтому окончательная
+// проверка - при соединении, в webhookDialControl.
+func (s *WebhookService) validateWebhookURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidWebhookURL
+	}
+	if s.allowPrivate {
+		return nil
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrPrivateWebhookURL
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !isPublicAddr(addr) {
+		return ErrPrivateWebhookURL
+	}
+	return nil
+}
+
+// Диапазоны, которые не отмечены в netip, но из интернета недоступны:
+// "этот хост" (RFC 1122) и операторский NAT (RFC 6598)
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+}
+
+// isPublicAddr сообщает, можно ли отправлять вебхук на addr без
+// AllowPrivateNetworks. Закрыты loopback, частные сети, link-local (в том
+// числе адрес метаданных облака 169.254.169.254), multicast и
+// неопределенный адрес.
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeWebhookEvents убирает повторы; пустой список означает все события
+func normalizeWebhookEvents(events []models.WebhookEvent) []models.WebhookEvent {
+	if len(events) == 0 {
+		return slices.Clone(models.WebhookEvents)
+	}
+
+	var normalized []models.WebhookEvent
+	for _, event := range models.WebhookEvents {
+		if slices.Contains(events, event) {
+			normalized = append(normalized, event)
+		}
+	}
+	return normalized
+}
+
+// notifyWebhooks ставит в очередь доставку события задачи after всем
+// активным вебхукам, подписанным на него, у пользователей, которые видят
+// задачу: владельца личной задачи или участников ее списка.
+func (s *TodoService) notifyWebhooks(ctx context.Context, actorID string, action models.HistoryAction, before, after models.Task) error {
+	event := webhookEvent(action, before, after)
+
+	recipients := []string{after.OwnerID}
+	if after.ListID != "" {
+		members, err := s.storage.GetMembers(ctx, after.ListID)
+		if err != nil && !errors.Is(err, storage.ErrListNotFound) {
+			return err
+		}
+		recipients = recipients[:0]
+		for _, member := range members {
+			recipients = append(recipients, member.UserID)
+		}
+	}
+
+	var webhooks []models.Webhook
+	for _, userID := range recipients {
+		owned, err := s.storage.GetWebhooks(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, webhook := range owned {
+			if webhook.Active && slices.Contains(webhook.Events, event) {
+				webhooks = append(webhooks, webhook)
+			}
+		}
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	eventID := uuid.New().String()
+	occurredAt := time.Now()
+	payload, err := json.Marshal(models.WebhookPayload{
+		ID:         eventID,
+		Event:      event,
+		ActorID:    actorID,
+		Task:       after,
+		OccurredAt: occurredAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	deliveries := make([]models.WebhookDelivery, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		deliveries = append(deliveries, models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       eventID,
+			Event:         event,
+			TaskID:        after.ID,
+			Payload:       payload,
+			Status:        models.DeliveryPending,
+			NextAttemptAt: &occurredAt,
+		})
+	}
+
+	return s.storage.AddDeliveries(ctx, deliveries)
+}
+
+// webhookEvent сопоставляет действие из истории событию вебхука.
+// Восстановление из корзины сообщается как task.updated.
+func webhookEvent(action models.HistoryAction, before, after models.Task) models.WebhookEvent {
+	switch {
+	case action == models.HistoryCreated:
+		return models.WebhookTaskCreated
+	case action == models.HistoryDeleted:
+		return models.WebhookTaskDeleted
+	case !before.Completed && after.Completed:
+		return models.WebhookTaskCompleted
+	default:
+		return models.WebhookTaskUpdated
+	}
+}
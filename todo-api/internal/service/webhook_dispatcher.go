@@ -0,0 +1,293 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"sync"
+	"syscall"
+	"time"
+
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+// webhookBatchSize - сколько доставок выбирается из очереди за один запрос
+const webhookBatchSize = 20
+
+// maxWebhookBackoff ограничивает задержку между попытками: дальше она
+// перестает удваиваться
+const maxWebhookBackoff = 24 * time.Hour
+
+// Заголовки запроса вебхука. Подпись - HMAC-SHA256 тела запроса с ключом
+// вебхука в hex с префиксом "sha256=".
+const (
+	webhookEventHeader     = "X-Webhook-Event"
+	webhookDeliveryHeader  = "X-Webhook-Delivery"
+	webhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// WebhookConfig настраивает доставку вебхуков
+type WebhookConfig struct {
+	// Interval - как часто искать доставки, которым пора отправиться
+	Interval time.Duration
+	// Timeout ограничивает одну попытку доставки
+	Timeout time.Duration
+	// MaxAttempts - после стольких неудачных попыток доставка попадает в
+	// список недоставленных
+	MaxAttempts int
+	// Backoff - задержка перед второй попыткой; каждая следующая вдвое длиннее,
+	// но не больше maxWebhookBackoff
+	Backoff time.Duration
+	// AllowPrivateNetworks разрешает вебхуки на loopback, частные и
+	// link-local адреса
+	AllowPrivateNetworks bool
+}
+
+// WebhookDispatcher отправляет доставки вебхуков из очереди в хранилище.
+// Успешным считается ответ 2xx; после неудачи попытка повторяется с
+// экспоненциальной задержкой, пока не исчерпан MaxAttempts.
+type WebhookDispatcher struct {
+	storage storage.WebhookStorage
+	config  WebhookConfig
+	client  *http.Client
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewWebhookDispatcher(storage storage.WebhookStorage, config WebhookConfig) *WebhookDispatcher {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !config.AllowPrivateNetworks {
+		// Адрес проверяется уже после разрешения имени, перед каждым
+		// соединением: так его не обойти ни перенаправлением, ни DNS, который
+		// после создания вебхука стал отвечать внутренним адресом
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   webhookDialControl,
+		}
+		transport.DialContext = dialer.DialContext
+		// Через прокси соединение шло бы к адресу прокси, а не получателя
+		transport.Proxy = nil
+	}
+
+	return &WebhookDispatcher{
+		storage: storage,
+		config:  config,
+		client:  &http.Client{Timeout: config.Timeout, Transport: transport},
+	}
+}
+
+// webhookDialControl не дает соединиться с адресом во внутренней сети
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !isPublicAddr(addr) {
+		return fmt.Errorf("webhook destination %s is not a public address", addr)
+	}
+	return nil
+}
+
+// Start запускает фоновую доставку раз в Interval. Отмена ctx
+// останавливает ее так же, как Stop, и прерывает идущий проход.
+func (d *WebhookDispatcher) Start(ctx context.Context) {
+	ctx, d.cancel = context.WithCancel(ctx)
+	d.done = make(chan struct{})
+	go d.loop(ctx)
+}
+
+// Stop останавливает фоновую доставку и дожидается ее завершения.
+func (d *WebhookDispatcher) Stop() {
+	if d.cancel == nil {
+		return
+	}
+	d.cancel()
+	<-d.done
+	d.cancel = nil
+}
+
+func (d *WebhookDispatcher) loop(ctx context.Context) {
+	defer close(d.done)
+
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		// Ошибку прохода, прерванного остановкой, не пишем
+		if _, err := d.Run(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			slog.Error("Failed to deliver webhooks", slog.Any("error", err))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Run делает по одной попытке для всех доставок, которым к моменту now пора
+// отправиться, и возвращает число попыток. Повторные попытки назначаются
+// не раньше следующего прохода.
+func (d *WebhookDispatcher) Run(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := tracer.Start(ctx, "WebhookDispatcher.Run")
+	defer span.End()
+
+	// Пока идут попытки, доставки выборки не возьмет другой экземпляр
+	// сервера. Доставки одного вебхука отправляются по очереди, и в выборке
+	// их может быть до webhookBatchSize, поэтому аренда рассчитана на худший
+	// случай с запасом в одну попытку на запись результатов. Доставки
+	// упавшего экземпляра вернутся в очередь через это же время.
+	lease := (webhookBatchSize + 1) * d.config.Timeout
+
+	attempted := 0
+	for {
+		deliveries, err := d.storage.ClaimDeliveries(ctx, now, lease, webhookBatchSize)
+		if err != nil {
+			return attempted, err
+		}
+		if len(deliveries) == 0 {
+			return attempted, nil
+		}
+
+		// Разные вебхуки получают доставки параллельно, один вебхук - по
+		// очереди, чтобы события одной задачи приходили в порядке изменений.
+		// После неудачной попытки порядок уже не гарантируется.
+		var (
+			order     []string
+			byWebhook = make(map[string][]models.WebhookDelivery)
+		)
+		for _, delivery := range deliveries {
+			if _, ok := byWebhook[delivery.WebhookID]; !ok {
+				order = append(order, delivery.WebhookID)
+			}
+			byWebhook[delivery.WebhookID] = append(byWebhook[delivery.WebhookID], delivery)
+		}
+
+		errs := make([]error, len(order))
+		var wg sync.WaitGroup
+		for i, webhookID := range order {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for _, delivery := range byWebhook[webhookID] {
+					errs[i] = errors.Join(errs[i], d.attempt(ctx, delivery, now))
+				}
+			}()
+		}
+		wg.Wait()
+
+		attempted += len(deliveries)
+		if err := errors.Join(errs...); err != nil {
+			return attempted, err
+		}
+	}
+}
+
+// attempt отправляет доставку и сохраняет результат попытки; повторная
+// попытка назначается относительно now
+func (d *WebhookDispatcher) attempt(ctx context.Context, delivery models.WebhookDelivery, now time.Time) error {
+	webhook, err := d.storage.GetWebhook(ctx, delivery.WebhookID)
+	if errors.Is(err, storage.ErrWebhookNotFound) {
+		// Вебхук удален вместе с доставками, пока шла выборка
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	delivery.Attempts++
+	if !webhook.Active {
+		delivery.LastStatusCode = 0
+		delivery.LastError = "webhook is disabled"
+		delivery.Status = models.DeliveryDead
+		delivery.NextAttemptAt = nil
+		return d.storage.UpdateDelivery(ctx, delivery)
+	}
+
+	delivery.LastStatusCode, err = d.send(ctx, webhook, delivery)
+	if ctx.Err() != nil {
+		// Доставку прервала остановка сервера: попытка не засчитывается, а
+		// доставка вернется в очередь, когда истечет ее аренда
+		return ctx.Err()
+	}
+	switch {
+	case err == nil:
+		delivery.LastError = ""
+		delivery.Status = models.DeliveryDelivered
+		delivery.NextAttemptAt = nil
+	case delivery.Attempts >= d.config.MaxAttempts:
+		delivery.LastError = err.Error()
+		delivery.Status = models.DeliveryDead
+		delivery.NextAttemptAt = nil
+	default:
+		delivery.LastError = err.Error()
+		next := now.Add(retryDelay(d.config.Backoff, delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	}
+
+	return d.storage.UpdateDelivery(ctx, delivery)
+}
+
+// retryDelay возвращает задержку после attempts неудачных попыток. Задержка
+// удваивается до maxWebhookBackoff без сдвига на attempts, который
+// переполнил бы time.Duration.
+func retryDelay(backoff time.Duration, attempts int) time.Duration {
+	delay := min(backoff, maxWebhookBackoff)
+	for range attempts - 1 {
+		if delay >= maxWebhookBackoff/2 {
+			return maxWebhookBackoff
+		}
+		delay *= 2
+	}
+	return delay
+}
+
+// send отправляет подписанное тело доставки и возвращает статус ответа
+func (d *WebhookDispatcher) send(ctx context.Context, webhook models.Webhook, delivery models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "todo-api-webhooks")
+	req.Header.Set(webhookEventHeader, string(delivery.Event))
+	req.Header.Set(webhookDeliveryHeader, delivery.ID)
+	req.Header.Set(webhookSignatureHeader, SignWebhook(webhook.Secret, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Тело ответа не нужно, но дочитываем его, чтобы соединение вернулось в пул
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhook возвращает значение заголовка X-Webhook-Signature для тела body
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,421 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+// webhookReceiver - тестовый получатель вебхуков, отвечающий status
+type webhookReceiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newWebhookReceiver(t *testing.T, status int) (*webhookReceiver, *httptest.Server) {
+	receiver := &webhookReceiver{status: status}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+		receiver.requests = append(receiver.requests, r)
+		receiver.bodies = append(receiver.bodies, body)
+		w.WriteHeader(receiver.status)
+	}))
+	t.Cleanup(server.Close)
+	return receiver, server
+}
+
+func (r *webhookReceiver) events() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var events []string
+	for _, req := range r.requests {
+		events = append(events, req.Header.Get(webhookEventHeader))
+	}
+	return events
+}
+
+// Тестовые получатели слушают 127.0.0.1
+var testWebhookConfig = WebhookConfig{
+	Timeout:              time.Second,
+	MaxAttempts:          3,
+	Backoff:              time.Minute,
+	AllowPrivateNetworks: true,
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	todos := NewTodoService(store)
+	webhooks := NewWebhookService(store, testWebhookConfig)
+	dispatcher := NewWebhookDispatcher(store, testWebhookConfig)
+	receiver, server := newWebhookReceiver(t, http.StatusNoContent)
+
+	webhook, err := webhooks.CreateWebhook(t.Context(), "user", models.CreateWebhookRequest{URL: server.URL})
+	if err != nil {
+		t.Fatalf("CreateWebhook() error = %v", err)
+	}
+	if webhook.Secret == "" || len(webhook.Events) != len(models.WebhookEvents) {
+		t.Fatalf("CreateWebhook() = %+v, want generated secret and all events", webhook)
+	}
+	if _, err := webhooks.CreateWebhook(t.Context(), "other", models.CreateWebhookRequest{URL: server.URL}); err != nil {
+		t.Fatalf("CreateWebhook() error = %v", err)
+	}
+
+	task := createTask(t, todos, "user", models.CreateTaskRequest{Title: "Задача"})
+	if _, err := todos.CompleteTask(t.Context(), "user", task.ID, nil); err != nil {
+		t.Fatalf("CompleteTask() error = %v", err)
+	}
+	if err := todos.DeleteTask(t.Context(), "user", task.ID, models.ChildrenDelete, nil); err != nil {
+		t.Fatalf("DeleteTask() error = %v", err)
+	}
+
+	if sent, err := dispatcher.Run(t.Context(), time.Now()); err != nil || sent != 3 {
+		t.Fatalf("Run() = %d, %v, want 3", sent, err)
+	}
+
+	// Вебхук другого пользователя не получает событий чужой задачи
+	events := receiver.events()
+	if len(events) != 3 || events[0] != "task.created" || events[1] != "task.completed" || events[2] != "task.deleted" {
+		t.Fatalf("received events = %v", events)
+	}
+
+	req, body := receiver.requests[0], receiver.bodies[0]
+	if got, want := req.Header.Get(webhookSignatureHeader), SignWebhook(webhook.Secret, body); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+	var payload models.WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil || payload.Task.ID != task.ID || payload.ActorID != "user" {
+		t.Errorf("payload = %s, %v", body, err)
+	}
+
+	log, err := webhooks.GetDeliveries(t.Context(), "user", webhook.ID, models.DeliveryDelivered, 0, 0)
+	if err != nil || log.Total != 3 || log.Deliveries[0].Attempts != 1 || log.Deliveries[0].LastStatusCode != http.StatusNoContent {
+		t.Errorf("GetDeliveries() = %+v, %v", log, err)
+	}
+	if _, err := webhooks.GetDeliveries(t.Context(), "other", webhook.ID, "", 0, 0); !errors.Is(err, storage.ErrWebhookNotFound) {
+		t.Errorf("GetDeliveries() by other user error = %v, want %v", err, storage.ErrWebhookNotFound)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	todos := NewTodoService(store)
+	webhooks := NewWebhookService(store, testWebhookConfig)
+	dispatcher := NewWebhookDispatcher(store, testWebhookConfig)
+	receiver, server := newWebhookReceiver(t, http.StatusInternalServerError)
+
+	webhook, err := webhooks.CreateWebhook(t.Context(), "user", models.CreateWebhookRequest{
+		URL:    server.URL,
+		Events: []models.WebhookEvent{models.WebhookTaskCreated},
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhook() error = %v", err)
+	}
+	createTask(t, todos, "user", models.CreateTaskRequest{Title: "Задача"})
+
+	// Задержка удваивается: 1 и 2 минуты после первой и второй попытки
+	now := time.Now()
+	for _, step := range []struct {
+		at   time.Duration
+		sent int
+	}{{0, 1}, {59 * time.Second, 0}, {time.Minute, 1}, {3*time.Minute - time.Second, 0}, {3 * time.Minute, 1}} {
+		if sent, err := dispatcher.Run(t.Context(), now.Add(step.at)); err != nil || sent != step.sent {
+			t.Fatalf("Run(+%s) = %d, %v, want %d", step.at, sent, err, step.sent)
+		}
+	}
+
+	dead, err := webhooks.GetDeadLetters(t.Context(), "user", 0, 0)
+	if err != nil || dead.Total != 1 {
+		t.Fatalf("GetDeadLetters() = %+v, %v", dead, err)
+	}
+	delivery := dead.Deliveries[0]
+	if delivery.Attempts != 3 || delivery.LastStatusCode != http.StatusInternalServerError || delivery.NextAttemptAt != nil {
+		t.Errorf("dead delivery = %+v", delivery)
+	}
+	if _, err := dispatcher.Run(t.Context(), now.Add(time.Hour)); err != nil || len(receiver.events()) != 3 {
+		t.Errorf("dead delivery was attempted again: %d requests, %v", len(receiver.events()), err)
+	}
+
+	// Повторенная доставка снова попадает в очередь
+	receiver.mu.Lock()
+	receiver.status = http.StatusOK
+	receiver.mu.Unlock()
+	if _, err := webhooks.RetryDelivery(t.Context(), "user", webhook.ID, delivery.ID); err != nil {
+		t.Fatalf("RetryDelivery() error = %v", err)
+	}
+	if _, err := webhooks.RetryDelivery(t.Context(), "user", webhook.ID, delivery.ID); !errors.Is(err, ErrDeliveryNotDead) {
+		t.Errorf("RetryDelivery() of pending delivery error = %v, want %v", err, ErrDeliveryNotDead)
+	}
+	if sent, err := dispatcher.Run(t.Context(), time.Now()); err != nil || sent != 1 {
+		t.Fatalf("Run() after retry = %d, %v, want 1", sent, err)
+	}
+	if got, _ := store.GetDelivery(t.Context(), delivery.ID); got.Status != models.DeliveryDelivered || got.Attempts != 1 {
+		t.Errorf("retried delivery = %+v", got)
+	}
+}
+
+func TestWebhookRetryDelayCap(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	todos := NewTodoService(store)
+	config := testWebhookConfig
+	config.MaxAttempts = math.MaxInt
+	webhooks := NewWebhookService(store, config)
+	dispatcher := NewWebhookDispatcher(store, config)
+	_, server := newWebhookReceiver(t, http.StatusInternalServerError)
+
+	webhook, err := webhooks.CreateWebhook(t.Context(), "user", models.CreateWebhookRequest{
+		URL:    server.URL,
+		Events: []models.WebhookEvent{models.WebhookTaskCreated},
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhook() error = %v", err)
+	}
+	createTask(t, todos, "user", models.CreateTaskRequest{Title: "Задача"})
+
+	// Сдвиг Backoff на столько попыток переполнил бы time.Duration
+	deliveries, _, err := store.GetDeliveries(t.Context(), models.DeliveryQuery{WebhookID: webhook.ID, Limit: 10})
+	if err != nil || len(deliveries) != 1 {
+		t.Fatalf("GetDeliveries() = %+v, %v", deliveries, err)
+	}
+	delivery := deliveries[0]
+	delivery.Attempts = 100
+	if err := store.UpdateDelivery(t.Context(), delivery); err != nil {
+		t.Fatalf("UpdateDelivery() error = %v", err)
+	}
+
+	now := time.Now()
+	if sent, err := dispatcher.Run(t.Context(), now); err != nil || sent != 1 {
+		t.Fatalf("Run() = %d, %v, want 1", sent, err)
+	}
+	got, err := store.GetDelivery(t.Context(), delivery.ID)
+	if err != nil || got.Attempts != 101 || got.NextAttemptAt == nil || !got.NextAttemptAt.Equal(now.Add(maxWebhookBackoff)) {
+		t.Errorf("GetDelivery() = %+v, %v, want next attempt at %s", got, err, now.Add(maxWebhookBackoff))
+	}
+
+	for _, tc := range []struct {
+		backoff  time.Duration
+		attempts int
+		want     time.Duration
+	}{
+		{time.Minute, 1, time.Minute},
+		{time.Minute, 3, 4 * time.Minute},
+		{time.Hour, 6, maxWebhookBackoff},
+		{time.Nanosecond, math.MaxInt, maxWebhookBackoff},
+		{48 * time.Hour, 1, maxWebhookBackoff},
+	} {
+		if got := retryDelay(tc.backoff, tc.attempts); got != tc.want {
+			t.Errorf("retryDelay(%s, %d) = %s, want %s", tc.backoff, tc.attempts, got, tc.want)
+		}
+	}
+}
+
+func TestWebhookLease(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	todos := NewTodoService(store)
+	webhooks := NewWebhookService(store, testWebhookConfig)
+	dispatcher := NewWebhookDispatcher(store, testWebhookConfig)
+
+	// Во время первой попытки другой экземпляр сервера пробует забрать
+	// доставки из очереди с часами, которые показывают время последней
+	// попытки, если бы каждая занимала весь Timeout
+	const tasks = 5
+	var (
+		now      time.Time
+		mu       sync.Mutex
+		requests int
+		stolen   []models.WebhookDelivery
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		if requests == 1 {
+			claimed, err := store.ClaimDeliveries(r.Context(), now.Add(tasks*testWebhookConfig.Timeout), testWebhookConfig.Timeout, webhookBatchSize)
+			if err != nil {
+				t.Errorf("ClaimDeliveries() error = %v", err)
+			}
+			stolen = claimed
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	if _, err := webhooks.CreateWebhook(t.Context(), "user", models.CreateWebhookRequest{
+		URL:    server.URL,
+		Events: []models.WebhookEvent{models.WebhookTaskCreated},
+	}); err != nil {
+		t.Fatalf("CreateWebhook() error = %v", err)
+	}
+	for range tasks {
+		createTask(t, todos, "user", models.CreateTaskRequest{Title: "Задача"})
+	}
+
+	now = time.Now()
+	if sent, err := dispatcher.Run(t.Context(), now); err != nil || sent != tasks {
+		t.Fatalf("Run() = %d, %v, want %d", sent, err, tasks)
+	}
+	// Доставки, которые ждут своей очереди за другими доставками того же
+	// вебхука, все еще в аренде
+	if len(stolen) != 0 {
+		t.Errorf("%d deliveries were claimed again while the batch was being sent", len(stolen))
+	}
+}
+
+func TestWebhookListMembers(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	todos := NewTodoService(store)
+	webhooks := NewWebhookService(store, testWebhookConfig)
+
+	owner, _ := store.CreateUser(t.Context(), models.User{Username: "owner"})
+	member, _ := store.CreateUser(t.Context(), models.User{Username: "member"})
+	lists := NewListService(store)
+	list, err := lists.CreateList(t.Context(), owner.ID, models.CreateListRequest{Name: "Дом"})
+	if err != nil {
+		t.Fatalf("CreateList() error = %v", err)
+	}
+	if _, err := lists.AddMember(t.Context(), owner.ID, list.ID, models.AddMemberRequest{Username: "member", Role: models.RoleViewer}); err != nil {
+		t.Fatalf("AddMember() error = %v", err)
+	}
+
+	for _, userID := range []string{owner.ID, member.ID, "outsider"} {
+		if _, err := webhooks.CreateWebhook(t.Context(), userID, models.CreateWebhookRequest{URL: "https://example.com/" + userID}); err != nil {
+			t.Fatalf("CreateWebhook() error = %v", err)
+		}
+	}
+	if _, err := webhooks.CreateWebhook(t.Context(), "user", models.CreateWebhookRequest{URL: "ftp://example.com"}); !errors.Is(err, ErrInvalidWebhookURL) {
+		t.Errorf("CreateWebhook(ftp) error = %v, want %v", err, ErrInvalidWebhookURL)
+	}
+
+	createTask(t, todos, owner.ID, models.CreateTaskRequest{Title: "Общая", ListID: list.ID})
+
+	for userID, want := range map[string]int{owner.ID: 1, member.ID: 1, "outsider": 0} {
+		pending, _, err := store.GetDeliveries(t.Context(), models.DeliveryQuery{OwnerID: userID, Limit: 10})
+		if err != nil || len(pending) != want {
+			t.Errorf("deliveries of %s = %d, %v, want %d", userID, len(pending), err, want)
+		}
+	}
+}
+
+func TestWebhookDispatcherStop(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	todos := NewTodoService(store)
+	webhooks := NewWebhookService(store, testWebhookConfig)
+
+	// Получатель не отвечает, пока доставку не прервут
+	arrived := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Обрыв соединения сервер замечает только после чтения тела
+		io.ReadAll(r.Body)
+		arrived <- struct{}{}
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+
+	webhook, err := webhooks.CreateWebhook(t.Context(), "user", models.CreateWebhookRequest{
+		URL:    server.URL,
+		Events: []models.WebhookEvent{models.WebhookTaskCreated},
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhook() error = %v", err)
+	}
+	createTask(t, todos, "user", models.CreateTaskRequest{Title: "Задача"})
+
+	config := testWebhookConfig
+	config.Interval = time.Hour
+	config.Timeout = time.Minute
+	dispatcher := NewWebhookDispatcher(store, config)
+	dispatcher.Start(t.Context())
+
+	select {
+	case <-arrived:
+	case <-time.After(5 * time.Second):
+		t.Fatal("delivery was not attempted")
+	}
+	dispatcher.Stop()
+
+	// Прерванная попытка не засчитывается
+	log, err := webhooks.GetDeliveries(t.Context(), "user", webhook.ID, models.DeliveryPending, 0, 0)
+	if err != nil || log.Total != 1 || log.Deliveries[0].Attempts != 0 {
+		t.Errorf("GetDeliveries() after Stop = %+v, %v", log, err)
+	}
+}
+
+func TestWebhookPrivateNetworks(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	todos := NewTodoService(store)
+	config := testWebhookConfig
+	config.AllowPrivateNetworks = false
+	webhooks := NewWebhookService(store, config)
+	dispatcher := NewWebhookDispatcher(store, config)
+	receiver, server := newWebhookReceiver(t, http.StatusNoContent)
+
+	for _, rawURL := range []string{
+		server.URL,
+		"http://localhost:8080/hook",
+		"http://api.localhost/hook",
+		"http://10.0.0.1/hook",
+		"http://192.168.1.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://100.64.0.1/hook",
+		"http://0.0.0.0/hook",
+		"http://[::1]/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+		"http://[fd00::1]/hook",
+	} {
+		if _, err := webhooks.CreateWebhook(t.Context(), "user", models.CreateWebhookRequest{URL: rawURL}); !errors.Is(err, ErrPrivateWebhookURL) {
+			t.Errorf("CreateWebhook(%s) error = %v, want %v", rawURL, err, ErrPrivateWebhookURL)
+		}
+	}
+	webhook, err := webhooks.CreateWebhook(t.Context(), "user", models.CreateWebhookRequest{URL: "https://93.184.215.14/hook"})
+	if err != nil {
+		t.Fatalf("CreateWebhook(public address) error = %v", err)
+	}
+	if _, err := webhooks.UpdateWebhook(t.Context(), "user", webhook.ID, models.UpdateWebhookRequest{URL: "http://127.0.0.1/hook"}); !errors.Is(err, ErrPrivateWebhookURL) {
+		t.Errorf("UpdateWebhook(loopback) error = %v, want %v", err, ErrPrivateWebhookURL)
+	}
+	if err := webhooks.DeleteWebhook(t.Context(), "user", webhook.ID); err != nil {
+		t.Fatalf("DeleteWebhook() error = %v", err)
+	}
+
+	// Имя, которое разрешается во внутренний адрес уже после проверки
+	// при создании, останавливает проверка при соединении
+	port := server.URL[strings.LastIndex(server.URL, ":"):]
+	if _, err := store.CreateWebhook(t.Context(), models.Webhook{
+		OwnerID: "user",
+		URL:     "http://localhost" + port,
+		Events:  []models.WebhookEvent{models.WebhookTaskCreated},
+		Secret:  "secret",
+		Active:  true,
+	}); err != nil {
+		t.Fatalf("CreateWebhook() error = %v", err)
+	}
+	createTask(t, todos, "user", models.CreateTaskRequest{Title: "Задача"})
+
+	if sent, err := dispatcher.Run(t.Context(), time.Now()); err != nil || sent != 1 {
+		t.Fatalf("Run() = %d, %v, want 1", sent, err)
+	}
+	if events := receiver.events(); len(events) != 0 {
+		t.Errorf("private receiver got %v", events)
+	}
+	pending, _, err := store.GetDeliveries(t.Context(), models.DeliveryQuery{OwnerID: "user", Status: models.DeliveryPending, Limit: 10})
+	if err != nil || len(pending) != 1 || !strings.Contains(pending[0].LastError, "not a public address") {
+		t.Errorf("deliveries = %+v, %v, want one pending with a blocked destination", pending, err)
+	}
+}
@@ -0,0 +1,321 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"todo-api/internal/models"
+)
+
+// Операции, которые записываются в журнал MemoryStorage.
+const (
+	opCreate   = "create"
+	opUpdate   = "update"
+	opDelete   = "delete"
+	opComplete = "complete"
+	opRemind   = "remind"
+
+	opAddHistory = "add_history"
+	// opBatch объединяет изменения ApplyBatch в одну запись, чтобы при
+	// восстановлении они применялись целиком или не применялись вовсе
+	opBatch = "batch"
+
+	opAddDependency    = "add_dependency"
+	opRemoveDependency = "remove_dependency"
+
+	opPutTag    = "put_tag"
+	opUpdateTag = "update_tag"
+	opDeleteTag = "delete_tag"
+
+	opCreateUser = "create_user"
+
+	opPutList      = "put_list"
+	opDeleteList   = "delete_list"
+	opPutMember    = "put_member"
+	opRemoveMember = "remove_member"
+
+	opPutRefreshToken   = "put_refresh_token"
+	opRevokeAccessToken = "revoke_access_token"
+
+	opPutWebhook    = "put_webhook"
+	opDeleteWebhook = "delete_webhook"
+	opPutDelivery   = "put_delivery"
+)
+
+// journalHeaderSize - длина (uint32) и CRC32 (uint32) полезной нагрузки записи.
+const journalHeaderSize = 8
+
+// maxJournalRecordSize ограничивает длину записи при чтении, чтобы
+// повреждённый заголовок не приводил к попытке выделить гигабайты памяти.
+const maxJournalRecordSize = 16 << 20
+
+type journalRecord struct {
+	Seq  uint64       `json:"seq"`
+	Op   string       `json:"op"`
+	ID   string       `json:"id,omitempty"`
+	Task *models.Task `json:"task,omitempty"`
+	User *storedUser  `json:"user,omitempty"`
+
+	BlockerID string      `json:"blocker_id,omitempty"`
+	Tag       *models.Tag `json:"tag,omitempty"`
+
+	List   *models.List       `json:"list,omitempty"`
+	Member *models.ListMember `json:"member,omitempty"`
+	UserID string             `json:"user_id,omitempty"`
+
+	RefreshToken *models.RefreshToken `json:"refresh_token,omitempty"`
+	ExpiresAt    *time.Time           `json:"expires_at,omitempty"`
+
+	History *models.HistoryEntry `json:"history,omitempty"`
+
+	Webhook  *models.Webhook         `json:"webhook,omitempty"`
+	Delivery *models.WebhookDelivery `json:"delivery,omitempty"`
+
+	Batch []journalRecord `json:"batch,omitempty"`
+}
+
+// storedUser - пользователь в журнале и снимке. В отличие от models.User
+// сериализует хеш пароля, который нельзя отдавать через API.
+type storedUser struct {
+	ID           string    `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"password_hash"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func newStoredUser(user models.User) storedUser {
+	return storedUser(user)
+}
+
+func (u storedUser) toModel() models.User {
+	return models.User(u)
+}
+
+// dependency - зависимость между задачами в снимке
+type dependency struct {
+	TaskID    string `json:"task_id"`
+	BlockerID string `json:"blocker_id"`
+}
+
+// journal - журнал упреждающей записи. Каждая запись хранится как
+// заголовок (длина + CRC32) и JSON, поэтому оборванная при сбое запись
+// в конце файла обнаруживается и отбрасывается при открытии.
+type journal struct {
+	file *os.File
+	size int64
+	seq  uint64
+	sync bool
+}
+
+// openJournal открывает (или создаёт) журнал и возвращает все целые записи.
+// Хвост файла после последней целой записи обрезается.
+func openJournal(path string, sync bool) (*journal, []journalRecord, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	records, size, err := readJournal(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	if err := file.Truncate(size); err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("truncate journal: %w", err)
+	}
+	if _, err := file.Seek(size, io.SeekStart); err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	j := &journal{file: file, size: size, sync: sync}
+	if len(records) > 0 {
+		j.seq = records[len(records)-1].Seq
+	}
+
+	return j, records, nil
+}
+
+// readJournal читает записи до конца файла или до первой повреждённой записи
+// и возвращает смещение, на котором закончились целые записи.
+func readJournal(r io.Reader) ([]journalRecord, int64, error) {
+	reader := bufio.NewReader(r)
+	header := make([]byte, journalHeaderSize)
+
+	var (
+		records []journalRecord
+		offset  int64
+	)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return records, offset, nil
+			}
+			return nil, 0, err
+		}
+
+		length := binary.LittleEndian.Uint32(header[0:4])
+		checksum := binary.LittleEndian.Uint32(header[4:8])
+		if length == 0 || length > maxJournalRecordSize {
+			return records, offset, nil
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return records, offset, nil
+			}
+			return nil, 0, err
+		}
+		if crc32.ChecksumIEEE(payload) != checksum {
+			return records, offset, nil
+		}
+
+		var record journalRecord
+		if err := json.Unmarshal(payload, &record); err != nil {
+			return records, offset, nil
+		}
+
+		records = append(records, record)
+		offset += journalHeaderSize + int64(length)
+	}
+}
+
+// append дописывает запись в конец журнала, присваивая ей следующий номер.
+// Если запись не удалась, файл возвращается к прежнему размеру.
+func (j *journal) append(record journalRecord) error {
+	record.Seq = j.seq + 1
+
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, journalHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+	copy(buf[journalHeaderSize:], payload)
+
+	if _, err := j.file.Write(buf); err != nil {
+		j.rollback()
+		return fmt.Errorf("write journal: %w", err)
+	}
+	if j.sync {
+		if err := j.file.Sync(); err != nil {
+			j.rollback()
+			return fmt.Errorf("sync journal: %w", err)
+		}
+	}
+
+	j.size += int64(len(buf))
+	j.seq = record.Seq
+	return nil
+}
+
+func (j *journal) rollback() {
+	j.file.Truncate(j.size)
+	j.file.Seek(j.size, io.SeekStart)
+}
+
+// reset очищает журнал после того, как его содержимое попало в снимок.
+// Нумерация записей продолжается.
+func (j *journal) reset() error {
+	if err := j.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := j.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	j.size = 0
+	return j.file.Sync()
+}
+
+func (j *journal) close() error {
+	return j.file.Close()
+}
+
+// snapshot - полное состояние хранилища на момент записи журнала с номером Seq.
+type snapshot struct {
+	Seq   uint64        `json:"seq"`
+	Tasks []models.Task `json:"tasks"`
+	Users []storedUser  `json:"users"`
+
+	Dependencies []dependency `json:"dependencies"`
+	Tags         []models.Tag `json:"tags"`
+
+	History []models.HistoryEntry `json:"history"`
+
+	Lists   []models.List       `json:"lists"`
+	Members []models.ListMember `json:"members"`
+
+	RefreshTokens []models.RefreshToken `json:"refresh_tokens"`
+	RevokedTokens map[string]time.Time  `json:"revoked_tokens"`
+
+	Webhooks   []models.Webhook         `json:"webhooks"`
+	Deliveries []models.WebhookDelivery `json:"deliveries"`
+}
+
+func readSnapshot(path string) (snapshot, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return snapshot{}, nil
+	}
+	if err != nil {
+		return snapshot{}, err
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return snapshot{}, fmt.Errorf("decode snapshot: %w", err)
+	}
+
+	return snap, nil
+}
+
+// writeSnapshot атомарно заменяет файл снимка: данные пишутся во временный
+// файл, сбрасываются на диск и только потом переименовываются.
+func writeSnapshot(path string, snap snapshot) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := json.NewEncoder(tmp).Encode(snap); err != nil {
+		tmp.Close()
+		return fmt.Errorf("encode snapshot: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	return syncDir(filepath.Dir(path))
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
@@ -0,0 +1,536 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"todo-api/internal/models"
+)
+
+// crash имитирует аварийное завершение: журнал закрывается без снимка
+func crash(s *MemoryStorage) {
+	s.journal.close()
+	s.journal = nil
+}
+
+func openPersistent(t *testing.T, dir string) *MemoryStorage {
+	t.Helper()
+
+	s, err := NewPersistentMemoryStorage(PersistenceOptions{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewPersistentMemoryStorage() error = %v", err)
+	}
+	return s
+}
+
+func assertSameTasks(t *testing.T, got, want map[string]models.Task) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("recovered %d tasks, want %d", len(got), len(want))
+	}
+	for id, wantTask := range want {
+		gotTask, ok := got[id]
+		if !ok {
+			t.Errorf("task %s was not recovered", id)
+			continue
+		}
+		gotJSON, _ := json.Marshal(gotTask)
+		wantJSON, _ := json.Marshal(wantTask)
+		if string(gotJSON) != string(wantJSON) {
+			t.Errorf("task %s = %s, want %s", id, gotJSON, wantJSON)
+		}
+	}
+}
+
+// applyOperations выполняет все журналируемые операции
+func applyOperations(t *testing.T, s *MemoryStorage) {
+	t.Helper()
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		task, err := s.Create(t.Context(), models.Task{Title: fmt.Sprintf("Задача %d", i), Description: "Описание"})
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		ids = append(ids, task.ID)
+	}
+
+	if _, err := s.Update(t.Context(), ids[0], models.Task{Title: "Изменённая", Description: ""}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := s.CompleteTask(t.Context(), ids[1], 0); err != nil {
+		t.Fatalf("CompleteTask() error = %v", err)
+	}
+	if err := s.Delete(t.Context(), ids[2], 0); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+}
+
+func TestPersistentMemoryStorageReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	s := openPersistent(t, dir)
+	applyOperations(t, s)
+	user, err := s.CreateUser(t.Context(), models.User{Username: "alice", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	want := s.tasks
+	crash(s)
+
+	recovered := openPersistent(t, dir)
+	defer recovered.Close()
+
+	assertSameTasks(t, recovered.tasks, want)
+
+	got, err := recovered.GetUserByUsername(t.Context(), "alice")
+	if err != nil {
+		t.Fatalf("GetUserByUsername() error = %v", err)
+	}
+	if got.ID != user.ID || got.PasswordHash != user.PasswordHash {
+		t.Errorf("recovered user = %+v, want %+v", got, user)
+	}
+}
+
+func TestPersistentMemoryStorageReplayDependencies(t *testing.T) {
+	dir := t.TempDir()
+
+	s := openPersistent(t, dir)
+	parent, _ := s.Create(t.Context(), models.Task{Title: "Родитель"})
+	s.Create(t.Context(), models.Task{Title: "Подзадача", ParentID: parent.ID})
+	first, _ := s.Create(t.Context(), models.Task{Title: "Первая"})
+	second, _ := s.Create(t.Context(), models.Task{Title: "Вторая"})
+	s.AddDependency(t.Context(), first.ID, second.ID)
+	s.AddDependency(t.Context(), second.ID, parent.ID)
+	s.RemoveDependency(t.Context(), second.ID, parent.ID)
+	s.AddDependency(t.Context(), first.ID, parent.ID)
+
+	// Часть состояния попадает в снимок, часть - только в журнал
+	if err := s.Compact(); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	if err := s.Delete(t.Context(), parent.ID, 0); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	want := s.tasks
+	crash(s)
+
+	recovered := openPersistent(t, dir)
+	defer recovered.Close()
+
+	assertSameTasks(t, recovered.tasks, want)
+
+	blockers, err := recovered.GetBlockers(t.Context(), first.ID)
+	if err != nil {
+		t.Fatalf("GetBlockers() error = %v", err)
+	}
+	if got := titles(blockers); len(got) != 1 || got[0] != "Вторая" {
+		t.Errorf("recovered blockers = %v, want [Вторая]", got)
+	}
+}
+
+func TestPersistentMemoryStorageReplayTags(t *testing.T) {
+	dir := t.TempDir()
+
+	s := openPersistent(t, dir)
+	s.CreateTag(t.Context(), models.Tag{Name: "дом", Color: "#00ff00", OwnerID: "user"})
+	s.CreateTag(t.Context(), models.Tag{Name: "работа", OwnerID: "user"})
+	s.Create(t.Context(), models.Task{Title: "Первая", OwnerID: "user", Tags: []string{"дом", "работа"}})
+
+	if err := s.Compact(); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	s.Create(t.Context(), models.Task{Title: "Вторая", OwnerID: "user", Tags: []string{"дом"}})
+	s.UpdateTag(t.Context(), "user", "дом", models.Tag{Name: "дача", Color: "#0000ff"})
+	s.DeleteTag(t.Context(), "user", "работа")
+	want := s.tasks
+	crash(s)
+
+	recovered := openPersistent(t, dir)
+	defer recovered.Close()
+
+	assertSameTasks(t, recovered.tasks, want)
+
+	tags, err := recovered.GetTags(t.Context(), "user")
+	if err != nil {
+		t.Fatalf("GetTags() error = %v", err)
+	}
+	if len(tags) != 1 || tags[0].Name != "дача" || tags[0].Color != "#0000ff" {
+		t.Errorf("recovered tags = %+v, want [дача]", tags)
+	}
+}
+
+func TestPersistentMemoryStorageReplayLists(t *testing.T) {
+	dir := t.TempDir()
+
+	s := openPersistent(t, dir)
+	owner, _ := s.CreateUser(t.Context(), models.User{Username: "owner", PasswordHash: "hash"})
+	editor, _ := s.CreateUser(t.Context(), models.User{Username: "editor", PasswordHash: "hash"})
+	kept, _ := s.CreateList(t.Context(), models.List{Name: "Дом", OwnerID: owner.ID})
+	removed, _ := s.CreateList(t.Context(), models.List{Name: "Работа", OwnerID: owner.ID})
+	s.PutMember(t.Context(), models.ListMember{ListID: kept.ID, UserID: editor.ID, Role: models.RoleViewer})
+	s.Create(t.Context(), models.Task{Title: "Купить хлеб", OwnerID: editor.ID, ListID: kept.ID})
+	s.Create(t.Context(), models.Task{Title: "Отчет", OwnerID: owner.ID, ListID: removed.ID})
+
+	// Часть состояния попадает в снимок, часть - только в журнал
+	if err := s.Compact(); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	s.UpdateList(t.Context(), kept.ID, models.List{Name: "Дача"})
+	s.PutMember(t.Context(), models.ListMember{ListID: kept.ID, UserID: editor.ID, Role: models.RoleEditor})
+	s.PutMember(t.Context(), models.ListMember{ListID: removed.ID, UserID: editor.ID, Role: models.RoleEditor})
+	if err := s.DeleteList(t.Context(), removed.ID); err != nil {
+		t.Fatalf("DeleteList() error = %v", err)
+	}
+	want := s.tasks
+	crash(s)
+
+	recovered := openPersistent(t, dir)
+	defer recovered.Close()
+
+	assertSameTasks(t, recovered.tasks, want)
+
+	lists, err := recovered.GetListsByMember(t.Context(), editor.ID)
+	if err != nil {
+		t.Fatalf("GetListsByMember() error = %v", err)
+	}
+	if len(lists) != 1 || lists[0].ID != kept.ID || lists[0].Name != "Дача" {
+		t.Errorf("recovered lists = %+v, want [Дача]", lists)
+	}
+	if member, err := recovered.GetMember(t.Context(), kept.ID, editor.ID); err != nil || member.Role != models.RoleEditor {
+		t.Errorf("recovered member = %+v, %v, want role editor", member, err)
+	}
+	if _, err := recovered.GetList(t.Context(), removed.ID); err != ErrListNotFound {
+		t.Errorf("GetList() of deleted list error = %v, want %v", err, ErrListNotFound)
+	}
+}
+
+func TestPersistentMemoryStorageReplayBatch(t *testing.T) {
+	dir := t.TempDir()
+
+	s := openPersistent(t, dir)
+	parent, _ := s.Create(t.Context(), models.Task{Title: "Родитель"})
+	s.Create(t.Context(), models.Task{Title: "Подзадача", ParentID: parent.ID})
+	kept, _ := s.Create(t.Context(), models.Task{Title: "Остаётся"})
+
+	kept.Title = "Изменённая"
+	_, err := s.ApplyBatch(t.Context(), []BatchOp{
+		{Kind: BatchCreate, Task: models.Task{Title: "Новая"}},
+		{Kind: BatchUpdate, Task: kept},
+		{Kind: BatchDelete, Task: models.Task{ID: parent.ID}},
+	})
+	if err != nil {
+		t.Fatalf("ApplyBatch() error = %v", err)
+	}
+	want := s.tasks
+	crash(s)
+
+	recovered := openPersistent(t, dir)
+	defer recovered.Close()
+
+	assertSameTasks(t, recovered.tasks, want)
+}
+
+func TestPersistentMemoryStorageReplayTrash(t *testing.T) {
+	dir := t.TempDir()
+
+	s := openPersistent(t, dir)
+	parent, _ := s.Create(t.Context(), models.Task{Title: "Родитель"})
+	s.Create(t.Context(), models.Task{Title: "Подзадача", ParentID: parent.ID})
+	restored, _ := s.Create(t.Context(), models.Task{Title: "Восстановленная"})
+	purged, _ := s.Create(t.Context(), models.Task{Title: "Удалённая"})
+
+	for _, id := range []string{parent.ID, restored.ID, purged.ID} {
+		if err := s.Delete(t.Context(), id, 0); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+	}
+	if _, err := s.Restore(t.Context(), restored.ID); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	deletedAt := *s.tasks[parent.ID].DeletedAt
+	if _, err := s.Purge(t.Context(), deletedAt.Add(time.Nanosecond)); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	want := s.tasks
+	crash(s)
+
+	recovered := openPersistent(t, dir)
+	defer recovered.Close()
+
+	assertSameTasks(t, recovered.tasks, want)
+}
+
+func TestPersistentMemoryStorageReplayHistory(t *testing.T) {
+	dir := t.TempDir()
+
+	s := openPersistent(t, dir)
+	task, _ := s.Create(t.Context(), models.Task{Title: "Задача"})
+	for _, action := range []models.HistoryAction{models.HistoryCreated, models.HistoryUpdated} {
+		if _, err := s.AddHistory(t.Context(), models.HistoryEntry{TaskID: task.ID, ActorID: "user", Action: action}); err != nil {
+			t.Fatalf("AddHistory() error = %v", err)
+		}
+	}
+	want, _, _ := s.GetHistory(t.Context(), task.ID, 10, 0)
+	if err := s.Compact(); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	s.AddHistory(t.Context(), models.HistoryEntry{TaskID: task.ID, ActorID: "user", Action: models.HistoryDeleted})
+	crash(s)
+
+	recovered := openPersistent(t, dir)
+	defer recovered.Close()
+
+	got, total, _ := recovered.GetHistory(t.Context(), task.ID, 10, 0)
+	if total != 3 || got[0].Action != models.HistoryDeleted {
+		t.Fatalf("recovered history = %+v", got)
+	}
+	gotJSON, _ := json.Marshal(got[1:])
+	wantJSON, _ := json.Marshal(want)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("recovered history = %s, want %s", gotJSON, wantJSON)
+	}
+}
+
+func TestPersistentMemoryStorageReplayWebhooks(t *testing.T) {
+	dir := t.TempDir()
+
+	s := openPersistent(t, dir)
+	kept, _ := s.CreateWebhook(t.Context(), models.Webhook{OwnerID: "user", URL: "https://example.com/a", Active: true})
+	deleted, _ := s.CreateWebhook(t.Context(), models.Webhook{OwnerID: "user", URL: "https://example.com/b", Active: true})
+	now := time.Now()
+	s.AddDeliveries(t.Context(), []models.WebhookDelivery{
+		{WebhookID: kept.ID, Status: models.DeliveryPending, NextAttemptAt: &now},
+		{WebhookID: deleted.ID, Status: models.DeliveryPending, NextAttemptAt: &now},
+	})
+	if err := s.Compact(); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	claimed, _ := s.ClaimDeliveries(t.Context(), now, time.Minute, 10)
+	for _, delivery := range claimed {
+		delivery.Status = models.DeliveryDelivered
+		s.UpdateDelivery(t.Context(), delivery)
+	}
+	s.DeleteWebhook(t.Context(), deleted.ID)
+	crash(s)
+
+	recovered := openPersistent(t, dir)
+	defer recovered.Close()
+
+	if webhooks, _ := recovered.GetWebhooks(t.Context(), "user"); len(webhooks) != 1 || webhooks[0].ID != kept.ID {
+		t.Fatalf("recovered webhooks = %+v", webhooks)
+	}
+	deliveries, total, _ := recovered.GetDeliveries(t.Context(), models.DeliveryQuery{OwnerID: "user", Limit: 10})
+	if total != 1 || deliveries[0].Status != models.DeliveryDelivered {
+		t.Errorf("recovered deliveries = %+v", deliveries)
+	}
+}
+
+func TestPersistentMemoryStorageTornWrite(t *testing.T) {
+	dir := t.TempDir()
+
+	s := openPersistent(t, dir)
+	applyOperations(t, s)
+	want := s.tasks
+	crash(s)
+
+	// Заголовок обещает 100 байт, но записаны только 10 - как при сбое посреди записи
+	file, err := os.OpenFile(filepath.Join(dir, journalFileName), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := make([]byte, journalHeaderSize)
+	binary.LittleEndian.PutUint32(header[0:4], 100)
+	file.Write(header)
+	file.Write([]byte(`{"seq":99,`))
+	file.Close()
+
+	recovered := openPersistent(t, dir)
+	assertSameTasks(t, recovered.tasks, want)
+
+	// После обрезки хвоста новые записи должны читаться
+	task, err := recovered.Create(t.Context(), models.Task{Title: "После сбоя"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	want = recovered.tasks
+	crash(recovered)
+
+	again := openPersistent(t, dir)
+	defer again.Close()
+
+	if _, err := again.GetByID(t.Context(), task.ID); err != nil {
+		t.Errorf("task written after torn record was lost: %v", err)
+	}
+	assertSameTasks(t, again.tasks, want)
+}
+
+func TestPersistentMemoryStorageCorruptedRecord(t *testing.T) {
+	dir := t.TempDir()
+
+	s := openPersistent(t, dir)
+	first, _ := s.Create(t.Context(), models.Task{Title: "Первая"})
+	s.Create(t.Context(), models.Task{Title: "Вторая"})
+	crash(s)
+
+	// Портим последний байт: CRC второй записи перестаёт совпадать
+	path := filepath.Join(dir, journalFileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 0xff
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	recovered := openPersistent(t, dir)
+	defer recovered.Close()
+
+	if len(recovered.tasks) != 1 {
+		t.Fatalf("recovered %d tasks, want 1", len(recovered.tasks))
+	}
+	if _, err := recovered.GetByID(t.Context(), first.ID); err != nil {
+		t.Errorf("GetByID(first) error = %v", err)
+	}
+}
+
+func TestPersistentMemoryStorageCompact(t *testing.T) {
+	dir := t.TempDir()
+
+	s := openPersistent(t, dir)
+	applyOperations(t, s)
+
+	if err := s.Compact(); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	info, err := os.Stat(filepath.Join(dir, journalFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("journal size after Compact = %d, want 0", info.Size())
+	}
+
+	applyOperations(t, s)
+	want := s.tasks
+	crash(s)
+
+	recovered := openPersistent(t, dir)
+	defer recovered.Close()
+
+	assertSameTasks(t, recovered.tasks, want)
+}
+
+func TestPersistentMemoryStorageSnapshotBeforeJournalReset(t *testing.T) {
+	dir := t.TempDir()
+
+	s := openPersistent(t, dir)
+	applyOperations(t, s)
+	want := s.tasks
+
+	// Сбой между записью снимка и очисткой журнала: записи журнала
+	// уже есть в снимке и не должны применяться повторно
+	snap := snapshot{Seq: s.journal.seq}
+	for _, task := range s.tasks {
+		snap.Tasks = append(snap.Tasks, task)
+	}
+	if err := writeSnapshot(filepath.Join(dir, snapshotFileName), snap); err != nil {
+		t.Fatal(err)
+	}
+	crash(s)
+
+	recovered := openPersistent(t, dir)
+	assertSameTasks(t, recovered.tasks, want)
+
+	task, err := recovered.Create(t.Context(), models.Task{Title: "Новая"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	crash(recovered)
+
+	again := openPersistent(t, dir)
+	defer again.Close()
+
+	if _, err := again.GetByID(t.Context(), task.ID); err != nil {
+		t.Errorf("task created after recovery was lost: %v", err)
+	}
+}
+
+// TestJournalWriterProcess - вспомогательный процесс для
+// TestPersistentMemoryStorageKilledProcess. Он пишет задачи, пока его не убьют,
+// и печатает идентификатор каждой задачи после подтверждения записи.
+func TestJournalWriterProcess(t *testing.T) {
+	dir := os.Getenv("TODO_JOURNAL_WRITER_DIR")
+	if dir == "" {
+		t.Skip("helper process")
+	}
+
+	s, err := NewPersistentMemoryStorage(PersistenceOptions{
+		Dir:              dir,
+		SnapshotInterval: 5 * time.Millisecond,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	for i := 0; ; i++ {
+		task, err := s.Create(t.Context(), models.Task{Title: fmt.Sprintf("Задача %d", i)})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(task.ID)
+	}
+}
+
+func TestPersistentMemoryStorageKilledProcess(t *testing.T) {
+	if testing.Short() {
+		t.Skip("spawns a subprocess")
+	}
+
+	dir := t.TempDir()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestJournalWriterProcess$")
+	cmd.Env = append(os.Environ(), "TODO_JOURNAL_WRITER_DIR="+dir)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	var acknowledged []string
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		acknowledged = append(acknowledged, scanner.Text())
+		if len(acknowledged) == 2000 {
+			cmd.Process.Kill()
+			break
+		}
+	}
+	cmd.Wait()
+
+	if len(acknowledged) < 2000 {
+		t.Fatalf("writer stopped after %d tasks", len(acknowledged))
+	}
+
+	recovered := openPersistent(t, dir)
+	defer recovered.Close()
+
+	for _, id := range acknowledged {
+		if _, err := recovered.GetByID(t.Context(), id); err != nil {
+			t.Fatalf("acknowledged task %s was lost: %v", id, err)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package storage
 
 import (
-	"errors"
+	"cmp"
+	"context"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -10,40 +12,103 @@ import (
 	"github.com/google/uuid"
 
 	"todo-api/internal/models"
+	"todo-api/internal/search"
 )
 
-var (
-	ErrTaskNotFound = errors.New("task not found")
-)
-
+// MemoryStorage хранит данные в памяти под одной блокировкой. Методы
+// проверяют ctx, получив блокировку: запрос, отмененный или просроченный,
+// пока ждал ее, возвращает ошибку ctx и ничего не меняет.
 type MemoryStorage struct {
 	mu    sync.RWMutex
 	tasks map[string]models.Task
+	users map[string]models.User
+	lists map[string]models.List
+	// members[listID][userID]
+	members map[string]map[string]models.ListMember
+	// dependencies[taskID][blockerID]
+	dependencies map[string]map[string]bool
+	// tags[ownerID][name]
+	tags map[string]map[string]models.Tag
+	// history[taskID] - записи истории в порядке добавления
+	history map[string][]models.HistoryEntry
+	// webhooks и deliveries - вебхуки и доставки по ID
+	webhooks   map[string]models.Webhook
+	deliveries map[string]models.WebhookDelivery
+	// index - полнотекстовый индекс заголовков и описаний задач
+	index *search.Index
+	// onChange получает изменения задач, см. ChangeSource
+	onChange func(event models.ChangeEvent)
+
+	refreshTokens map[string]models.RefreshToken
+	revokedTokens map[string]time.Time
+
+	// Журнал и снимки включаются только в NewPersistentMemoryStorage
+	journal      *journal
+	snapshotPath string
+	snapshotSeq  uint64
+	stop         chan struct{}
+	done         chan struct{}
 }
 
 func NewMemoryStorage() *MemoryStorage {
 	return &MemoryStorage{
 		tasks: make(map[string]models.Task),
+		users: make(map[string]models.User),
+		lists: make(map[string]models.List),
+
+		members:      make(map[string]map[string]models.ListMember),
+		dependencies: make(map[string]map[string]bool),
+		tags:         make(map[string]map[string]models.Tag),
+		history:      make(map[string][]models.HistoryEntry),
+		webhooks:     make(map[string]models.Webhook),
+		deliveries:   make(map[string]models.WebhookDelivery),
+		index:        search.NewIndex(),
+
+		refreshTokens: make(map[string]models.RefreshToken),
+		revokedTokens: make(map[string]time.Time),
 	}
 }
 
-func (s *MemoryStorage) Create(task models.Task) (models.Task, error) {
+func (s *MemoryStorage) OnChange(fn func(event models.ChangeEvent)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onChange = fn
+}
+
+func (s *MemoryStorage) Create(ctx context.Context, task models.Task) (models.Task, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if err := ctx.Err(); err != nil {
+		return models.Task{}, err
+	}
+
+	task = newTask(task)
+	if err := s.put(opCreate, task); err != nil {
+		return models.Task{}, err
+	}
+	return task, nil
+}
+
+// newTask заполняет поля, которые хранилище задаёт при создании задачи
+func newTask(task models.Task) models.Task {
 	task.ID = uuid.New().String()
 	task.CreatedAt = time.Now()
 	task.UpdatedAt = time.Now()
-
-	s.tasks[task.ID] = task
-	return task, nil
+	task.Version = 1
+	return task
 }
 
-func (s *MemoryStorage) GetByID(id string) (models.Task, error) {
+func (s *MemoryStorage) GetByID(ctx context.Context, id string) (models.Task, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	task, exists := s.tasks[id]
+	if err := ctx.Err(); err != nil {
+		return models.Task{}, err
+	}
+
+	task, exists := s.liveTask(id)
 	if !exists {
 		return models.Task{}, ErrTaskNotFound
 	}
@@ -51,23 +116,35 @@ func (s *MemoryStorage) GetByID(id string) (models.Task, error) {
 	return task, nil
 }
 
-func (s *MemoryStorage) GetAll(query models.TaskQuery) ([]models.Task, int, error) {
+// liveTask возвращает задачу, если она есть и не в корзине. Вызывается под s.mu.
+func (s *MemoryStorage) liveTask(id string) (models.Task, bool) {
+	task, exists := s.tasks[id]
+	if !exists || task.DeletedAt != nil {
+		return models.Task{}, false
+	}
+	return task, true
+}
+
+func (s *MemoryStorage) GetAll(ctx context.Context, query models.TaskQuery) ([]models.Task, int, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	var tasks []models.Task
-	for _, task := range s.tasks {
-		tasks = append(tasks, task)
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
 	}
 
 	// Фильтрация
-	tasks = s.filterTasks(tasks, query)
+	tasks := s.matchingTasks(query)
+	total := len(tasks)
+
+	if query.Cursor != nil {
+		return pageAfterCursor(tasks, query), total, nil
+	}
 
 	// Сортировка
 	tasks = s.sortTasks(tasks, query)
 
 	// Пагинация
-	total := len(tasks)
 	start := query.Offset
 	if start > len(tasks) {
 		start = len(tasks)
@@ -80,23 +157,55 @@ func (s *MemoryStorage) GetAll(query models.TaskQuery) ([]models.Task, int, erro
 	return tasks[start:end], total, nil
 }
 
+// matchingTasks возвращает задачи, подходящие под фильтры и поисковый запрос
+// query. При поиске перебираются только найденные индексом задачи, и у
+// каждой заполняется Score.
+func (s *MemoryStorage) matchingTasks(query models.TaskQuery) []models.Task {
+	if query.Search == "" {
+		tasks := make([]models.Task, 0, len(s.tasks))
+		for _, task := range s.tasks {
+			tasks = append(tasks, task)
+		}
+		return s.filterTasks(tasks, query)
+	}
+
+	results := s.index.Search(search.ParseQuery(query.Search))
+	tasks := make([]models.Task, 0, len(results))
+	for id, score := range results {
+		task := s.tasks[id]
+		task.Score = score
+		tasks = append(tasks, task)
+	}
+	return s.filterTasks(tasks, query)
+}
+
 func (s *MemoryStorage) filterTasks(tasks []models.Task, query models.TaskQuery) []models.Task {
 	var filtered []models.Task
+	now := time.Now()
 
 	for _, task := range tasks {
+		if (task.DeletedAt != nil) != query.Deleted {
+			continue
+		}
+
+		if !taskVisible(task, query) {
+			continue
+		}
+
+		if query.ListID != "" && task.ListID != query.ListID {
+			continue
+		}
+
 		if query.Completed != nil && task.Completed != *query.Completed {
 			continue
 		}
 
-		if query.Search != "" {
-			searchLower := strings.ToLower(query.Search)
-			titleLower := strings.ToLower(task.Title)
-			descLower := strings.ToLower(task.Description)
+		if !dueMatches(task, query, now) {
+			continue
+		}
 
-			if !strings.Contains(titleLower, searchLower) &&
-				!strings.Contains(descLower, searchLower) {
-				continue
-			}
+		if !tagsMatch(task, query) {
+			continue
 		}
 
 		filtered = append(filtered, task)
@@ -105,68 +214,573 @@ func (s *MemoryStorage) filterTasks(tasks []models.Task, query models.TaskQuery)
 	return filtered
 }
 
+// dueMatches проверяет фильтры по сроку выполнения
+func dueMatches(task models.Task, query models.TaskQuery, now time.Time) bool {
+	if query.DueAfter == nil && query.DueBefore == nil && !query.Overdue {
+		return true
+	}
+	if task.DueAt == nil {
+		return false
+	}
+
+	if query.DueAfter != nil && task.DueAt.Before(*query.DueAfter) {
+		return false
+	}
+	if query.DueBefore != nil && !task.DueAt.Before(*query.DueBefore) {
+		return false
+	}
+	if query.Overdue && (task.Completed || !task.DueAt.Before(now)) {
+		return false
+	}
+
+	return true
+}
+
+// tagsMatch проверяет фильтр по тегам
+func tagsMatch(task models.Task, query models.TaskQuery) bool {
+	if len(query.Tags) == 0 {
+		return true
+	}
+
+	for _, tag := range query.Tags {
+		has := slices.Contains(task.Tags, tag)
+		if has && !query.AllTags {
+			return true
+		}
+		if !has && query.AllTags {
+			return false
+		}
+	}
+
+	return query.AllTags
+}
+
+// taskVisible проверяет ограничение видимости query.OwnerID/query.ListIDs
+func taskVisible(task models.Task, query models.TaskQuery) bool {
+	if query.OwnerID == "" && len(query.ListIDs) == 0 {
+		return true
+	}
+
+	if task.ListID == "" {
+		return task.OwnerID == query.OwnerID
+	}
+	for _, listID := range query.ListIDs {
+		if task.ListID == listID {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (s *MemoryStorage) sortTasks(tasks []models.Task, query models.TaskQuery) []models.Task {
+	keys := query.SortKeys()
 	sort.Slice(tasks, func(i, j int) bool {
-		switch query.SortBy {
-		case "created_at":
-			if query.SortOrder == "desc" {
-				return tasks[i].CreatedAt.After(tasks[j].CreatedAt)
-			}
-			return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
-		case "completed":
-			if query.SortOrder == "desc" {
-				return tasks[i].Completed && !tasks[j].Completed
-			}
-			return !tasks[i].Completed && tasks[j].Completed
-		default:
-			return tasks[i].CreatedAt.After(tasks[j].CreatedAt)
-		}
+		return compareByKeys(tasks[i], tasks[j], keys) < 0
 	})
 
 	return tasks
 }
 
-func (s *MemoryStorage) Update(id string, updatedTask models.Task) (models.Task, error) {
+// pageAfterCursor возвращает до query.Limit задач, следующих за курсором
+// (или предшествующих ему), в порядке сортировки запроса. Сортируются только
+// задачи по нужную сторону от курсора.
+func pageAfterCursor(tasks []models.Task, query models.TaskQuery) []models.Task {
+	keys := query.SortKeys()
+	direction := 1
+	if query.Cursor.Backward {
+		direction = -1
+	}
+
+	var page []models.Task
+	for _, task := range tasks {
+		if compareByKeys(task, query.Cursor.Task, keys)*direction > 0 {
+			page = append(page, task)
+		}
+	}
+
+	// При движении назад ближайшие к курсору задачи - последние в порядке
+	// сортировки, поэтому выбираем их в обратном порядке и разворачиваем
+	sort.Slice(page, func(i, j int) bool {
+		return compareByKeys(page[i], page[j], keys)*direction < 0
+	})
+	if len(page) > query.Limit {
+		page = page[:query.Limit]
+	}
+	if query.Cursor.Backward {
+		slices.Reverse(page)
+	}
+
+	return page
+}
+
+// compareByKeys сравнивает задачи по всем ключам сортировки, а при их
+// равенстве - по идентификатору
+func compareByKeys(a, b models.Task, keys []models.SortKey) int {
+	for _, key := range keys {
+		if c := compareTasks(a, b, key); c != 0 {
+			return c
+		}
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+// compareTasks сравнивает задачи по одному ключу сортировки с учётом его направления
+func compareTasks(a, b models.Task, key models.SortKey) int {
+	var c int
+	switch key.Field {
+	case models.SortCreatedAt:
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	case models.SortUpdatedAt:
+		c = a.UpdatedAt.Compare(b.UpdatedAt)
+	case models.SortCompleted:
+		c = compareBool(a.Completed, b.Completed)
+	case models.SortPriority:
+		c = cmp.Compare(a.Priority, b.Priority)
+	case models.SortTitle:
+		c = strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+	case models.SortRelevance:
+		c = cmp.Compare(a.Score, b.Score)
+	case models.SortDueAt:
+		// Задачи без срока идут последними при любом порядке
+		switch {
+		case a.DueAt == nil && b.DueAt == nil:
+			return 0
+		case a.DueAt == nil:
+			return 1
+		case b.DueAt == nil:
+			return -1
+		}
+		c = a.DueAt.Compare(*b.DueAt)
+	}
+
+	if key.Desc {
+		return -c
+	}
+	return c
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
+
+func (s *MemoryStorage) Update(ctx context.Context, id string, updatedTask models.Task) (models.Task, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	existing, exists := s.tasks[id]
+	if err := ctx.Err(); err != nil {
+		return models.Task{}, err
+	}
+
+	existing, exists := s.liveTask(id)
 	if !exists {
 		return models.Task{}, ErrTaskNotFound
 	}
+	if err := checkVersion(existing, updatedTask.Version); err != nil {
+		return models.Task{}, err
+	}
 
-	updatedTask.ID = id
-	updatedTask.CreatedAt = existing.CreatedAt
-	updatedTask.UpdatedAt = time.Now()
-
-	s.tasks[id] = updatedTask
+	updatedTask = applyUpdate(existing, updatedTask)
+	if err := s.put(opUpdate, updatedTask); err != nil {
+		return models.Task{}, err
+	}
 	return updatedTask, nil
 }
 
-func (s *MemoryStorage) Delete(id string) error {
+func (s *MemoryStorage) Delete(ctx context.Context, id string, version int64) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, exists := s.tasks[id]; !exists {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	task, exists := s.liveTask(id)
+	if !exists {
 		return ErrTaskNotFound
 	}
+	if err := checkVersion(task, version); err != nil {
+		return err
+	}
+
+	return s.putAll(markDeleted(s.tasks, id, time.Now()))
+}
+
+// applyUpdate возвращает задачу existing после обновления до updated:
+// владелец, список и время создания не меняются, версия растёт
+func applyUpdate(existing, updated models.Task) models.Task {
+	updated.ID = existing.ID
+	updated.OwnerID = existing.OwnerID
+	updated.ListID = existing.ListID
+	updated.CreatedAt = existing.CreatedAt
+	updated.UpdatedAt = time.Now()
+	updated.Version = existing.Version + 1
+	return updated
+}
+
+// checkVersion возвращает ErrVersionConflict, если задана версия version и
+// задача уже изменена после неё
+func checkVersion(task models.Task, version int64) error {
+	if version != 0 && version != task.Version {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// deleteTask удаляет задачу, её подзадачи и все связанные зависимости.
+// Вызывается под s.mu, в том числе при восстановлении из журнала.
+func (s *MemoryStorage) deleteTask(id string) {
+	if task, exists := s.tasks[id]; exists && s.onChange != nil {
+		s.onChange(models.ChangeEvent{Type: models.ChangePurged, Task: &task})
+	}
 
 	delete(s.tasks, id)
+	s.index.Remove(id)
+	delete(s.history, id)
+	delete(s.dependencies, id)
+	for _, blockers := range s.dependencies {
+		delete(blockers, id)
+	}
+
+	for childID, task := range s.tasks {
+		if task.ParentID == id {
+			s.deleteTask(childID)
+		}
+	}
+}
+
+func (s *MemoryStorage) GetChildren(ctx context.Context, parentID string) ([]models.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var children []models.Task
+	for _, task := range s.tasks {
+		if task.ParentID == parentID && task.DeletedAt == nil {
+			children = append(children, task)
+		}
+	}
+	sortByCreation(children)
+
+	return children, nil
+}
+
+func (s *MemoryStorage) AddDependency(ctx context.Context, taskID, blockerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, exists := s.liveTask(taskID); !exists {
+		return ErrTaskNotFound
+	}
+	if _, exists := s.liveTask(blockerID); !exists {
+		return ErrTaskNotFound
+	}
+	if s.dependencies[taskID][blockerID] {
+		return nil
+	}
+
+	if s.journal != nil {
+		if err := s.journal.append(journalRecord{Op: opAddDependency, ID: taskID, BlockerID: blockerID}); err != nil {
+			return err
+		}
+	}
+
+	s.setDependency(taskID, blockerID)
 	return nil
 }
 
-func (s *MemoryStorage) CompleteTask(id string) (models.Task, error) {
+func (s *MemoryStorage) setDependency(taskID, blockerID string) {
+	if s.dependencies[taskID] == nil {
+		s.dependencies[taskID] = make(map[string]bool)
+	}
+	s.dependencies[taskID][blockerID] = true
+}
+
+func (s *MemoryStorage) RemoveDependency(ctx context.Context, taskID, blockerID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	task, exists := s.tasks[id]
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if !s.dependencies[taskID][blockerID] {
+		return ErrDependencyNotFound
+	}
+
+	if s.journal != nil {
+		if err := s.journal.append(journalRecord{Op: opRemoveDependency, ID: taskID, BlockerID: blockerID}); err != nil {
+			return err
+		}
+	}
+
+	delete(s.dependencies[taskID], blockerID)
+	return nil
+}
+
+func (s *MemoryStorage) GetBlockers(ctx context.Context, taskID string) ([]models.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var blockers []models.Task
+	for blockerID := range s.dependencies[taskID] {
+		if blocker, exists := s.liveTask(blockerID); exists {
+			blockers = append(blockers, blocker)
+		}
+	}
+	sortByCreation(blockers)
+
+	return blockers, nil
+}
+
+// sortByCreation сортирует задачи так же, как ORDER BY created_at, id
+func sortByCreation(tasks []models.Task) {
+	sort.Slice(tasks, func(i, j int) bool {
+		if !tasks[i].CreatedAt.Equal(tasks[j].CreatedAt) {
+			return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+}
+
+func (s *MemoryStorage) CompleteTask(ctx context.Context, id string, version int64) (models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return models.Task{}, err
+	}
+
+	task, exists := s.liveTask(id)
 	if !exists {
 		return models.Task{}, ErrTaskNotFound
 	}
+	if err := checkVersion(task, version); err != nil {
+		return models.Task{}, err
+	}
 
 	task.Completed = true
 	task.UpdatedAt = time.Now()
-	s.tasks[id] = task
+	task.Version++
+	if err := s.put(opComplete, task); err != nil {
+		return models.Task{}, err
+	}
 
 	return task, nil
 }
+
+func (s *MemoryStorage) GetDueReminders(ctx context.Context, now time.Time, limit int) ([]models.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var due []models.Task
+	for _, task := range s.tasks {
+		if task.Completed || task.DeletedAt != nil || task.RemindAt == nil || task.RemindedAt != nil || task.RemindAt.After(now) {
+			continue
+		}
+		due = append(due, task)
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].RemindAt.Before(*due[j].RemindAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	return due, nil
+}
+
+func (s *MemoryStorage) MarkReminded(ctx context.Context, id string, remindAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	task, exists := s.liveTask(id)
+	if !exists || task.RemindAt == nil || !task.RemindAt.Equal(remindAt) || task.RemindedAt != nil {
+		return false, nil
+	}
+
+	remindedAt := time.Now()
+	task.RemindedAt = &remindedAt
+	task.Version++
+	if err := s.put(opRemind, task); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *MemoryStorage) CreateUser(ctx context.Context, user models.User) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return models.User{}, err
+	}
+
+	for _, existing := range s.users {
+		if existing.Username == user.Username {
+			return models.User{}, ErrUserExists
+		}
+	}
+
+	user.ID = uuid.New().String()
+	user.CreatedAt = time.Now()
+
+	if err := s.putUser(user); err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+func (s *MemoryStorage) GetUserByID(ctx context.Context, id string) (models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return models.User{}, err
+	}
+
+	user, exists := s.users[id]
+	if !exists {
+		return models.User{}, ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+func (s *MemoryStorage) GetUserByUsername(ctx context.Context, username string) (models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return models.User{}, err
+	}
+
+	for _, user := range s.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+
+	return models.User{}, ErrUserNotFound
+}
+
+func (s *MemoryStorage) CreateRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.putRefreshToken(token)
+}
+
+func (s *MemoryStorage) GetRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return models.RefreshToken{}, err
+	}
+
+	token, exists := s.refreshTokens[tokenHash]
+	if !exists {
+		return models.RefreshToken{}, ErrTokenNotFound
+	}
+
+	return token, nil
+}
+
+func (s *MemoryStorage) UseRefreshToken(ctx context.Context, tokenHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	token, exists := s.refreshTokens[tokenHash]
+	if !exists {
+		return ErrTokenNotFound
+	}
+	if token.UsedAt != nil {
+		return ErrTokenUsed
+	}
+
+	now := time.Now()
+	token.UsedAt = &now
+	return s.putRefreshToken(token)
+}
+
+func (s *MemoryStorage) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, token := range s.refreshTokens {
+		if token.FamilyID != familyID || token.RevokedAt != nil {
+			continue
+		}
+		token.RevokedAt = &now
+		if err := s.putRefreshToken(token); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *MemoryStorage) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.putRevokedToken(jti, expiresAt)
+}
+
+func (s *MemoryStorage) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	_, revoked := s.revokedTokens[jti]
+	return revoked, nil
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"time"
+
+	"todo-api/internal/models"
+)
+
+func (s *MemoryStorage) ApplyBatch(ctx context.Context, ops []BatchOp) ([]models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Изменения сначала проверяются на копии s.tasks. Карта задач меняется,
+	// только если проверку прошли все изменения.
+	view := maps.Clone(s.tasks)
+
+	results := make([]models.Task, len(ops))
+	var changed []models.Task
+	for i, op := range ops {
+		switch op.Kind {
+		case BatchCreate:
+			task := newTask(op.Task)
+			view[task.ID] = task
+			results[i] = task
+			changed = append(changed, task)
+		case BatchUpdate, BatchDelete:
+			existing, ok := view[op.Task.ID]
+			if !ok || existing.DeletedAt != nil {
+				return nil, &BatchError{Index: i, Err: ErrTaskNotFound}
+			}
+			if err := checkVersion(existing, op.Task.Version); err != nil {
+				return nil, &BatchError{Index: i, Err: err}
+			}
+
+			if op.Kind == BatchDelete {
+				for _, task := range markDeleted(view, existing.ID, time.Now()) {
+					view[task.ID] = task
+					changed = append(changed, task)
+				}
+				continue
+			}
+			task := applyUpdate(existing, op.Task)
+			view[task.ID] = task
+			results[i] = task
+			changed = append(changed, task)
+		default:
+			return nil, &BatchError{Index: i, Err: fmt.Errorf("unknown batch operation %q", op.Kind)}
+		}
+	}
+
+	if err := s.putAll(changed); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"todo-api/internal/models"
+)
+
+func (s *MemoryStorage) AddHistory(ctx context.Context, entry models.HistoryEntry) (models.HistoryEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return models.HistoryEntry{}, err
+	}
+
+	if _, exists := s.tasks[entry.TaskID]; !exists {
+		return models.HistoryEntry{}, ErrTaskNotFound
+	}
+
+	entry.ID = uuid.New().String()
+	entry.CreatedAt = time.Now()
+
+	if s.journal != nil {
+		if err := s.journal.append(journalRecord{Op: opAddHistory, History: &entry}); err != nil {
+			return models.HistoryEntry{}, err
+		}
+	}
+
+	s.history[entry.TaskID] = append(s.history[entry.TaskID], entry)
+	return entry, nil
+}
+
+func (s *MemoryStorage) GetHistory(ctx context.Context, taskID string, limit, offset int) ([]models.HistoryEntry, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	entries := s.history[taskID]
+	total := len(entries)
+
+	// Записи хранятся от старых к новым
+	end := max(total-offset, 0)
+	start := max(end-limit, 0)
+	page := slices.Clone(entries[start:end])
+	slices.Reverse(page)
+
+	return page, total, nil
+}
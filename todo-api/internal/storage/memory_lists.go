@@ -0,0 +1,257 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"todo-api/internal/models"
+)
+
+func (s *MemoryStorage) CreateList(ctx context.Context, list models.List) (models.List, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return models.List{}, err
+	}
+
+	list.ID = uuid.New().String()
+	list.Role = ""
+	list.CreatedAt = time.Now()
+	list.UpdatedAt = list.CreatedAt
+
+	if err := s.putList(list); err != nil {
+		return models.List{}, err
+	}
+
+	owner := models.ListMember{
+		ListID:    list.ID,
+		UserID:    list.OwnerID,
+		Role:      models.RoleOwner,
+		CreatedAt: list.CreatedAt,
+	}
+	if err := s.putMember(owner); err != nil {
+		return models.List{}, err
+	}
+
+	return list, nil
+}
+
+func (s *MemoryStorage) GetList(ctx context.Context, id string) (models.List, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return models.List{}, err
+	}
+
+	list, exists := s.lists[id]
+	if !exists {
+		return models.List{}, ErrListNotFound
+	}
+
+	return list, nil
+}
+
+func (s *MemoryStorage) GetListsByMember(ctx context.Context, userID string) ([]models.List, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var lists []models.List
+	for listID, members := range s.members {
+		if _, ok := members[userID]; ok {
+			lists = append(lists, s.lists[listID])
+		}
+	}
+
+	sort.Slice(lists, func(i, j int) bool {
+		return lists[i].CreatedAt.Before(lists[j].CreatedAt)
+	})
+
+	return lists, nil
+}
+
+func (s *MemoryStorage) UpdateList(ctx context.Context, id string, updatedList models.List) (models.List, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return models.List{}, err
+	}
+
+	existing, exists := s.lists[id]
+	if !exists {
+		return models.List{}, ErrListNotFound
+	}
+
+	updatedList.ID = id
+	updatedList.Role = ""
+	updatedList.OwnerID = existing.OwnerID
+	updatedList.CreatedAt = existing.CreatedAt
+	updatedList.UpdatedAt = time.Now()
+
+	if err := s.putList(updatedList); err != nil {
+		return models.List{}, err
+	}
+	return updatedList, nil
+}
+
+func (s *MemoryStorage) DeleteList(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, exists := s.lists[id]; !exists {
+		return ErrListNotFound
+	}
+
+	if s.journal != nil {
+		if err := s.journal.append(journalRecord{Op: opDeleteList, ID: id}); err != nil {
+			return err
+		}
+	}
+
+	s.deleteList(id)
+	return nil
+}
+
+// deleteList удаляет список, его участников и задачи. Вызывается под s.mu
+// и при воспроизведении журнала.
+func (s *MemoryStorage) deleteList(id string) {
+	delete(s.lists, id)
+	delete(s.members, id)
+	for taskID, task := range s.tasks {
+		if task.ListID == id {
+			s.deleteTask(taskID)
+		}
+	}
+}
+
+func (s *MemoryStorage) GetMember(ctx context.Context, listID, userID string) (models.ListMember, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return models.ListMember{}, err
+	}
+
+	member, exists := s.members[listID][userID]
+	if !exists {
+		return models.ListMember{}, ErrMemberNotFound
+	}
+
+	return member, nil
+}
+
+func (s *MemoryStorage) GetMembers(ctx context.Context, listID string) ([]models.ListMember, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, exists := s.lists[listID]; !exists {
+		return nil, ErrListNotFound
+	}
+
+	var members []models.ListMember
+	for _, member := range s.members[listID] {
+		members = append(members, member)
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].CreatedAt.Before(members[j].CreatedAt)
+	})
+
+	return members, nil
+}
+
+func (s *MemoryStorage) PutMember(ctx context.Context, member models.ListMember) (models.ListMember, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return models.ListMember{}, err
+	}
+
+	if _, exists := s.lists[member.ListID]; !exists {
+		return models.ListMember{}, ErrListNotFound
+	}
+
+	member.Username = ""
+	if existing, exists := s.members[member.ListID][member.UserID]; exists {
+		member.CreatedAt = existing.CreatedAt
+	} else {
+		member.CreatedAt = time.Now()
+	}
+
+	if err := s.putMember(member); err != nil {
+		return models.ListMember{}, err
+	}
+	return member, nil
+}
+
+func (s *MemoryStorage) RemoveMember(ctx context.Context, listID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, exists := s.members[listID][userID]; !exists {
+		return ErrMemberNotFound
+	}
+
+	if s.journal != nil {
+		record := journalRecord{Op: opRemoveMember, ID: listID, UserID: userID}
+		if err := s.journal.append(record); err != nil {
+			return err
+		}
+	}
+
+	delete(s.members[listID], userID)
+	return nil
+}
+
+// putList записывает список в журнал и в карту. Вызывается под s.mu.
+func (s *MemoryStorage) putList(list models.List) error {
+	if s.journal != nil {
+		if err := s.journal.append(journalRecord{Op: opPutList, List: &list}); err != nil {
+			return err
+		}
+	}
+
+	s.lists[list.ID] = list
+	return nil
+}
+
+// putMember записывает участника в журнал и в карту. Вызывается под s.mu.
+func (s *MemoryStorage) putMember(member models.ListMember) error {
+	if s.journal != nil {
+		if err := s.journal.append(journalRecord{Op: opPutMember, Member: &member}); err != nil {
+			return err
+		}
+	}
+
+	s.setMember(member)
+	return nil
+}
+
+func (s *MemoryStorage) setMember(member models.ListMember) {
+	if s.members[member.ListID] == nil {
+		s.members[member.ListID] = make(map[string]models.ListMember)
+	}
+	s.members[member.ListID][member.UserID] = member
+}
@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"todo-api/internal/models"
+)
+
+func (s *MemoryStorage) CreateTag(ctx context.Context, tag models.Tag) (models.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return models.Tag{}, err
+	}
+
+	if _, exists := s.tags[tag.OwnerID][tag.Name]; exists {
+		return models.Tag{}, ErrTagExists
+	}
+
+	tag.CreatedAt = time.Now()
+	if err := s.putTag(tag); err != nil {
+		return models.Tag{}, err
+	}
+
+	return tag, nil
+}
+
+func (s *MemoryStorage) GetTag(ctx context.Context, ownerID, name string) (models.Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return models.Tag{}, err
+	}
+
+	tag, exists := s.tags[ownerID][name]
+	if !exists {
+		return models.Tag{}, ErrTagNotFound
+	}
+
+	return tag, nil
+}
+
+func (s *MemoryStorage) GetTags(ctx context.Context, ownerID string) ([]models.Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	tags := make([]models.Tag, 0, len(s.tags[ownerID]))
+	for _, tag := range s.tags[ownerID] {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+
+	return tags, nil
+}
+
+func (s *MemoryStorage) UpdateTag(ctx context.Context, ownerID, name string, tag models.Tag) (models.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return models.Tag{}, err
+	}
+
+	existing, exists := s.tags[ownerID][name]
+	if !exists {
+		return models.Tag{}, ErrTagNotFound
+	}
+	if tag.Name != name {
+		if _, exists := s.tags[ownerID][tag.Name]; exists {
+			return models.Tag{}, ErrTagExists
+		}
+	}
+
+	tag.OwnerID = ownerID
+	tag.CreatedAt = existing.CreatedAt
+
+	if s.journal != nil {
+		if err := s.journal.append(journalRecord{Op: opUpdateTag, ID: name, Tag: &tag}); err != nil {
+			return models.Tag{}, err
+		}
+	}
+
+	s.updateTag(name, tag)
+	return tag, nil
+}
+
+func (s *MemoryStorage) DeleteTag(ctx context.Context, ownerID, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, exists := s.tags[ownerID][name]; !exists {
+		return ErrTagNotFound
+	}
+
+	if s.journal != nil {
+		if err := s.journal.append(journalRecord{Op: opDeleteTag, ID: name, UserID: ownerID}); err != nil {
+			return err
+		}
+	}
+
+	s.deleteTag(ownerID, name)
+	return nil
+}
+
+func (s *MemoryStorage) CountTags(ctx context.Context, query models.TaskQuery) ([]models.TagCount, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, task := range s.matchingTasks(query) {
+		for _, tag := range task.Tags {
+			counts[tag]++
+		}
+	}
+
+	result := make([]models.TagCount, 0, len(counts))
+	for name, count := range counts {
+		result = append(result, models.TagCount{Name: name, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// putTag записывает тег каталога в журнал и в карту. Вызывается под s.mu.
+func (s *MemoryStorage) putTag(tag models.Tag) error {
+	if s.journal != nil {
+		if err := s.journal.append(journalRecord{Op: opPutTag, Tag: &tag}); err != nil {
+			return err
+		}
+	}
+
+	s.setTag(tag)
+	return nil
+}
+
+func (s *MemoryStorage) setTag(tag models.Tag) {
+	if s.tags[tag.OwnerID] == nil {
+		s.tags[tag.OwnerID] = make(map[string]models.Tag)
+	}
+	s.tags[tag.OwnerID][tag.Name] = tag
+}
+
+// updateTag заменяет тег name в каталоге и, если он переименован, в задачах
+// владельца. Вызывается под s.mu, в том числе при восстановлении из журнала.
+func (s *MemoryStorage) updateTag(name string, tag models.Tag) {
+	delete(s.tags[tag.OwnerID], name)
+	s.setTag(tag)
+
+	if tag.Name != name {
+		s.replaceTaskTag(tag.OwnerID, name, tag.Name)
+	}
+}
+
+// deleteTag удаляет тег из каталога и из задач владельца.
+// Вызывается под s.mu, в том числе при восстановлении из журнала.
+func (s *MemoryStorage) deleteTag(ownerID, name string) {
+	delete(s.tags[ownerID], name)
+	s.replaceTaskTag(ownerID, name, "")
+}
+
+// replaceTaskTag заменяет тег from на to (или удаляет его, если to пустой)
+// во всех задачах владельца, увеличивая их версии. Срезы тегов не меняются на месте: их могли
+// получить вызывающие вместе с копией задачи.
+func (s *MemoryStorage) replaceTaskTag(ownerID, from, to string) {
+	for _, task := range s.tasks {
+		if task.OwnerID != ownerID || !slices.Contains(task.Tags, from) {
+			continue
+		}
+
+		var tags []string
+		for _, tag := range task.Tags {
+			if tag != from {
+				tags = append(tags, tag)
+			}
+		}
+		if to != "" && !slices.Contains(tags, to) {
+			tags = append(tags, to)
+			sort.Strings(tags)
+		}
+
+		task.Tags = tags
+		task.Version++
+		s.setTask(task)
+	}
+}
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"todo-api/internal/models"
+)
+
+func (s *MemoryStorage) GetDeleted(ctx context.Context, id string) (models.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return models.Task{}, err
+	}
+
+	task, exists := s.tasks[id]
+	if !exists || task.DeletedAt == nil {
+		return models.Task{}, ErrTaskNotFound
+	}
+
+	return task, nil
+}
+
+func (s *MemoryStorage) Restore(ctx context.Context, id string) (models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return models.Task{}, err
+	}
+
+	task, exists := s.tasks[id]
+	if !exists || task.DeletedAt == nil {
+		return models.Task{}, ErrTaskNotFound
+	}
+
+	restored := markRestored(s.tasks, id, *task.DeletedAt)
+	if err := s.putAll(restored); err != nil {
+		return models.Task{}, err
+	}
+	return restored[0], nil
+}
+
+func (s *MemoryStorage) Purge(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var expired []string
+	for id, task := range s.tasks {
+		if task.DeletedAt != nil && task.DeletedAt.Before(before) {
+			expired = append(expired, id)
+		}
+	}
+
+	purged := 0
+	for _, id := range expired {
+		// Подзадачи могли уже удалиться вместе с родителем
+		if _, exists := s.tasks[id]; exists {
+			if err := s.remove(id); err != nil {
+				return purged, err
+			}
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// markDeleted возвращает задачу id и все её подзадачи не из корзины,
+// помеченные удалёнными в момент deletedAt. Карта tasks не меняется.
+func markDeleted(tasks map[string]models.Task, id string, deletedAt time.Time) []models.Task {
+	task := tasks[id]
+	task.DeletedAt = &deletedAt
+	task.Version++
+
+	marked := []models.Task{task}
+	for _, child := range tasks {
+		if child.ParentID == id && child.DeletedAt == nil {
+			marked = append(marked, markDeleted(tasks, child.ID, deletedAt)...)
+		}
+	}
+	return marked
+}
+
+// markRestored возвращает задачу id и её подзадачи, удалённые вместе с ней
+// в момент deletedAt, с очищенным DeletedAt. Подзадачи, удалённые раньше
+// родителя, остаются в корзине. Карта tasks не меняется.
+func markRestored(tasks map[string]models.Task, id string, deletedAt time.Time) []models.Task {
+	task := tasks[id]
+	task.DeletedAt = nil
+	task.Version++
+
+	marked := []models.Task{task}
+	for _, child := range tasks {
+		if child.ParentID == id && child.DeletedAt != nil && child.DeletedAt.Equal(deletedAt) {
+			marked = append(marked, markRestored(tasks, child.ID, deletedAt)...)
+		}
+	}
+	return marked
+}
+
+// putAll записывает изменения задач в журнал одной записью и применяет их
+// к карте. Вызывается под s.mu.
+func (s *MemoryStorage) putAll(tasks []models.Task) error {
+	records := make([]journalRecord, len(tasks))
+	for i := range tasks {
+		records[i] = journalRecord{Op: opUpdate, Task: &tasks[i]}
+	}
+
+	if s.journal != nil {
+		if err := s.journal.append(journalRecord{Op: opBatch, Batch: records}); err != nil {
+			return err
+		}
+	}
+	for _, task := range tasks {
+		s.setTask(task)
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"todo-api/internal/models"
+)
+
+func (s *MemoryStorage) CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return models.Webhook{}, err
+	}
+
+	webhook.ID = uuid.New().String()
+	webhook.Events = slices.Clone(webhook.Events)
+	webhook.CreatedAt = time.Now()
+	webhook.UpdatedAt = webhook.CreatedAt
+
+	if err := s.putWebhook(webhook); err != nil {
+		return models.Webhook{}, err
+	}
+	return webhook, nil
+}
+
+func (s *MemoryStorage) GetWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return models.Webhook{}, err
+	}
+
+	webhook, exists := s.webhooks[id]
+	if !exists {
+		return models.Webhook{}, ErrWebhookNotFound
+	}
+
+	webhook.Events = slices.Clone(webhook.Events)
+	return webhook, nil
+}
+
+func (s *MemoryStorage) GetWebhooks(ctx context.Context, ownerID string) ([]models.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var webhooks []models.Webhook
+	for _, webhook := range s.webhooks {
+		if webhook.OwnerID == ownerID {
+			webhook.Events = slices.Clone(webhook.Events)
+			webhooks = append(webhooks, webhook)
+		}
+	}
+
+	slices.SortFunc(webhooks, func(a, b models.Webhook) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+
+	return webhooks, nil
+}
+
+func (s *MemoryStorage) UpdateWebhook(ctx context.Context, id string, webhook models.Webhook) (models.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return models.Webhook{}, err
+	}
+
+	existing, exists := s.webhooks[id]
+	if !exists {
+		return models.Webhook{}, ErrWebhookNotFound
+	}
+
+	webhook.ID = id
+	webhook.OwnerID = existing.OwnerID
+	webhook.Secret = existing.Secret
+	webhook.Events = slices.Clone(webhook.Events)
+	webhook.CreatedAt = existing.CreatedAt
+	webhook.UpdatedAt = time.Now()
+
+	if err := s.putWebhook(webhook); err != nil {
+		return models.Webhook{}, err
+	}
+	return webhook, nil
+}
+
+func (s *MemoryStorage) DeleteWebhook(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, exists := s.webhooks[id]; !exists {
+		return ErrWebhookNotFound
+	}
+
+	if s.journal != nil {
+		if err := s.journal.append(journalRecord{Op: opDeleteWebhook, ID: id}); err != nil {
+			return err
+		}
+	}
+
+	s.deleteWebhook(id)
+	return nil
+}
+
+func (s *MemoryStorage) AddDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	records := make([]journalRecord, 0, len(deliveries))
+	for i := range deliveries {
+		if _, exists := s.webhooks[deliveries[i].WebhookID]; !exists {
+			return ErrWebhookNotFound
+		}
+
+		deliveries[i].ID = uuid.New().String()
+		deliveries[i].CreatedAt = now
+		deliveries[i].UpdatedAt = now
+		records = append(records, journalRecord{Op: opPutDelivery, Delivery: &deliveries[i]})
+	}
+
+	// Доставки одного события записываются одной записью журнала
+	if s.journal != nil {
+		if err := s.journal.append(journalRecord{Op: opBatch, Batch: records}); err != nil {
+			return err
+		}
+	}
+
+	for _, delivery := range deliveries {
+		s.deliveries[delivery.ID] = delivery
+	}
+	return nil
+}
+
+func (s *MemoryStorage) GetDelivery(ctx context.Context, id string) (models.WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	delivery, exists := s.deliveries[id]
+	if !exists {
+		return models.WebhookDelivery{}, ErrDeliveryNotFound
+	}
+
+	return delivery, nil
+}
+
+func (s *MemoryStorage) GetDeliveries(ctx context.Context, query models.DeliveryQuery) ([]models.WebhookDelivery, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var deliveries []models.WebhookDelivery
+	for _, delivery := range s.deliveries {
+		if query.WebhookID != "" && delivery.WebhookID != query.WebhookID {
+			continue
+		}
+		if query.OwnerID != "" && s.webhooks[delivery.WebhookID].OwnerID != query.OwnerID {
+			continue
+		}
+		if query.Status != "" && delivery.Status != query.Status {
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	slices.SortFunc(deliveries, func(a, b models.WebhookDelivery) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(b.ID, a.ID))
+	})
+
+	total := len(deliveries)
+	start := min(max(query.Offset, 0), total)
+	end := min(start+query.Limit, total)
+
+	return slices.Clone(deliveries[start:end]), total, nil
+}
+
+func (s *MemoryStorage) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var due []models.WebhookDelivery
+	for _, delivery := range s.deliveries {
+		if delivery.Status == models.DeliveryPending && delivery.NextAttemptAt != nil && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+
+	slices.SortFunc(due, func(a, b models.WebhookDelivery) int {
+		return cmp.Or(a.NextAttemptAt.Compare(*b.NextAttemptAt), cmp.Compare(a.ID, b.ID))
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	leaseUntil := now.Add(lease)
+	for i := range due {
+		due[i].NextAttemptAt = &leaseUntil
+		if err := s.putDelivery(due[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return due, nil
+}
+
+func (s *MemoryStorage) UpdateDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	existing, exists := s.deliveries[delivery.ID]
+	if !exists {
+		return ErrDeliveryNotFound
+	}
+
+	delivery.WebhookID = existing.WebhookID
+	delivery.EventID = existing.EventID
+	delivery.Event = existing.Event
+	delivery.TaskID = existing.TaskID
+	delivery.Payload = existing.Payload
+	delivery.CreatedAt = existing.CreatedAt
+	delivery.UpdatedAt = time.Now()
+
+	return s.putDelivery(delivery)
+}
+
+// putWebhook записывает вебхук в журнал и в карту. Вызывается под s.mu.
+func (s *MemoryStorage) putWebhook(webhook models.Webhook) error {
+	if s.journal != nil {
+		if err := s.journal.append(journalRecord{Op: opPutWebhook, Webhook: &webhook}); err != nil {
+			return err
+		}
+	}
+
+	s.webhooks[webhook.ID] = webhook
+	return nil
+}
+
+// putDelivery записывает доставку в журнал и в карту. Вызывается под s.mu.
+func (s *MemoryStorage) putDelivery(delivery models.WebhookDelivery) error {
+	if s.journal != nil {
+		if err := s.journal.append(journalRecord{Op: opPutDelivery, Delivery: &delivery}); err != nil {
+			return err
+		}
+	}
+
+	s.deliveries[delivery.ID] = delivery
+	return nil
+}
+
+// deleteWebhook удаляет вебхук вместе с его доставками. Вызывается под s.mu.
+func (s *MemoryStorage) deleteWebhook(id string) {
+	delete(s.webhooks, id)
+	for deliveryID, delivery := range s.deliveries {
+		if delivery.WebhookID == id {
+			delete(s.deliveries, deliveryID)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// loadMigrations читает файлы вида 0001_name.sql из каталога dir
+// и возвращает их отсортированными по номеру версии.
+func loadMigrations(fsys fs.FS, dir string) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []migration
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		prefix, _, found := strings.Cut(entry.Name(), "_")
+		if !found {
+			return nil, fmt.Errorf("invalid migration name %q", entry.Name())
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q: %w", entry.Name(), err)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, migration{
+			version: version,
+			name:    entry.Name(),
+			sql:     string(content),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	return migrations, nil
+}
+
+// migrate применяет все ещё не применённые миграции. Каждая миграция
+// выполняется в отдельной транзакции вместе с записью в schema_migrations.
+// Транзакции открываются запросами dialect.beginMigration, которые не дают
+// нескольким процессам, запущенным одновременно, применить одну миграцию
+// дважды: версия проверяется заново уже под блокировкой.
+func migrate(db *sql.DB, dialect sqlDialect, fsys fs.FS, dir string) error {
+	migrations, err := loadMigrations(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("load migrations: %w", err)
+	}
+
+	// Транзакция открывается запросами, а не db.Begin, поэтому все запросы
+	// миграции идут через одно соединение
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	err = inMigrationTx(ctx, conn, dialect, func() error {
+		_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    INTEGER PRIMARY KEY,
+			name       TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	for _, m := range migrations {
+		err := inMigrationTx(ctx, conn, dialect, func() error {
+			// Версия и имя берутся из встроенных файлов, поэтому запросы
+			// собираются без плейсхолдеров - так они одинаково работают в любой СУБД.
+			var applied int
+			check := fmt.Sprintf(`SELECT COUNT(*) FROM schema_migrations WHERE version = %d`, m.version)
+			if err := conn.QueryRowContext(ctx, check).Scan(&applied); err != nil {
+				return fmt.Errorf("read schema_migrations: %w", err)
+			}
+			if applied > 0 {
+				return nil
+			}
+
+			if _, err := conn.ExecContext(ctx, m.sql); err != nil {
+				return fmt.Errorf("apply migration %s: %w", m.name, err)
+			}
+			record := fmt.Sprintf(
+				`INSERT INTO schema_migrations (version, name) VALUES (%d, '%s')`,
+				m.version, strings.ReplaceAll(m.name, "'", "''"),
+			)
+			if _, err := conn.ExecContext(ctx, record); err != nil {
+				return fmt.Errorf("record migration %s: %w", m.name, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// inMigrationTx выполняет fn в транзакции миграции на соединении conn и
+// фиксирует ее, если fn не вернула ошибку
+func inMigrationTx(ctx context.Context, conn *sql.Conn, dialect sqlDialect, fn func() error) error {
+	for i, query := range dialect.beginMigration {
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			if i > 0 {
+				conn.ExecContext(ctx, "ROLLBACK")
+			}
+			return fmt.Errorf("lock migrations: %w", err)
+		}
+	}
+
+	if err := fn(); err != nil {
+		conn.ExecContext(ctx, "ROLLBACK")
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, "COMMIT"); err != nil {
+		return fmt.Errorf("commit migration: %w", err)
+	}
+	return nil
+}
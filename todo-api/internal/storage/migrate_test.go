@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// testConcurrentOpen открывает хранилище из нескольких горутин сразу, как
+// реплики, запущенные одновременно: все должны открыться, а каждая миграция -
+// записаться в schema_migrations один раз
+func testConcurrentOpen(t *testing.T, fsys fs.FS, dir string, open func() (*sqlStorage, error)) {
+	t.Helper()
+
+	migrations, err := loadMigrations(fsys, dir)
+	if err != nil {
+		t.Fatalf("loadMigrations() error = %v", err)
+	}
+
+	const replicas = 4
+	stores := make([]*sqlStorage, replicas)
+	errs := make([]error, replicas)
+	var wg sync.WaitGroup
+	for i := range replicas {
+		wg.Go(func() {
+			stores[i], errs[i] = open()
+		})
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("replica %d: open error = %v", i, err)
+		}
+		t.Cleanup(func() { stores[i].Close() })
+	}
+
+	var total, versions int
+	err = stores[0].db.QueryRow(`SELECT COUNT(*), COUNT(DISTINCT version) FROM schema_migrations`).Scan(&total, &versions)
+	if err != nil {
+		t.Fatalf("read schema_migrations: %v", err)
+	}
+	if total != len(migrations) || versions != len(migrations) {
+		t.Errorf("schema_migrations has %d rows with %d versions, want %d", total, versions, len(migrations))
+	}
+}
+
+func TestSQLiteConcurrentOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todo.db")
+	testConcurrentOpen(t, sqliteMigrations, "migrations/sqlite", func() (*sqlStorage, error) {
+		s, err := NewSQLiteStorage(path)
+		if err != nil {
+			return nil, err
+		}
+		return &s.sqlStorage, nil
+	})
+}
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS users (
+    id            TEXT PRIMARY KEY,
+    username      TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL
+);
+
+ALTER TABLE tasks ADD COLUMN owner_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS tasks_owner_id_idx ON tasks (owner_id);
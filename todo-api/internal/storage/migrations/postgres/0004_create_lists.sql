@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS lists (
+    id          TEXT PRIMARY KEY,
+    name        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    owner_id    TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at  TIMESTAMPTZ NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS list_members (
+    list_id    TEXT NOT NULL REFERENCES lists (id) ON DELETE CASCADE,
+    user_id    TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role       TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (list_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS list_members_user_id_idx ON list_members (user_id);
+
+ALTER TABLE tasks ADD COLUMN list_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS tasks_list_id_idx ON tasks (list_id);
@@ -0,0 +1 @@
+ALTER TABLE tasks ADD COLUMN recurrence TEXT NOT NULL DEFAULT '';
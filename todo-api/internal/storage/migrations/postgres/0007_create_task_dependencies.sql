@@ -0,0 +1,11 @@
+ALTER TABLE tasks ADD COLUMN parent_id TEXT REFERENCES tasks (id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS tasks_parent_id_idx ON tasks (parent_id);
+
+CREATE TABLE IF NOT EXISTS task_dependencies (
+    task_id    TEXT NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    blocker_id TEXT NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, blocker_id)
+);
+
+CREATE INDEX IF NOT EXISTS task_dependencies_blocker_id_idx ON task_dependencies (blocker_id);
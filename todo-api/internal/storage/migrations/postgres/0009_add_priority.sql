@@ -0,0 +1,3 @@
+ALTER TABLE tasks ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS tasks_priority_idx ON tasks (priority);
@@ -0,0 +1,6 @@
+ALTER TABLE tasks ADD COLUMN due_at TIMESTAMP;
+ALTER TABLE tasks ADD COLUMN remind_at TIMESTAMP;
+ALTER TABLE tasks ADD COLUMN reminded_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS tasks_due_at_idx ON tasks (due_at);
+CREATE INDEX IF NOT EXISTS tasks_remind_at_idx ON tasks (remind_at) WHERE reminded_at IS NULL;
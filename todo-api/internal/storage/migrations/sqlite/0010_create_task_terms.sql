@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS task_terms (
+    task_id  TEXT    NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    term     TEXT    NOT NULL,
+    weight   INTEGER NOT NULL,
+    PRIMARY KEY (task_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS task_terms_term_idx ON task_terms (term, task_id);
@@ -0,0 +1,12 @@
+-- seq задаёт порядок записей: у нескольких записей может совпасть created_at
+CREATE TABLE IF NOT EXISTS task_history (
+    seq        INTEGER PRIMARY KEY AUTOINCREMENT,
+    id         TEXT NOT NULL UNIQUE,
+    task_id    TEXT NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    actor_id   TEXT NOT NULL,
+    action     TEXT NOT NULL,
+    changes    TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS task_history_task_id_idx ON task_history (task_id, seq);
@@ -0,0 +1,383 @@
+package storage
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"todo-api/internal/models"
+)
+
+const (
+	journalFileName  = "journal.log"
+	snapshotFileName = "snapshot.json"
+)
+
+// PersistenceOptions настраивает сохранение MemoryStorage на диск.
+type PersistenceOptions struct {
+	// Dir - каталог для журнала и снимка.
+	Dir string
+	// SnapshotInterval - период фоновой компактизации. 0 отключает её,
+	// тогда журнал сжимается только вызовом Compact.
+	SnapshotInterval time.Duration
+	// SyncWrites включает fsync после каждой записи журнала.
+	SyncWrites bool
+}
+
+// NewPersistentMemoryStorage восстанавливает MemoryStorage из снимка и журнала
+// в каталоге opts.Dir. Все последующие изменения сначала пишутся в журнал
+// и только потом применяются к карте задач.
+func NewPersistentMemoryStorage(opts PersistenceOptions) (*MemoryStorage, error) {
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	s := NewMemoryStorage()
+	s.snapshotPath = filepath.Join(opts.Dir, snapshotFileName)
+
+	snap, err := readSnapshot(s.snapshotPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, task := range snap.Tasks {
+		s.setTask(task)
+	}
+	for _, dependency := range snap.Dependencies {
+		s.setDependency(dependency.TaskID, dependency.BlockerID)
+	}
+	for _, tag := range snap.Tags {
+		s.setTag(tag)
+	}
+	for _, entry := range snap.History {
+		s.history[entry.TaskID] = append(s.history[entry.TaskID], entry)
+	}
+	for _, user := range snap.Users {
+		s.users[user.ID] = user.toModel()
+	}
+	for _, list := range snap.Lists {
+		s.lists[list.ID] = list
+	}
+	for _, member := range snap.Members {
+		s.setMember(member)
+	}
+	for _, token := range snap.RefreshTokens {
+		s.refreshTokens[token.TokenHash] = token
+	}
+	for jti, expiresAt := range snap.RevokedTokens {
+		s.revokedTokens[jti] = expiresAt
+	}
+	for _, webhook := range snap.Webhooks {
+		s.webhooks[webhook.ID] = webhook
+	}
+	for _, delivery := range snap.Deliveries {
+		s.deliveries[delivery.ID] = delivery
+	}
+
+	journal, records, err := openJournal(filepath.Join(opts.Dir, journalFileName), opts.SyncWrites)
+	if err != nil {
+		return nil, fmt.Errorf("open journal: %w", err)
+	}
+
+	// Записи, уже вошедшие в снимок, пропускаются: сбой мог произойти
+	// между записью снимка и очисткой журнала.
+	for _, record := range records {
+		if record.Seq <= snap.Seq {
+			continue
+		}
+		s.replay(record)
+	}
+	if journal.seq < snap.Seq {
+		journal.seq = snap.Seq
+	}
+	s.journal = journal
+	s.snapshotSeq = snap.Seq
+
+	if opts.SnapshotInterval > 0 {
+		s.stop = make(chan struct{})
+		s.done = make(chan struct{})
+		go s.compactLoop(opts.SnapshotInterval)
+	}
+
+	return s, nil
+}
+
+func (s *MemoryStorage) replay(record journalRecord) {
+	switch record.Op {
+	case opBatch:
+		for _, change := range record.Batch {
+			s.replay(change)
+		}
+	case opDelete:
+		s.deleteTask(record.ID)
+	case opAddHistory:
+		if record.History != nil {
+			s.history[record.History.TaskID] = append(s.history[record.History.TaskID], *record.History)
+		}
+	case opAddDependency:
+		s.setDependency(record.ID, record.BlockerID)
+	case opRemoveDependency:
+		delete(s.dependencies[record.ID], record.BlockerID)
+	case opPutTag:
+		if record.Tag != nil {
+			s.setTag(*record.Tag)
+		}
+	case opUpdateTag:
+		if record.Tag != nil {
+			s.updateTag(record.ID, *record.Tag)
+		}
+	case opDeleteTag:
+		s.deleteTag(record.UserID, record.ID)
+	case opCreateUser:
+		if record.User != nil {
+			s.users[record.User.ID] = record.User.toModel()
+		}
+	case opPutList:
+		if record.List != nil {
+			s.lists[record.List.ID] = *record.List
+		}
+	case opDeleteList:
+		s.deleteList(record.ID)
+	case opPutMember:
+		if record.Member != nil {
+			s.setMember(*record.Member)
+		}
+	case opRemoveMember:
+		delete(s.members[record.ID], record.UserID)
+	case opPutRefreshToken:
+		if record.RefreshToken != nil {
+			s.refreshTokens[record.RefreshToken.TokenHash] = *record.RefreshToken
+		}
+	case opRevokeAccessToken:
+		if record.ExpiresAt != nil {
+			s.revokedTokens[record.ID] = *record.ExpiresAt
+		}
+	case opPutWebhook:
+		if record.Webhook != nil {
+			s.webhooks[record.Webhook.ID] = *record.Webhook
+		}
+	case opDeleteWebhook:
+		s.deleteWebhook(record.ID)
+	case opPutDelivery:
+		if record.Delivery != nil {
+			s.deliveries[record.Delivery.ID] = *record.Delivery
+		}
+	default:
+		if record.Task != nil {
+			s.setTask(*record.Task)
+		}
+	}
+}
+
+// put записывает изменение задачи в журнал и применяет его к карте.
+// Вызывается под s.mu.
+func (s *MemoryStorage) put(op string, task models.Task) error {
+	if s.journal != nil {
+		if err := s.journal.append(journalRecord{Op: op, Task: &task}); err != nil {
+			return err
+		}
+	}
+
+	s.setTask(task)
+	return nil
+}
+
+// setTask сохраняет задачу в карте и в поисковом индексе и сообщает об
+// изменении в onChange. Вызывается под s.mu.
+func (s *MemoryStorage) setTask(task models.Task) {
+	previous, existed := s.tasks[task.ID]
+	s.tasks[task.ID] = task
+	s.index.Put(task.ID, task.Title, task.Description)
+
+	if s.onChange == nil || (existed && previous.DeletedAt != nil && task.DeletedAt != nil) {
+		return
+	}
+	event := models.ChangeEvent{Type: models.ChangeUpdated, Task: &task}
+	switch {
+	case !existed:
+		event.Type = models.ChangeCreated
+	case previous.DeletedAt == nil && task.DeletedAt != nil:
+		event.Type = models.ChangeDeleted
+	case previous.DeletedAt != nil && task.DeletedAt == nil:
+		event.Type = models.ChangeRestored
+	}
+	if existed {
+		event.Previous = &previous
+	}
+	s.onChange(event)
+}
+
+// remove записывает удаление задачи в журнал и удаляет её вместе с подзадачами.
+// Вызывается под s.mu.
+func (s *MemoryStorage) remove(id string) error {
+	if s.journal != nil {
+		if err := s.journal.append(journalRecord{Op: opDelete, ID: id}); err != nil {
+			return err
+		}
+	}
+
+	s.deleteTask(id)
+	return nil
+}
+
+// putUser записывает пользователя в журнал и добавляет его в карту.
+// Вызывается под s.mu.
+func (s *MemoryStorage) putUser(user models.User) error {
+	if s.journal != nil {
+		stored := newStoredUser(user)
+		if err := s.journal.append(journalRecord{Op: opCreateUser, User: &stored}); err != nil {
+			return err
+		}
+	}
+
+	s.users[user.ID] = user
+	return nil
+}
+
+// putRefreshToken записывает состояние токена обновления в журнал и в карту.
+// Вызывается под s.mu.
+func (s *MemoryStorage) putRefreshToken(token models.RefreshToken) error {
+	if s.journal != nil {
+		if err := s.journal.append(journalRecord{Op: opPutRefreshToken, RefreshToken: &token}); err != nil {
+			return err
+		}
+	}
+
+	s.refreshTokens[token.TokenHash] = token
+	return nil
+}
+
+// putRevokedToken записывает отзыв токена доступа в журнал и в карту.
+// Вызывается под s.mu.
+func (s *MemoryStorage) putRevokedToken(jti string, expiresAt time.Time) error {
+	if s.journal != nil {
+		if err := s.journal.append(journalRecord{Op: opRevokeAccessToken, ID: jti, ExpiresAt: &expiresAt}); err != nil {
+			return err
+		}
+	}
+
+	s.revokedTokens[jti] = expiresAt
+	return nil
+}
+
+// Compact сохраняет снимок текущего состояния и очищает журнал.
+// Для хранилища без журнала ничего не делает.
+func (s *MemoryStorage) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.journal == nil || s.journal.seq == s.snapshotSeq {
+		return nil
+	}
+
+	snap := snapshot{
+		Seq:   s.journal.seq,
+		Tasks: make([]models.Task, 0, len(s.tasks)),
+	}
+	for _, task := range s.tasks {
+		snap.Tasks = append(snap.Tasks, task)
+	}
+	for taskID, blockers := range s.dependencies {
+		for blockerID := range blockers {
+			snap.Dependencies = append(snap.Dependencies, dependency{TaskID: taskID, BlockerID: blockerID})
+		}
+	}
+	for _, tags := range s.tags {
+		for _, tag := range tags {
+			snap.Tags = append(snap.Tags, tag)
+		}
+	}
+	for _, entries := range s.history {
+		snap.History = append(snap.History, entries...)
+	}
+	for _, user := range s.users {
+		snap.Users = append(snap.Users, newStoredUser(user))
+	}
+	for _, list := range s.lists {
+		snap.Lists = append(snap.Lists, list)
+	}
+	for _, members := range s.members {
+		for _, member := range members {
+			snap.Members = append(snap.Members, member)
+		}
+	}
+	for _, webhook := range s.webhooks {
+		snap.Webhooks = append(snap.Webhooks, webhook)
+	}
+	for _, delivery := range s.deliveries {
+		snap.Deliveries = append(snap.Deliveries, delivery)
+	}
+
+	// Истёкшие токены больше не нужны ни для проверки, ни для ротации,
+	// поэтому при компактизации они не попадают в снимок.
+	now := time.Now()
+	snap.RevokedTokens = make(map[string]time.Time, len(s.revokedTokens))
+	for jti, expiresAt := range s.revokedTokens {
+		if expiresAt.Before(now) {
+			delete(s.revokedTokens, jti)
+			continue
+		}
+		snap.RevokedTokens[jti] = expiresAt
+	}
+	for hash, token := range s.refreshTokens {
+		if token.ExpiresAt.Before(now) {
+			delete(s.refreshTokens, hash)
+			continue
+		}
+		snap.RefreshTokens = append(snap.RefreshTokens, token)
+	}
+
+	if err := writeSnapshot(s.snapshotPath, snap); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	s.snapshotSeq = snap.Seq
+
+	if err := s.journal.reset(); err != nil {
+		return fmt.Errorf("reset journal: %w", err)
+	}
+
+	return nil
+}
+
+func (s *MemoryStorage) compactLoop(interval time.Duration) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Compact(); err != nil {
+				slog.Error("Failed to compact storage", slog.Any("error", err))
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Close останавливает фоновую компактизацию, сохраняет снимок и закрывает журнал.
+func (s *MemoryStorage) Close() error {
+	if s.journal == nil {
+		return nil
+	}
+
+	if s.stop != nil {
+		close(s.stop)
+		<-s.done
+		s.stop = nil
+	}
+
+	if err := s.Compact(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.journal.close()
+	s.journal = nil
+	return err
+}
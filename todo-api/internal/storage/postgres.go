@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+//go:embed migrations/postgres/*.sql
+var postgresMigrations embed.FS
+
+var postgresDialect = sqlDialect{
+	rebind: func(query string) string {
+		return query
+	},
+	sortText: func(column string) string {
+		// Правила сортировки локали базы отличаются от порядка кодовых точек
+		return fmt.Sprintf(`lower(%s) COLLATE "C"`, column)
+	},
+	// Транзакционная рекомендательная блокировка снимается при COMMIT или
+	// ROLLBACK; ключ - произвольная константа приложения
+	beginMigration: []string{"BEGIN", "SELECT pg_advisory_xact_lock(7157342054651420739)"},
+}
+
+// PostgresStorage хранит задачи в PostgreSQL. Схема создаётся и обновляется
+// миграциями при открытии хранилища.
+type PostgresStorage struct {
+	sqlStorage
+}
+
+func NewPostgresStorage(dsn string) (*PostgresStorage, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open postgres: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ping postgres: %w", err)
+	}
+
+	if err := migrate(db, postgresDialect, postgresMigrations, "migrations/postgres"); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	s := &PostgresStorage{sqlStorage{db: db, dialect: postgresDialect}}
+	if err := s.indexTasks(context.Background()); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("index tasks: %w", err)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-api/internal/models"
+)
+
+// postgresDSNEnv - переменная окружения со строкой подключения к PostgreSQL
+// для тестов. Без нее тесты PostgreSQL пропускаются.
+const postgresDSNEnv = "TODO_TEST_POSTGRES_DSN"
+
+// postgresTestDSN возвращает строку подключения к пустой схеме, которая
+// удаляется после теста, чтобы тесты не видели данных друг друга
+func postgresTestDSN(t *testing.T) string {
+	t.Helper()
+
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", postgresDSNEnv)
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+	schema := fmt.Sprintf("todo_test_%d", time.Now().UnixNano())
+	if _, err := db.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		db.Close()
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := db.Exec(`DROP SCHEMA ` + schema + ` CASCADE`); err != nil {
+			t.Errorf("drop schema: %v", err)
+		}
+		db.Close()
+	})
+
+	// Неизвестные pgx параметры строки подключения становятся параметрами сеанса
+	switch {
+	case !strings.Contains(dsn, "://"):
+		return dsn + " search_path=" + schema
+	case strings.Contains(dsn, "?"):
+		return dsn + "&search_path=" + schema
+	default:
+		return dsn + "?search_path=" + schema
+	}
+}
+
+func newTestPostgresStorage(t *testing.T) *PostgresStorage {
+	t.Helper()
+
+	s, err := NewPostgresStorage(postgresTestDSN(t))
+	if err != nil {
+		t.Fatalf("NewPostgresStorage() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+func TestPostgresConcurrentOpen(t *testing.T) {
+	dsn := postgresTestDSN(t)
+	testConcurrentOpen(t, postgresMigrations, "migrations/postgres", func() (*sqlStorage, error) {
+		s, err := NewPostgresStorage(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return &s.sqlStorage, nil
+	})
+}
+
+func TestPostgresReopen(t *testing.T) {
+	dsn := postgresTestDSN(t)
+
+	s, err := NewPostgresStorage(dsn)
+	if err != nil {
+		t.Fatalf("NewPostgresStorage() error = %v", err)
+	}
+	if _, err := s.CreateUser(t.Context(), models.User{Username: "alice", PasswordHash: "hash"}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	s.Close()
+
+	// Повторное открытие не применяет миграции заново и не теряет данные
+	s, err = NewPostgresStorage(dsn)
+	if err != nil {
+		t.Fatalf("NewPostgresStorage() reopen error = %v", err)
+	}
+	defer s.Close()
+	if _, err := s.GetUserByUsername(t.Context(), "alice"); err != nil {
+		t.Errorf("GetUserByUsername() after reopen error = %v", err)
+	}
+}
+
+func TestPostgresCRUD(t *testing.T) {
+	testCRUD(t, newTestPostgresStorage(t))
+}
@@ -0,0 +1,745 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"todo-api/internal/models"
+	"todo-api/internal/search"
+)
+
+const (
+	taskColumns = `id, title, description, completed, priority, owner_id, list_id, parent_id, due_at, remind_at, reminded_at, recurrence, created_at, updated_at, deleted_at, version`
+	userColumns = `id, username, password_hash, created_at`
+
+	refreshTokenColumns = `token_hash, user_id, family_id, expires_at, created_at, used_at, revoked_at`
+)
+
+// sqlDialect описывает различия между СУБД, которые важны для запросов
+// sqlStorage. Запросы пишутся с плейсхолдерами в стиле PostgreSQL ($1, $2).
+type sqlDialect struct {
+	// rebind переводит плейсхолдеры $N в синтаксис конкретной СУБД.
+	rebind func(query string) string
+	// sortText возвращает выражение для сортировки текста без учёта регистра
+	// в порядке кодовых точек, как strings.Compare в MemoryStorage.
+	sortText func(column string) string
+	// beginMigration открывают транзакцию миграции и берут блокировку, которая
+	// держится до ее конца и не пускает другие процессы к миграциям.
+	beginMigration []string
+}
+
+// sqlStorage - общая реализация TaskStorage поверх database/sql,
+// используемая PostgresStorage и SQLiteStorage.
+type sqlStorage struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+func (s *sqlStorage) Close() error {
+	return s.db.Close()
+}
+
+func (s *sqlStorage) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return s.db.ExecContext(ctx, s.dialect.rebind(query), args...)
+}
+
+func (s *sqlStorage) query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return s.db.QueryContext(ctx, s.dialect.rebind(query), args...)
+}
+
+func (s *sqlStorage) queryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	return s.db.QueryRowContext(ctx, s.dialect.rebind(query), args...)
+}
+
+// sqlTx - транзакция с тем же переводом плейсхолдеров, что и у sqlStorage.
+// Запросы выполняются с контекстом, в котором транзакция начата.
+type sqlTx struct {
+	ctx     context.Context
+	tx      *sql.Tx
+	dialect sqlDialect
+}
+
+func (t sqlTx) exec(query string, args ...any) (sql.Result, error) {
+	return t.tx.ExecContext(t.ctx, t.dialect.rebind(query), args...)
+}
+
+func (t sqlTx) queryRow(query string, args ...any) *sql.Row {
+	return t.tx.QueryRowContext(t.ctx, t.dialect.rebind(query), args...)
+}
+
+// withTx выполняет fn в транзакции и фиксирует её, если fn не вернула ошибку.
+func (s *sqlStorage) withTx(ctx context.Context, fn func(tx sqlTx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(sqlTx{ctx: ctx, tx: tx, dialect: s.dialect}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *sqlStorage) Create(ctx context.Context, task models.Task) (models.Task, error) {
+	err := s.withTx(ctx, func(tx sqlTx) error {
+		var err error
+		task, err = createTask(tx, task)
+		return err
+	})
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	return task, nil
+}
+
+func createTask(tx sqlTx, task models.Task) (models.Task, error) {
+	task.ID = uuid.New().String()
+	task.CreatedAt = now()
+	task.UpdatedAt = task.CreatedAt
+	task.Version = 1
+
+	_, err := tx.exec(
+		`INSERT INTO tasks (`+taskColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`,
+		task.ID, task.Title, task.Description, task.Completed, task.Priority, task.OwnerID, task.ListID, nullString(task.ParentID),
+		utc(task.DueAt), utc(task.RemindAt), utc(task.RemindedAt), task.Recurrence, task.CreatedAt, task.UpdatedAt, utc(task.DeletedAt), task.Version,
+	)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	if err := setTaskTerms(tx, task); err != nil {
+		return models.Task{}, err
+	}
+	if err := setTaskTags(tx, task.ID, task.Tags); err != nil {
+		return models.Task{}, err
+	}
+
+	return task, nil
+}
+
+func (s *sqlStorage) GetByID(ctx context.Context, id string) (models.Task, error) {
+	row := s.queryRow(ctx, `SELECT `+taskColumns+` FROM tasks WHERE id = $1 AND deleted_at IS NULL`, id)
+	task, err := scanTask(row)
+	return s.withTags(ctx, task, err)
+}
+
+func (s *sqlStorage) GetAll(ctx context.Context, query models.TaskQuery) ([]models.Task, int, error) {
+	where, args := s.taskFilter(query)
+
+	var total int
+	if err := s.queryRow(ctx, `SELECT count(*) FROM tasks`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	// При поиске задачи возвращаются вместе с релевантностью
+	columns := taskColumns
+	if query.Search != "" {
+		score, err := s.scoreColumn(ctx, search.ParseQuery(query.Search), &args)
+		if err != nil {
+			return nil, 0, err
+		}
+		columns += ", " + score
+	}
+
+	// Сортировка и пагинация. Страница перед курсором выбирается в
+	// обратном порядке и затем разворачивается.
+	terms := s.sortTerms(query)
+	reverse := query.Cursor != nil && query.Cursor.Backward
+	if query.Cursor != nil {
+		where += " AND " + cursorCondition(terms, query.Cursor, &args)
+	}
+	args = append(args, query.Limit)
+	page := fmt.Sprintf(" ORDER BY %s LIMIT $%d", orderByClause(terms, reverse), len(args))
+	if query.Cursor == nil {
+		args = append(args, query.Offset)
+		page += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	tasks, err := s.queryTasks(ctx, `SELECT `+columns+` FROM tasks`+where+page, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	if reverse {
+		slices.Reverse(tasks)
+	}
+
+	return tasks, total, nil
+}
+
+// taskFilter строит условие WHERE для фильтров query. Используется GetAll
+// и CountTags, чтобы оба запроса отбирали одни и те же задачи.
+func (s *sqlStorage) taskFilter(query models.TaskQuery) (string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+
+	// Фильтрация
+	if query.Deleted {
+		conditions = append(conditions, "deleted_at IS NOT NULL")
+	} else {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+	if query.OwnerID != "" || len(query.ListIDs) > 0 {
+		args = append(args, query.OwnerID)
+		visible := []string{fmt.Sprintf("(list_id = '' AND owner_id = $%d)", len(args))}
+		if len(query.ListIDs) > 0 {
+			visible = append(visible, "list_id IN ("+placeholders(&args, query.ListIDs)+")")
+		}
+		conditions = append(conditions, "("+strings.Join(visible, " OR ")+")")
+	}
+	if query.ListID != "" {
+		args = append(args, query.ListID)
+		conditions = append(conditions, fmt.Sprintf("list_id = $%d", len(args)))
+	}
+	if query.Completed != nil {
+		args = append(args, *query.Completed)
+		conditions = append(conditions, fmt.Sprintf("completed = $%d", len(args)))
+	}
+	if query.DueAfter != nil {
+		args = append(args, query.DueAfter.UTC())
+		conditions = append(conditions, fmt.Sprintf("due_at >= $%d", len(args)))
+	}
+	if query.DueBefore != nil {
+		args = append(args, query.DueBefore.UTC())
+		conditions = append(conditions, fmt.Sprintf("due_at < $%d", len(args)))
+	}
+	if query.Overdue {
+		args = append(args, now())
+		conditions = append(conditions, fmt.Sprintf("completed = FALSE AND due_at < $%d", len(args)))
+	}
+	if len(query.Tags) > 0 {
+		tagged := "SELECT task_id FROM task_tags WHERE tag IN (" + placeholders(&args, query.Tags) + ")"
+		if query.AllTags {
+			// Теги задачи уникальны, поэтому совпадение всех тегов -
+			// это столько же найденных строк, сколько тегов в запросе
+			args = append(args, len(query.Tags))
+			tagged += fmt.Sprintf(" GROUP BY task_id HAVING count(*) = $%d", len(args))
+		}
+		conditions = append(conditions, "id IN ("+tagged+")")
+	}
+	if query.Search != "" {
+		conditions = append(conditions, searchCondition(search.ParseQuery(query.Search), &args))
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// placeholders добавляет values в args и возвращает их плейсхолдеры через запятую
+func placeholders(args *[]any, values []string) string {
+	result := make([]string, len(values))
+	for i, value := range values {
+		*args = append(*args, value)
+		result[i] = fmt.Sprintf("$%d", len(*args))
+	}
+	return strings.Join(result, ", ")
+}
+
+func (s *sqlStorage) Update(ctx context.Context, id string, updatedTask models.Task) (models.Task, error) {
+	var task models.Task
+	err := s.withTx(ctx, func(tx sqlTx) error {
+		var err error
+		task, err = updateTask(tx, id, updatedTask)
+		return err
+	})
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	return task, nil
+}
+
+func updateTask(tx sqlTx, id string, updatedTask models.Task) (models.Task, error) {
+	updatedTask.UpdatedAt = now()
+
+	row := tx.queryRow(
+		`UPDATE tasks SET title = $2, description = $3, completed = $4, priority = $5, parent_id = $6,
+			due_at = $7, remind_at = $8, reminded_at = $9, recurrence = $10, updated_at = $11, version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND $12 IN (0, version) RETURNING `+taskColumns,
+		id, updatedTask.Title, updatedTask.Description, updatedTask.Completed, updatedTask.Priority,
+		nullString(updatedTask.ParentID),
+		utc(updatedTask.DueAt), utc(updatedTask.RemindAt), utc(updatedTask.RemindedAt), updatedTask.Recurrence,
+		updatedTask.UpdatedAt, updatedTask.Version,
+	)
+
+	task, err := scanTask(row)
+	if errors.Is(err, ErrTaskNotFound) {
+		return models.Task{}, missingTask(tx.queryRow, id)
+	}
+	if err != nil {
+		return models.Task{}, err
+	}
+	task.Tags = updatedTask.Tags
+
+	if err := setTaskTerms(tx, task); err != nil {
+		return models.Task{}, err
+	}
+	if err := setTaskTags(tx, id, task.Tags); err != nil {
+		return models.Task{}, err
+	}
+
+	return task, nil
+}
+
+// Delete помечает удалёнными задачу и все её подзадачи не из корзины.
+func (s *sqlStorage) Delete(ctx context.Context, id string, version int64) error {
+	return s.withTx(ctx, func(tx sqlTx) error {
+		return deleteTask(tx, id, version)
+	})
+}
+
+func deleteTask(tx sqlTx, id string, version int64) error {
+	deletedAt := now()
+	result, err := tx.exec(
+		`UPDATE tasks SET deleted_at = $2, version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND $3 IN (0, version)`,
+		id, deletedAt, version,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return missingTask(tx.queryRow, id)
+	}
+
+	_, err = tx.exec(
+		`WITH RECURSIVE subtree (id) AS (
+			SELECT id FROM tasks WHERE parent_id = $1 AND deleted_at IS NULL
+			UNION ALL
+			SELECT tasks.id FROM tasks JOIN subtree ON tasks.parent_id = subtree.id
+			WHERE tasks.deleted_at IS NULL
+		)
+		UPDATE tasks SET deleted_at = $2, version = version + 1
+		WHERE id IN (SELECT id FROM subtree)`,
+		id, deletedAt,
+	)
+	return err
+}
+
+// ApplyBatch применяет все изменения в одной транзакции
+func (s *sqlStorage) ApplyBatch(ctx context.Context, ops []BatchOp) ([]models.Task, error) {
+	results := make([]models.Task, len(ops))
+	err := s.withTx(ctx, func(tx sqlTx) error {
+		for i, op := range ops {
+			var err error
+			switch op.Kind {
+			case BatchCreate:
+				results[i], err = createTask(tx, op.Task)
+			case BatchUpdate:
+				results[i], err = updateTask(tx, op.Task.ID, op.Task)
+			case BatchDelete:
+				err = deleteTask(tx, op.Task.ID, op.Task.Version)
+			default:
+				err = fmt.Errorf("unknown batch operation %q", op.Kind)
+			}
+			if err != nil {
+				return &BatchError{Index: i, Err: err}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (s *sqlStorage) CompleteTask(ctx context.Context, id string, version int64) (models.Task, error) {
+	row := s.queryRow(ctx,
+		`UPDATE tasks SET completed = TRUE, updated_at = $2, version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND $3 IN (0, version) RETURNING `+taskColumns,
+		id, now(), version,
+	)
+	task, err := scanTask(row)
+	if errors.Is(err, ErrTaskNotFound) {
+		return models.Task{}, missingTask(func(query string, args ...any) *sql.Row {
+			return s.queryRow(ctx, query, args...)
+		}, id)
+	}
+	return s.withTags(ctx, task, err)
+}
+
+// missingTask объясняет, почему условное изменение не затронуло задачу:
+// её нет (ErrTaskNotFound) или её версия уже другая (ErrVersionConflict)
+func missingTask(queryRow func(query string, args ...any) *sql.Row, id string) error {
+	var count int
+	if err := queryRow(`SELECT count(*) FROM tasks WHERE id = $1 AND deleted_at IS NULL`, id).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrTaskNotFound
+	}
+	return ErrVersionConflict
+}
+
+func (s *sqlStorage) GetChildren(ctx context.Context, parentID string) ([]models.Task, error) {
+	return s.queryTasks(ctx, `SELECT `+taskColumns+` FROM tasks WHERE parent_id = $1 AND deleted_at IS NULL ORDER BY created_at, id`, parentID)
+}
+
+func (s *sqlStorage) GetDueReminders(ctx context.Context, now time.Time, limit int) ([]models.Task, error) {
+	return s.queryTasks(ctx,
+		`SELECT `+taskColumns+` FROM tasks
+		WHERE completed = FALSE AND deleted_at IS NULL AND reminded_at IS NULL AND remind_at <= $1
+		ORDER BY remind_at, id LIMIT $2`,
+		now.UTC(), limit,
+	)
+}
+
+func (s *sqlStorage) MarkReminded(ctx context.Context, id string, remindAt time.Time) (bool, error) {
+	result, err := s.exec(ctx,
+		`UPDATE tasks SET reminded_at = $3, version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND remind_at = $2 AND reminded_at IS NULL`,
+		id, remindAt.UTC(), now(),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (s *sqlStorage) CreateUser(ctx context.Context, user models.User) (models.User, error) {
+	user.ID = uuid.New().String()
+	user.CreatedAt = now()
+
+	_, err := s.exec(ctx,
+		`INSERT INTO users (`+userColumns+`) VALUES ($1, $2, $3, $4)`,
+		user.ID, user.Username, user.PasswordHash, user.CreatedAt,
+	)
+	if err != nil {
+		// Драйверы по-разному сообщают о нарушении уникальности,
+		// поэтому проверяем, не занято ли имя, уже после ошибки.
+		if _, lookupErr := s.GetUserByUsername(ctx, user.Username); lookupErr == nil {
+			return models.User{}, ErrUserExists
+		}
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+func (s *sqlStorage) GetUserByID(ctx context.Context, id string) (models.User, error) {
+	row := s.queryRow(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id)
+	return scanUser(row)
+}
+
+func (s *sqlStorage) GetUserByUsername(ctx context.Context, username string) (models.User, error) {
+	row := s.queryRow(ctx, `SELECT `+userColumns+` FROM users WHERE username = $1`, username)
+	return scanUser(row)
+}
+
+func scanUser(row rowScanner) (models.User, error) {
+	var user models.User
+	err := row.Scan(&user.ID, &user.Username, &user.PasswordHash, &user.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.User{}, ErrUserNotFound
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+func (s *sqlStorage) CreateRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	_, err := s.exec(ctx,
+		`INSERT INTO refresh_tokens (`+refreshTokenColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		token.TokenHash, token.UserID, token.FamilyID, token.ExpiresAt.UTC(), token.CreatedAt.UTC(),
+		token.UsedAt, token.RevokedAt,
+	)
+	return err
+}
+
+func (s *sqlStorage) GetRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := s.queryRow(ctx,
+		`SELECT `+refreshTokenColumns+` FROM refresh_tokens WHERE token_hash = $1`, tokenHash,
+	).Scan(
+		&token.TokenHash,
+		&token.UserID,
+		&token.FamilyID,
+		&token.ExpiresAt,
+		&token.CreatedAt,
+		&token.UsedAt,
+		&token.RevokedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.RefreshToken{}, ErrTokenNotFound
+	}
+	if err != nil {
+		return models.RefreshToken{}, err
+	}
+
+	return token, nil
+}
+
+func (s *sqlStorage) UseRefreshToken(ctx context.Context, tokenHash string) error {
+	result, err := s.exec(ctx,
+		`UPDATE refresh_tokens SET used_at = $2 WHERE token_hash = $1 AND used_at IS NULL`,
+		tokenHash, now(),
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		if _, err := s.GetRefreshToken(ctx, tokenHash); err != nil {
+			return err
+		}
+		return ErrTokenUsed
+	}
+
+	return nil
+}
+
+func (s *sqlStorage) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	_, err := s.exec(ctx,
+		`UPDATE refresh_tokens SET revoked_at = $2 WHERE family_id = $1 AND revoked_at IS NULL`,
+		familyID, now(),
+	)
+	return err
+}
+
+func (s *sqlStorage) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	// Заодно удаляем записи, срок действия которых уже истёк
+	if _, err := s.exec(ctx, `DELETE FROM revoked_access_tokens WHERE expires_at < $1`, now()); err != nil {
+		return err
+	}
+
+	_, err := s.exec(ctx,
+		`INSERT INTO revoked_access_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING`,
+		jti, expiresAt.UTC(),
+	)
+	return err
+}
+
+func (s *sqlStorage) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var count int
+	err := s.queryRow(ctx, `SELECT count(*) FROM revoked_access_tokens WHERE jti = $1`, jti).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// now возвращает текущее время в UTC: так метки времени одинаково
+// сравниваются и сортируются независимо от часового пояса сервера.
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+// utc приводит необязательную метку времени к UTC перед записью
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// queryTasks выполняет запрос, возвращающий столбцы taskColumns и,
+// при поиске, последним столбцом score
+func (s *sqlStorage) queryTasks(ctx context.Context, query string, args ...any) ([]models.Task, error) {
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	scored := columns[len(columns)-1] == "score"
+
+	var tasks []models.Task
+	for rows.Next() {
+		var (
+			task models.Task
+			err  error
+		)
+		if scored {
+			var score float64
+			task, err = scanTask(rows, &score)
+			task.Score = score
+		} else {
+			task, err = scanTask(rows)
+		}
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if err := s.loadTags(ctx, tasks); err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// nullString записывает пустую строку как NULL - для столбцов с внешним ключом
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanTask читает столбцы taskColumns и следующие за ними столбцы в extra
+func scanTask(row rowScanner, extra ...any) (models.Task, error) {
+	var (
+		task     models.Task
+		parentID sql.NullString
+	)
+	dest := []any{
+		&task.ID,
+		&task.Title,
+		&task.Description,
+		&task.Completed,
+		&task.Priority,
+		&task.OwnerID,
+		&task.ListID,
+		&parentID,
+		&task.DueAt,
+		&task.RemindAt,
+		&task.RemindedAt,
+		&task.Recurrence,
+		&task.CreatedAt,
+		&task.UpdatedAt,
+		&task.DeletedAt,
+		&task.Version,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Task{}, ErrTaskNotFound
+	}
+	if err != nil {
+		return models.Task{}, err
+	}
+	task.ParentID = parentID.String
+
+	return task, nil
+}
+
+// sortTerm - выражение ORDER BY и значение этого выражения для задачи курсора
+type sortTerm struct {
+	expr  string
+	desc  bool
+	value func(task models.Task) any
+}
+
+// sortTerms переводит ключи сортировки в выражения ORDER BY по правилам
+// MemoryStorage.compareTasks. Идентификатор добавляется последним
+// выражением, чтобы порядок страниц был стабильным.
+func (s *sqlStorage) sortTerms(query models.TaskQuery) []sortTerm {
+	var terms []sortTerm
+	for _, key := range query.SortKeys() {
+		switch key.Field {
+		case models.SortDueAt:
+			// Задачи без срока идут последними при любом порядке
+			terms = append(terms,
+				sortTerm{"(due_at IS NULL)", false, func(task models.Task) any { return task.DueAt == nil }},
+				sortTerm{"due_at", key.Desc, func(task models.Task) any { return utc(task.DueAt) }},
+			)
+		case models.SortTitle:
+			terms = append(terms, sortTerm{s.dialect.sortText("title"), key.Desc, func(task models.Task) any {
+				return strings.ToLower(task.Title)
+			}})
+		case models.SortCreatedAt:
+			terms = append(terms, sortTerm{"created_at", key.Desc, func(task models.Task) any { return task.CreatedAt.UTC() }})
+		case models.SortUpdatedAt:
+			terms = append(terms, sortTerm{"updated_at", key.Desc, func(task models.Task) any { return task.UpdatedAt.UTC() }})
+		case models.SortCompleted:
+			terms = append(terms, sortTerm{"completed", key.Desc, func(task models.Task) any { return task.Completed }})
+		case models.SortPriority:
+			terms = append(terms, sortTerm{"priority", key.Desc, func(task models.Task) any { return task.Priority }})
+		case models.SortRelevance:
+			// Столбец score есть только при поиске; курсоры с ним не используются
+			if query.Search != "" {
+				terms = append(terms, sortTerm{"score", key.Desc, func(task models.Task) any { return task.Score }})
+			}
+		}
+	}
+
+	return append(terms, sortTerm{"id", false, func(task models.Task) any { return task.ID }})
+}
+
+// orderByClause строит ORDER BY из sortTerms. reverse меняет направление
+// всех выражений - для выборки страницы перед курсором.
+func orderByClause(terms []sortTerm, reverse bool) string {
+	clause := make([]string, len(terms))
+	for i, term := range terms {
+		direction := " ASC"
+		if term.desc != reverse {
+			direction = " DESC"
+		}
+		clause[i] = term.expr + direction
+	}
+
+	return strings.Join(clause, ", ")
+}
+
+// cursorCondition возвращает условие "задача идёт после курсора" (или
+// перед ним, если cursor.Backward) в порядке terms:
+// (t1 > v1) OR (t1 = v1 AND t2 > v2) OR ...
+func cursorCondition(terms []sortTerm, cursor *models.Cursor, args *[]any) string {
+	var (
+		alternatives []string
+		equal        []string
+	)
+	for _, term := range terms {
+		value := term.value(cursor.Task)
+		// NULL в due_at бывает только вместе с (due_at IS NULL) = TRUE в
+		// предыдущем выражении, которое уже задаёт равенство
+		if t, ok := value.(*time.Time); ok {
+			if t == nil {
+				continue
+			}
+			value = *t
+		}
+
+		*args = append(*args, value)
+		param := fmt.Sprintf("$%d", len(*args))
+
+		op := " > "
+		if term.desc != cursor.Backward {
+			op = " < "
+		}
+		alternatives = append(alternatives, "("+strings.Join(append(equal, term.expr+op+param), " AND ")+")")
+		equal = append(equal, term.expr+" = "+param)
+	}
+
+	return "(" + strings.Join(alternatives, " OR ") + ")"
+}
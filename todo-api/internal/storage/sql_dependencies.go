@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+
+	"todo-api/internal/models"
+)
+
+func (s *sqlStorage) AddDependency(ctx context.Context, taskID, blockerID string) error {
+	for _, id := range []string{taskID, blockerID} {
+		if _, err := s.GetByID(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	_, err := s.exec(ctx,
+		`INSERT INTO task_dependencies (task_id, blocker_id) VALUES ($1, $2)
+		ON CONFLICT (task_id, blocker_id) DO NOTHING`,
+		taskID, blockerID,
+	)
+	return err
+}
+
+func (s *sqlStorage) RemoveDependency(ctx context.Context, taskID, blockerID string) error {
+	result, err := s.exec(ctx, `DELETE FROM task_dependencies WHERE task_id = $1 AND blocker_id = $2`, taskID, blockerID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrDependencyNotFound
+	}
+
+	return nil
+}
+
+func (s *sqlStorage) GetBlockers(ctx context.Context, taskID string) ([]models.Task, error) {
+	return s.queryTasks(ctx,
+		`SELECT `+taskColumns+` FROM tasks
+		WHERE id IN (SELECT blocker_id FROM task_dependencies WHERE task_id = $1) AND deleted_at IS NULL
+		ORDER BY created_at, id`,
+		taskID,
+	)
+}
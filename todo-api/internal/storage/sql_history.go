@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+
+	"todo-api/internal/models"
+)
+
+const historyColumns = `id, task_id, actor_id, action, changes, created_at`
+
+func (s *sqlStorage) AddHistory(ctx context.Context, entry models.HistoryEntry) (models.HistoryEntry, error) {
+	entry.ID = uuid.New().String()
+	entry.CreatedAt = now()
+
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return models.HistoryEntry{}, err
+	}
+
+	_, err = s.exec(ctx,
+		`INSERT INTO task_history (`+historyColumns+`) VALUES ($1, $2, $3, $4, $5, $6)`,
+		entry.ID, entry.TaskID, entry.ActorID, entry.Action, string(changes), entry.CreatedAt,
+	)
+	if err != nil {
+		// Внешний ключ не даёт добавить запись к несуществующей задаче
+		var count int
+		if lookupErr := s.queryRow(ctx, `SELECT count(*) FROM tasks WHERE id = $1`, entry.TaskID).Scan(&count); lookupErr == nil && count == 0 {
+			return models.HistoryEntry{}, ErrTaskNotFound
+		}
+		return models.HistoryEntry{}, err
+	}
+
+	return entry, nil
+}
+
+func (s *sqlStorage) GetHistory(ctx context.Context, taskID string, limit, offset int) ([]models.HistoryEntry, int, error) {
+	var total int
+	if err := s.queryRow(ctx, `SELECT count(*) FROM task_history WHERE task_id = $1`, taskID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.query(ctx,
+		`SELECT `+historyColumns+` FROM task_history WHERE task_id = $1
+		ORDER BY seq DESC LIMIT $2 OFFSET $3`,
+		taskID, limit, offset,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []models.HistoryEntry{}
+	for rows.Next() {
+		var (
+			entry   models.HistoryEntry
+			changes string
+		)
+		if err := rows.Scan(&entry.ID, &entry.TaskID, &entry.ActorID, &entry.Action, &changes, &entry.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		if err := json.Unmarshal([]byte(changes), &entry.Changes); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, total, rows.Err()
+}
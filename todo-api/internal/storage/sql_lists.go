@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"todo-api/internal/models"
+)
+
+const (
+	listColumns   = `id, name, description, owner_id, created_at, updated_at`
+	memberColumns = `list_id, user_id, role, created_at`
+)
+
+func (s *sqlStorage) CreateList(ctx context.Context, list models.List) (models.List, error) {
+	list.ID = uuid.New().String()
+	list.Role = ""
+	list.CreatedAt = now()
+	list.UpdatedAt = list.CreatedAt
+
+	err := s.withTx(ctx, func(tx sqlTx) error {
+		_, err := tx.exec(
+			`INSERT INTO lists (`+listColumns+`) VALUES ($1, $2, $3, $4, $5, $6)`,
+			list.ID, list.Name, list.Description, list.OwnerID, list.CreatedAt, list.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.exec(
+			`INSERT INTO list_members (`+memberColumns+`) VALUES ($1, $2, $3, $4)`,
+			list.ID, list.OwnerID, models.RoleOwner, list.CreatedAt,
+		)
+		return err
+	})
+	if err != nil {
+		return models.List{}, err
+	}
+
+	return list, nil
+}
+
+func (s *sqlStorage) GetList(ctx context.Context, id string) (models.List, error) {
+	row := s.queryRow(ctx, `SELECT `+listColumns+` FROM lists WHERE id = $1`, id)
+	return scanList(row)
+}
+
+func (s *sqlStorage) GetListsByMember(ctx context.Context, userID string) ([]models.List, error) {
+	rows, err := s.query(ctx,
+		`SELECT l.id, l.name, l.description, l.owner_id, l.created_at, l.updated_at
+		FROM lists l JOIN list_members m ON m.list_id = l.id
+		WHERE m.user_id = $1
+		ORDER BY l.created_at, l.id`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lists []models.List
+	for rows.Next() {
+		list, err := scanList(rows)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+
+	return lists, rows.Err()
+}
+
+func (s *sqlStorage) UpdateList(ctx context.Context, id string, updatedList models.List) (models.List, error) {
+	row := s.queryRow(ctx,
+		`UPDATE lists SET name = $2, description = $3, updated_at = $4
+		WHERE id = $1 RETURNING `+listColumns,
+		id, updatedList.Name, updatedList.Description, now(),
+	)
+	return scanList(row)
+}
+
+func (s *sqlStorage) DeleteList(ctx context.Context, id string) error {
+	return s.withTx(ctx, func(tx sqlTx) error {
+		result, err := tx.exec(`DELETE FROM lists WHERE id = $1`, id)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrListNotFound
+		}
+
+		// Участники удаляются каскадно, задачи ссылаются на список без внешнего ключа
+		_, err = tx.exec(`DELETE FROM tasks WHERE list_id = $1`, id)
+		return err
+	})
+}
+
+func (s *sqlStorage) GetMember(ctx context.Context, listID, userID string) (models.ListMember, error) {
+	row := s.queryRow(ctx,
+		`SELECT `+memberColumns+` FROM list_members WHERE list_id = $1 AND user_id = $2`,
+		listID, userID,
+	)
+	return scanMember(row)
+}
+
+func (s *sqlStorage) GetMembers(ctx context.Context, listID string) ([]models.ListMember, error) {
+	if _, err := s.GetList(ctx, listID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.query(ctx,
+		`SELECT `+memberColumns+` FROM list_members WHERE list_id = $1 ORDER BY created_at, user_id`,
+		listID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []models.ListMember
+	for rows.Next() {
+		member, err := scanMember(rows)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+func (s *sqlStorage) PutMember(ctx context.Context, member models.ListMember) (models.ListMember, error) {
+	member.Username = ""
+
+	err := s.withTx(ctx, func(tx sqlTx) error {
+		var exists int
+		err := tx.queryRow(`SELECT count(*) FROM lists WHERE id = $1`, member.ListID).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists == 0 {
+			return ErrListNotFound
+		}
+
+		existing, err := scanMember(tx.queryRow(
+			`SELECT `+memberColumns+` FROM list_members WHERE list_id = $1 AND user_id = $2`,
+			member.ListID, member.UserID,
+		))
+		switch {
+		case err == nil:
+			member.CreatedAt = existing.CreatedAt
+			_, err = tx.exec(
+				`UPDATE list_members SET role = $3 WHERE list_id = $1 AND user_id = $2`,
+				member.ListID, member.UserID, member.Role,
+			)
+			return err
+		case errors.Is(err, ErrMemberNotFound):
+			member.CreatedAt = now()
+			_, err = tx.exec(
+				`INSERT INTO list_members (`+memberColumns+`) VALUES ($1, $2, $3, $4)`,
+				member.ListID, member.UserID, member.Role, member.CreatedAt,
+			)
+			return err
+		default:
+			return err
+		}
+	})
+	if err != nil {
+		return models.ListMember{}, err
+	}
+
+	return member, nil
+}
+
+func (s *sqlStorage) RemoveMember(ctx context.Context, listID, userID string) error {
+	result, err := s.exec(ctx, `DELETE FROM list_members WHERE list_id = $1 AND user_id = $2`, listID, userID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrMemberNotFound
+	}
+
+	return nil
+}
+
+func scanList(row rowScanner) (models.List, error) {
+	var list models.List
+	err := row.Scan(&list.ID, &list.Name, &list.Description, &list.OwnerID, &list.CreatedAt, &list.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.List{}, ErrListNotFound
+	}
+	if err != nil {
+		return models.List{}, err
+	}
+
+	return list, nil
+}
+
+func scanMember(row rowScanner) (models.ListMember, error) {
+	var member models.ListMember
+	err := row.Scan(&member.ListID, &member.UserID, &member.Role, &member.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ListMember{}, ErrMemberNotFound
+	}
+	if err != nil {
+		return models.ListMember{}, err
+	}
+
+	return member, nil
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"todo-api/internal/models"
+	"todo-api/internal/search"
+)
+
+// Полнотекстовый индекс SQL-хранилищ лежит в таблице task_terms: по строке
+// на каждое слово заголовка и описания задачи. Слова приводятся к основе тем
+// же анализатором, что и в MemoryStorage, а релевантность считается по тем же
+// формулам, поэтому результаты поиска совпадают.
+
+// setTaskTerms заменяет слова задачи в индексе
+func setTaskTerms(tx sqlTx, task models.Task) error {
+	if _, err := tx.exec(`DELETE FROM task_terms WHERE task_id = $1`, task.ID); err != nil {
+		return err
+	}
+
+	for _, token := range search.Analyze(task.Title, task.Description) {
+		_, err := tx.exec(
+			`INSERT INTO task_terms (task_id, position, term, weight) VALUES ($1, $2, $3, $4)`,
+			task.ID, token.Position, token.Term, token.Weight,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// indexTasks добавляет в индекс задачи, у которых ещё нет ни одного слова,
+// например созданные до появления индекса
+func (s *sqlStorage) indexTasks(ctx context.Context) error {
+	rows, err := s.query(ctx,
+		`SELECT id, title, description FROM tasks
+		WHERE NOT EXISTS (SELECT 1 FROM task_terms WHERE task_terms.task_id = tasks.id)`,
+	)
+	if err != nil {
+		return err
+	}
+
+	var tasks []models.Task
+	for rows.Next() {
+		var task models.Task
+		if err := rows.Scan(&task.ID, &task.Title, &task.Description); err != nil {
+			rows.Close()
+			return err
+		}
+		tasks = append(tasks, task)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return s.withTx(ctx, func(tx sqlTx) error {
+		for _, task := range tasks {
+			if err := setTaskTerms(tx, task); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// searchCondition возвращает условие "задача подходит под запрос": каждое
+// слово есть в индексе задачи, а слова каждой фразы стоят подряд
+func searchCondition(query search.Query, args *[]any) string {
+	if query.Empty() {
+		return "1 = 0"
+	}
+
+	var conditions []string
+	for _, term := range query.Terms {
+		*args = append(*args, term)
+		conditions = append(conditions, fmt.Sprintf("id IN (SELECT task_id FROM task_terms WHERE term = $%d)", len(*args)))
+	}
+
+	for _, phrase := range query.Phrases {
+		var (
+			joins []string
+			where []string
+		)
+		for i, term := range phrase {
+			*args = append(*args, term)
+			where = append(where, fmt.Sprintf("p%d.term = $%d", i, len(*args)))
+			if i > 0 {
+				joins = append(joins, fmt.Sprintf(
+					"JOIN task_terms p%d ON p%d.task_id = p0.task_id AND p%d.position = p0.position + %d", i, i, i, i,
+				))
+			}
+		}
+		conditions = append(conditions, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM task_terms p0 %s WHERE p0.task_id = tasks.id AND %s)",
+			strings.Join(joins, " "), strings.Join(where, " AND "),
+		))
+	}
+
+	return strings.Join(conditions, " AND ")
+}
+
+// scoreColumn возвращает столбец score с релевантностью задачи по правилам
+// search.Score. IDF слов считается заранее и передаётся параметрами.
+func (s *sqlStorage) scoreColumn(ctx context.Context, query search.Query, args *[]any) (string, error) {
+	terms := query.AllTerms()
+	if len(terms) == 0 {
+		return "0 AS score", nil
+	}
+
+	var total int
+	if err := s.queryRow(ctx, `SELECT count(*) FROM tasks`).Scan(&total); err != nil {
+		return "", err
+	}
+
+	var dfArgs []any
+	rows, err := s.query(ctx,
+		`SELECT term, count(DISTINCT task_id) FROM task_terms WHERE term IN (`+placeholders(&dfArgs, terms)+`) GROUP BY term`,
+		dfArgs...,
+	)
+	if err != nil {
+		return "", err
+	}
+	df := make(map[string]int, len(terms))
+	for rows.Next() {
+		var (
+			term  string
+			count int
+		)
+		if err := rows.Scan(&term, &count); err != nil {
+			rows.Close()
+			return "", err
+		}
+		df[term] = count
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	var cases []string
+	for _, term := range terms {
+		*args = append(*args, term, search.IDF(df[term], total))
+		cases = append(cases, fmt.Sprintf("WHEN $%d THEN CAST($%d AS DOUBLE PRECISION)", len(*args)-1, len(*args)))
+	}
+	scale := fmt.Sprintf("1e%d", search.ScorePrecision)
+
+	return fmt.Sprintf(
+		`(SELECT round(COALESCE(sum(w.weight * CASE w.term %s ELSE 0 END), 0) * %s) / %s
+		FROM task_terms w WHERE w.task_id = tasks.id AND w.term IN (%s)) AS score`,
+		strings.Join(cases, " "), scale, scale, placeholders(args, terms),
+	), nil
+}
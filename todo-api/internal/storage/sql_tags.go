@@ -0,0 +1,258 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"todo-api/internal/models"
+)
+
+const tagColumns = `owner_id, name, color, created_at`
+
+func (s *sqlStorage) CreateTag(ctx context.Context, tag models.Tag) (models.Tag, error) {
+	tag.CreatedAt = now()
+
+	result, err := s.exec(ctx,
+		`INSERT INTO tags (`+tagColumns+`) VALUES ($1, $2, $3, $4) ON CONFLICT (owner_id, name) DO NOTHING`,
+		tag.OwnerID, tag.Name, tag.Color, tag.CreatedAt,
+	)
+	if err != nil {
+		return models.Tag{}, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return models.Tag{}, err
+	}
+	if affected == 0 {
+		return models.Tag{}, ErrTagExists
+	}
+
+	return tag, nil
+}
+
+func (s *sqlStorage) GetTag(ctx context.Context, ownerID, name string) (models.Tag, error) {
+	row := s.queryRow(ctx, `SELECT `+tagColumns+` FROM tags WHERE owner_id = $1 AND name = $2`, ownerID, name)
+	return scanTag(row)
+}
+
+func (s *sqlStorage) GetTags(ctx context.Context, ownerID string) ([]models.Tag, error) {
+	rows, err := s.query(ctx, `SELECT `+tagColumns+` FROM tags WHERE owner_id = $1 ORDER BY name`, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []models.Tag
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+func (s *sqlStorage) UpdateTag(ctx context.Context, ownerID, name string, updatedTag models.Tag) (models.Tag, error) {
+	var tag models.Tag
+	err := s.withTx(ctx, func(tx sqlTx) error {
+		existing, err := scanTag(tx.queryRow(
+			`SELECT `+tagColumns+` FROM tags WHERE owner_id = $1 AND name = $2`, ownerID, name,
+		))
+		if err != nil {
+			return err
+		}
+
+		if updatedTag.Name != name {
+			var count int
+			err := tx.queryRow(
+				`SELECT count(*) FROM tags WHERE owner_id = $1 AND name = $2`, ownerID, updatedTag.Name,
+			).Scan(&count)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return ErrTagExists
+			}
+		}
+
+		tag = models.Tag{
+			Name:      updatedTag.Name,
+			Color:     updatedTag.Color,
+			OwnerID:   ownerID,
+			CreatedAt: existing.CreatedAt,
+		}
+		_, err = tx.exec(
+			`UPDATE tags SET name = $3, color = $4 WHERE owner_id = $1 AND name = $2`,
+			ownerID, name, tag.Name, tag.Color,
+		)
+		if err != nil || tag.Name == name {
+			return err
+		}
+
+		// Переименовываем тег в задачах владельца. Если у задачи уже есть
+		// тег с новым именем, старый просто удаляется.
+		_, err = tx.exec(
+			`INSERT INTO task_tags (task_id, tag)
+			SELECT t.task_id, $3 FROM task_tags t JOIN tasks ON tasks.id = t.task_id
+			WHERE tasks.owner_id = $1 AND t.tag = $2
+			ON CONFLICT (task_id, tag) DO NOTHING`,
+			ownerID, name, tag.Name,
+		)
+		if err != nil {
+			return err
+		}
+
+		return deleteOwnerTaskTag(tx, ownerID, name)
+	})
+	if err != nil {
+		return models.Tag{}, err
+	}
+
+	return tag, nil
+}
+
+func (s *sqlStorage) DeleteTag(ctx context.Context, ownerID, name string) error {
+	return s.withTx(ctx, func(tx sqlTx) error {
+		result, err := tx.exec(`DELETE FROM tags WHERE owner_id = $1 AND name = $2`, ownerID, name)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrTagNotFound
+		}
+
+		return deleteOwnerTaskTag(tx, ownerID, name)
+	})
+}
+
+func (s *sqlStorage) CountTags(ctx context.Context, query models.TaskQuery) ([]models.TagCount, error) {
+	where, args := s.taskFilter(query)
+
+	rows, err := s.query(ctx,
+		`SELECT tag, count(*) FROM task_tags
+		WHERE task_id IN (SELECT id FROM tasks`+where+`)
+		GROUP BY tag ORDER BY count(*) DESC, tag`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []models.TagCount{}
+	for rows.Next() {
+		var count models.TagCount
+		if err := rows.Scan(&count.Name, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
+
+// deleteOwnerTaskTag удаляет тег name из всех задач владельца, увеличивая их версии
+func deleteOwnerTaskTag(tx sqlTx, ownerID, name string) error {
+	_, err := tx.exec(
+		`UPDATE tasks SET version = version + 1
+		WHERE owner_id = $1 AND id IN (SELECT task_id FROM task_tags WHERE tag = $2)`,
+		ownerID, name,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.exec(
+		`DELETE FROM task_tags
+		WHERE tag = $2 AND task_id IN (SELECT id FROM tasks WHERE owner_id = $1)`,
+		ownerID, name,
+	)
+	return err
+}
+
+// setTaskTags заменяет теги задачи
+func setTaskTags(tx sqlTx, taskID string, tags []string) error {
+	if _, err := tx.exec(`DELETE FROM task_tags WHERE task_id = $1`, taskID); err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		if _, err := tx.exec(`INSERT INTO task_tags (task_id, tag) VALUES ($1, $2)`, taskID, tag); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// withTags дополняет результат scanTask тегами задачи
+func (s *sqlStorage) withTags(ctx context.Context, task models.Task, err error) (models.Task, error) {
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	tasks := []models.Task{task}
+	if err := s.loadTags(ctx, tasks); err != nil {
+		return models.Task{}, err
+	}
+
+	return tasks[0], nil
+}
+
+// loadTags заполняет Tags у задач одним запросом
+func (s *sqlStorage) loadTags(ctx context.Context, tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	var args []any
+	ids := make([]string, len(tasks))
+	index := make(map[string]int, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+		index[task.ID] = i
+	}
+
+	rows, err := s.query(ctx,
+		`SELECT task_id, tag FROM task_tags WHERE task_id IN (`+placeholders(&args, ids)+`) ORDER BY task_id, tag`,
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID, tag string
+		if err := rows.Scan(&taskID, &tag); err != nil {
+			return err
+		}
+		if i, ok := index[taskID]; ok {
+			tasks[i].Tags = append(tasks[i].Tags, tag)
+		}
+	}
+
+	return rows.Err()
+}
+
+func scanTag(row rowScanner) (models.Tag, error) {
+	var tag models.Tag
+	err := row.Scan(&tag.OwnerID, &tag.Name, &tag.Color, &tag.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Tag{}, ErrTagNotFound
+	}
+	if err != nil {
+		return models.Tag{}, err
+	}
+
+	return tag, nil
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"todo-api/internal/models"
+)
+
+func (s *sqlStorage) GetDeleted(ctx context.Context, id string) (models.Task, error) {
+	row := s.queryRow(ctx, `SELECT `+taskColumns+` FROM tasks WHERE id = $1 AND deleted_at IS NOT NULL`, id)
+	task, err := scanTask(row)
+	return s.withTags(ctx, task, err)
+}
+
+// Restore снимает отметку об удалении с задачи и с тех подзадач, у которых
+// deleted_at совпадает с её собственным.
+func (s *sqlStorage) Restore(ctx context.Context, id string) (models.Task, error) {
+	err := s.withTx(ctx, func(tx sqlTx) error {
+		result, err := tx.exec(
+			`WITH RECURSIVE subtree (id, deleted_at) AS (
+				SELECT id, deleted_at FROM tasks WHERE id = $1 AND deleted_at IS NOT NULL
+				UNION ALL
+				SELECT tasks.id, tasks.deleted_at FROM tasks JOIN subtree ON tasks.parent_id = subtree.id
+				WHERE tasks.deleted_at = subtree.deleted_at
+			)
+			UPDATE tasks SET deleted_at = NULL, version = version + 1
+			WHERE id IN (SELECT id FROM subtree)`,
+			id,
+		)
+		if err != nil {
+			return err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrTaskNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	return s.GetByID(ctx, id)
+}
+
+// Purge удаляет задачи; подзадачи и зависимости удаляются каскадно внешними ключами.
+func (s *sqlStorage) Purge(ctx context.Context, before time.Time) (int, error) {
+	result, err := s.exec(ctx, `DELETE FROM tasks WHERE deleted_at < $1`, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
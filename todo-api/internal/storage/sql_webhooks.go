@@ -0,0 +1,295 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"todo-api/internal/models"
+)
+
+const (
+	webhookColumns  = `id, owner_id, url, events, secret, active, created_at, updated_at`
+	deliveryColumns = `id, webhook_id, event_id, event, task_id, payload, status, attempts,
+		last_status_code, last_error, next_attempt_at, created_at, updated_at`
+)
+
+func (s *sqlStorage) CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	webhook.ID = uuid.New().String()
+	webhook.CreatedAt = now()
+	webhook.UpdatedAt = webhook.CreatedAt
+
+	events, err := json.Marshal(webhook.Events)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	_, err = s.exec(ctx,
+		`INSERT INTO webhooks (`+webhookColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		webhook.ID, webhook.OwnerID, webhook.URL, string(events), webhook.Secret, webhook.Active, webhook.CreatedAt, webhook.UpdatedAt,
+	)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	return webhook, nil
+}
+
+func (s *sqlStorage) GetWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	return scanWebhook(s.queryRow(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id = $1`, id))
+}
+
+func (s *sqlStorage) GetWebhooks(ctx context.Context, ownerID string) ([]models.Webhook, error) {
+	rows, err := s.query(ctx,
+		`SELECT `+webhookColumns+` FROM webhooks WHERE owner_id = $1 ORDER BY created_at, id`,
+		ownerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []models.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, rows.Err()
+}
+
+func (s *sqlStorage) UpdateWebhook(ctx context.Context, id string, webhook models.Webhook) (models.Webhook, error) {
+	events, err := json.Marshal(webhook.Events)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	row := s.queryRow(ctx,
+		`UPDATE webhooks SET url = $2, events = $3, active = $4, updated_at = $5
+		WHERE id = $1 RETURNING `+webhookColumns,
+		id, webhook.URL, string(events), webhook.Active, now(),
+	)
+	return scanWebhook(row)
+}
+
+func (s *sqlStorage) DeleteWebhook(ctx context.Context, id string) error {
+	// Доставки удаляются каскадно
+	result, err := s.exec(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (s *sqlStorage) AddDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	createdAt := now()
+	err := s.withTx(ctx, func(tx sqlTx) error {
+		for i := range deliveries {
+			deliveries[i].ID = uuid.New().String()
+			deliveries[i].CreatedAt = createdAt
+			deliveries[i].UpdatedAt = createdAt
+
+			d := deliveries[i]
+			_, err := tx.exec(
+				`INSERT INTO webhook_deliveries (`+deliveryColumns+`)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+				d.ID, d.WebhookID, d.EventID, d.Event, d.TaskID, string(d.Payload), d.Status, d.Attempts,
+				d.LastStatusCode, d.LastError, utc(d.NextAttemptAt), d.CreatedAt, d.UpdatedAt,
+			)
+			if err != nil {
+				// Внешний ключ не дает добавить доставку удаленному вебхуку
+				var count int
+				if lookupErr := tx.queryRow(`SELECT count(*) FROM webhooks WHERE id = $1`, d.WebhookID).Scan(&count); lookupErr == nil && count == 0 {
+					return ErrWebhookNotFound
+				}
+				return err
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *sqlStorage) GetDelivery(ctx context.Context, id string) (models.WebhookDelivery, error) {
+	return scanDelivery(s.queryRow(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = $1`, id))
+}
+
+func (s *sqlStorage) GetDeliveries(ctx context.Context, query models.DeliveryQuery) ([]models.WebhookDelivery, int, error) {
+	var (
+		conditions []string
+		args       []any
+	)
+	if query.WebhookID != "" {
+		args = append(args, query.WebhookID)
+		conditions = append(conditions, fmt.Sprintf("webhook_id = $%d", len(args)))
+	}
+	if query.OwnerID != "" {
+		args = append(args, query.OwnerID)
+		conditions = append(conditions, fmt.Sprintf("webhook_id IN (SELECT id FROM webhooks WHERE owner_id = $%d)", len(args)))
+	}
+	if query.Status != "" {
+		args = append(args, query.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := s.queryRow(ctx, `SELECT count(*) FROM webhook_deliveries`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, query.Limit, max(query.Offset, 0))
+	rows, err := s.query(ctx,
+		`SELECT `+deliveryColumns+` FROM webhook_deliveries`+where+
+			fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args)),
+		args...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, total, rows.Err()
+}
+
+func (s *sqlStorage) ClaimDeliveries(ctx context.Context, at time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := s.query(ctx,
+		`SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE status = $1 AND next_attempt_at <= $2
+		ORDER BY next_attempt_at, id LIMIT $3`,
+		models.DeliveryPending, at.UTC(), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var due []models.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		due = append(due, delivery)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Доставку забирает тот, чей UPDATE увидел прежний next_attempt_at:
+	// так два экземпляра сервера не отправят ее одновременно
+	leaseUntil := at.Add(lease).UTC()
+	var claimed []models.WebhookDelivery
+	for _, delivery := range due {
+		result, err := s.exec(ctx,
+			`UPDATE webhook_deliveries SET next_attempt_at = $3
+			WHERE id = $1 AND status = 'pending' AND next_attempt_at = $2`,
+			delivery.ID, utc(delivery.NextAttemptAt), leaseUntil,
+		)
+		if err != nil {
+			return nil, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if affected > 0 {
+			delivery.NextAttemptAt = &leaseUntil
+			claimed = append(claimed, delivery)
+		}
+	}
+
+	return claimed, nil
+}
+
+func (s *sqlStorage) UpdateDelivery(ctx context.Context, d models.WebhookDelivery) error {
+	result, err := s.exec(ctx,
+		`UPDATE webhook_deliveries SET status = $2, attempts = $3, last_status_code = $4,
+		last_error = $5, next_attempt_at = $6, updated_at = $7
+		WHERE id = $1`,
+		d.ID, d.Status, d.Attempts, d.LastStatusCode, d.LastError, utc(d.NextAttemptAt), now(),
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrDeliveryNotFound
+	}
+
+	return nil
+}
+
+func scanWebhook(row rowScanner) (models.Webhook, error) {
+	var (
+		webhook models.Webhook
+		events  string
+	)
+	err := row.Scan(&webhook.ID, &webhook.OwnerID, &webhook.URL, &events, &webhook.Secret, &webhook.Active, &webhook.CreatedAt, &webhook.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Webhook{}, ErrWebhookNotFound
+	}
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	if err := json.Unmarshal([]byte(events), &webhook.Events); err != nil {
+		return models.Webhook{}, err
+	}
+
+	return webhook, nil
+}
+
+func scanDelivery(row rowScanner) (models.WebhookDelivery, error) {
+	var (
+		d       models.WebhookDelivery
+		payload string
+	)
+	err := row.Scan(
+		&d.ID, &d.WebhookID, &d.EventID, &d.Event, &d.TaskID, &payload, &d.Status, &d.Attempts,
+		&d.LastStatusCode, &d.LastError, &d.NextAttemptAt, &d.CreatedAt, &d.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.WebhookDelivery{}, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	d.Payload = json.RawMessage(payload)
+
+	return d, nil
+}
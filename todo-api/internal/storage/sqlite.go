@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"embed"
+	"fmt"
+	"strings"
+
+	"modernc.org/sqlite"
+)
+
+//go:embed migrations/sqlite/*.sql
+var sqliteMigrations embed.FS
+
+func init() {
+	// Встроенная lower() в SQLite меняет регистр только у ASCII, поэтому
+	// сортировка по заголовку на кириллице работала бы иначе, чем в MemoryStorage.
+	sqlite.MustRegisterDeterministicScalarFunction("unicode_lower", 1,
+		func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			switch value := args[0].(type) {
+			case string:
+				return strings.ToLower(value), nil
+			case []byte:
+				return strings.ToLower(string(value)), nil
+			default:
+				return value, nil
+			}
+		},
+	)
+}
+
+var sqliteDialect = sqlDialect{
+	rebind: func(query string) string {
+		// SQLite понимает нумерованные параметры вида ?1
+		return strings.ReplaceAll(query, "$", "?")
+	},
+	sortText: func(column string) string {
+		// Встроенная сортировка BINARY сравнивает UTF-8 побайтно,
+		// что совпадает с порядком кодовых точек
+		return fmt.Sprintf("unicode_lower(%s)", column)
+	},
+	// IMMEDIATE сразу берет блокировку записи, поэтому другой процесс ждет
+	// ее снятия (busy_timeout), а не читает версии одновременно с нами
+	beginMigration: []string{"BEGIN IMMEDIATE"},
+}
+
+// SQLiteStorage хранит задачи во встроенной базе SQLite - для запуска
+// на одном узле без отдельного сервера БД.
+type SQLiteStorage struct {
+	sqlStorage
+}
+
+func NewSQLiteStorage(path string) (*SQLiteStorage, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", path)
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+	// SQLite допускает только одного писателя, а для ":memory:" каждое
+	// соединение получило бы свою базу.
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(db, sqliteDialect, sqliteMigrations, "migrations/sqlite"); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	s := &SQLiteStorage{sqlStorage{db: db, dialect: sqliteDialect}}
+	if err := s.indexTasks(context.Background()); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("index tasks: %w", err)
+	}
+
+	return s, nil
+}
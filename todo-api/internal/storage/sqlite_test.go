@@ -0,0 +1,1039 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"todo-api/internal/models"
+)
+
+func newTestSQLiteStorage(t *testing.T) *SQLiteStorage {
+	t.Helper()
+
+	s, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "todo.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+// testBackends возвращает хранилища, на которых проверяется одинаковое
+// поведение. PostgreSQL добавляется, если задана переменная postgresDSNEnv.
+func testBackends(t *testing.T) map[string]Storage {
+	t.Helper()
+
+	backends := map[string]Storage{
+		"memory": NewMemoryStorage(),
+		"sqlite": newTestSQLiteStorage(t),
+	}
+	if os.Getenv(postgresDSNEnv) != "" {
+		backends["postgres"] = newTestPostgresStorage(t)
+	}
+	return backends
+}
+
+func TestSQLiteGetAllMatchesMemory(t *testing.T) {
+	memory := NewMemoryStorage()
+	sqlite := newTestSQLiteStorage(t)
+
+	now := time.Now().UTC()
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	seed := []models.Task{
+		{Title: "Купить молоко", Description: "В магазине у дома", DueAt: at(-48 * time.Hour), Tags: []string{"дом", "покупки"}, Priority: models.PriorityHigh},
+		{Title: "Write report", Description: "Quarterly numbers", Completed: true, DueAt: at(-24 * time.Hour), Tags: []string{"work"}, Priority: models.PriorityHigh},
+		{Title: "Позвонить маме", Description: "", DueAt: at(24 * time.Hour), Tags: []string{"дом"}},
+		{Title: "Read book", Description: "МОЛОКО и мёд", Completed: true, Tags: []string{"дом", "покупки", "work"}},
+		{Title: "Fix bike", Description: "rear wheel", DueAt: at(72 * time.Hour), Priority: models.PriorityLow},
+		{Title: "allocate budget", Description: "", DueAt: at(96 * time.Hour), Priority: models.PriorityLow},
+	}
+
+	// Идентификаторы в хранилищах разные, поэтому задачи сопоставляются по заголовку
+	for _, task := range seed {
+		if _, err := memory.Create(t.Context(), task); err != nil {
+			t.Fatalf("memory.Create(t.Context()) error = %v", err)
+		}
+		if _, err := sqlite.Create(t.Context(), task); err != nil {
+			t.Fatalf("sqlite.Create(t.Context()) error = %v", err)
+		}
+	}
+
+	completed := true
+	notCompleted := false
+
+	tests := []struct {
+		name  string
+		query models.TaskQuery
+	}{
+		{"Default order", models.TaskQuery{Limit: 10}},
+		{"Created asc", models.TaskQuery{Limit: 10, Sort: sortBy("created_at", false)}},
+		{"Created desc", models.TaskQuery{Limit: 10, Sort: sortBy("created_at", true)}},
+		{"Title", models.TaskQuery{Limit: 10, Sort: sortBy("title", false)}},
+		{"Completed only", models.TaskQuery{Limit: 10, Completed: &completed}},
+		{"Not completed", models.TaskQuery{Limit: 10, Completed: &notCompleted}},
+		{"Search cyrillic case-insensitive", models.TaskQuery{Limit: 10, Search: "молоко"}},
+		{"Search latin", models.TaskQuery{Limit: 10, Search: "READ"}},
+		{"Search word forms", models.TaskQuery{Limit: 10, Search: "молоком купил"}},
+		{"Search with filter", models.TaskQuery{Limit: 10, Search: "молоко", Completed: &notCompleted}},
+		{"Search phrase", models.TaskQuery{Limit: 10, Search: `"молоко и мёд"`}},
+		{"Search phrase out of order", models.TaskQuery{Limit: 10, Search: `"мёд и молоко"`}},
+		{"Search by relevance", models.TaskQuery{Limit: 10, Search: "молоко", Sort: sortBy("relevance", true)}},
+		{"Search nothing", models.TaskQuery{Limit: 10, Search: "?!"}},
+		{"Pagination", models.TaskQuery{Limit: 2, Offset: 1, Sort: sortBy("created_at", false)}},
+		{"Offset past end", models.TaskQuery{Limit: 2, Offset: 10}},
+		{"Due asc", models.TaskQuery{Limit: 10, Sort: sortBy("due_at", false)}},
+		{"Due desc", models.TaskQuery{Limit: 10, Sort: sortBy("due_at", true)}},
+		{"Due after", models.TaskQuery{Limit: 10, DueAfter: at(-24 * time.Hour), Sort: sortBy("due_at", false)}},
+		{"Due before", models.TaskQuery{Limit: 10, DueBefore: at(24 * time.Hour), Sort: sortBy("due_at", false)}},
+		{"Due range", models.TaskQuery{Limit: 10, DueAfter: at(-72 * time.Hour), DueBefore: at(48 * time.Hour), Sort: sortBy("due_at", false)}},
+		{"Overdue", models.TaskQuery{Limit: 10, Overdue: true}},
+		{"Priority desc", models.TaskQuery{Limit: 10, Sort: []models.SortKey{
+			{Field: models.SortPriority, Desc: true}, {Field: models.SortCreatedAt},
+		}}},
+		{"Priority then due", models.TaskQuery{Limit: 10, Sort: []models.SortKey{
+			{Field: models.SortPriority, Desc: true}, {Field: models.SortDueAt}, {Field: models.SortTitle},
+		}}},
+		{"Completed then title desc", models.TaskQuery{Limit: 10, Sort: []models.SortKey{
+			{Field: models.SortCompleted}, {Field: models.SortTitle, Desc: true},
+		}}},
+		{"Any tag", models.TaskQuery{Limit: 10, Tags: []string{"work", "покупки"}}},
+		{"All tags", models.TaskQuery{Limit: 10, Tags: []string{"дом", "покупки"}, AllTags: true}},
+		{"All tags with filter", models.TaskQuery{Limit: 10, Tags: []string{"дом"}, AllTags: true, Completed: &notCompleted}},
+		{"Unknown tag", models.TaskQuery{Limit: 10, Tags: []string{"нет"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, wantTotal, err := memory.GetAll(t.Context(), tt.query)
+			if err != nil {
+				t.Fatalf("memory.GetAll(t.Context()) error = %v", err)
+			}
+			got, gotTotal, err := sqlite.GetAll(t.Context(), tt.query)
+			if err != nil {
+				t.Fatalf("sqlite.GetAll(t.Context()) error = %v", err)
+			}
+
+			if gotTotal != wantTotal {
+				t.Errorf("total = %d, want %d", gotTotal, wantTotal)
+			}
+			if !equalTitles(got, want) {
+				t.Errorf("titles = %v, want %v", titles(got), titles(want))
+			}
+			for i := range got {
+				if i < len(want) && got[i].Score != want[i].Score {
+					t.Errorf("%s score = %v, want %v", got[i].Title, got[i].Score, want[i].Score)
+				}
+			}
+
+			wantCounts, err := memory.CountTags(t.Context(), tt.query)
+			if err != nil {
+				t.Fatalf("memory.CountTags(t.Context()) error = %v", err)
+			}
+			gotCounts, err := sqlite.CountTags(t.Context(), tt.query)
+			if err != nil {
+				t.Fatalf("sqlite.CountTags(t.Context()) error = %v", err)
+			}
+			if fmt.Sprint(gotCounts) != fmt.Sprint(wantCounts) {
+				t.Errorf("CountTags() = %v, want %v", gotCounts, wantCounts)
+			}
+		})
+	}
+}
+
+func TestCursorPagination(t *testing.T) {
+	backends := testBackends(t)
+
+	now := time.Now().UTC()
+	for name, s := range backends {
+		for i := range 7 {
+			task := models.Task{Title: fmt.Sprintf("Задача %d", i%3), Priority: models.Priority(i % 2), Completed: i%4 == 0}
+			if i%3 != 0 {
+				due := now.Add(time.Duration(i%2) * time.Hour)
+				task.DueAt = &due
+			}
+			if _, err := s.Create(t.Context(), task); err != nil {
+				t.Fatalf("%s: Create() error = %v", name, err)
+			}
+		}
+	}
+
+	sorts := map[string][]models.SortKey{
+		"default":   nil,
+		"due":       {{Field: models.SortDueAt}},
+		"due desc":  {{Field: models.SortDueAt, Desc: true}},
+		"multi-key": {{Field: models.SortPriority, Desc: true}, {Field: models.SortTitle}, {Field: models.SortCompleted}},
+	}
+
+	for name, s := range backends {
+		for sortName, keys := range sorts {
+			t.Run(name+"/"+sortName, func(t *testing.T) {
+				all, _, err := s.GetAll(t.Context(), models.TaskQuery{Limit: 100, Sort: keys})
+				if err != nil {
+					t.Fatalf("GetAll() error = %v", err)
+				}
+
+				// Проходим все страницы вперёд, затем от последней задачи назад
+				var forward []models.Task
+				query := models.TaskQuery{Limit: 3, Sort: keys, Cursor: &models.Cursor{Task: all[0]}}
+				forward = append(forward, all[0])
+				for {
+					page, total, err := s.GetAll(t.Context(), query)
+					if err != nil {
+						t.Fatalf("GetAll(cursor) error = %v", err)
+					}
+					if total != len(all) {
+						t.Errorf("total = %d, want %d", total, len(all))
+					}
+					if len(page) == 0 {
+						break
+					}
+					forward = append(forward, page...)
+					query.Cursor = &models.Cursor{Task: page[len(page)-1]}
+				}
+				if !equalIDs(forward, all) {
+					t.Errorf("forward pages = %v, want %v", titles(forward), titles(all))
+				}
+
+				last := all[len(all)-1]
+				backward := []models.Task{last}
+				query.Cursor = &models.Cursor{Task: last, Backward: true}
+				for {
+					page, _, err := s.GetAll(t.Context(), query)
+					if err != nil {
+						t.Fatalf("GetAll(backward cursor) error = %v", err)
+					}
+					if len(page) == 0 {
+						break
+					}
+					backward = append(page, backward...)
+					query.Cursor = &models.Cursor{Task: page[0], Backward: true}
+				}
+				if !equalIDs(backward, all) {
+					t.Errorf("backward pages = %v, want %v", titles(backward), titles(all))
+				}
+			})
+		}
+	}
+}
+
+func TestSQLiteIndexTasks(t *testing.T) {
+	s := newTestSQLiteStorage(t)
+
+	task, err := s.Create(t.Context(), models.Task{Title: "Написать отчёт", Description: "за квартал"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// Задачи, созданные до появления индекса, индексируются при открытии хранилища
+	if _, err := s.exec(t.Context(), `DELETE FROM task_terms`); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.indexTasks(t.Context()); err != nil {
+		t.Fatalf("indexTasks() error = %v", err)
+	}
+
+	tasks, _, err := s.GetAll(t.Context(), models.TaskQuery{Limit: 10, Search: "отчёты квартала"})
+	if err != nil {
+		t.Fatalf("GetAll() error = %v", err)
+	}
+	if len(tasks) != 1 || tasks[0].ID != task.ID || tasks[0].Score == 0 {
+		t.Errorf("GetAll() = %+v, want the reindexed task with a score", tasks)
+	}
+}
+
+func TestSQLiteCRUD(t *testing.T) {
+	testCRUD(t, newTestSQLiteStorage(t))
+}
+
+func testCRUD(t *testing.T, s Storage) {
+	t.Helper()
+
+	created, err := s.Create(t.Context(), models.Task{Title: "Задача", Description: "Описание"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	got, err := s.GetByID(t.Context(), created.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if got.Title != created.Title || !got.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("GetByID() = %+v, want %+v", got, created)
+	}
+
+	got.Title = "Новый заголовок"
+	updated, err := s.Update(t.Context(), created.ID, got)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if updated.Title != "Новый заголовок" || !updated.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("Update() = %+v", updated)
+	}
+
+	completed, err := s.CompleteTask(t.Context(), created.ID, 0)
+	if err != nil {
+		t.Fatalf("CompleteTask() error = %v", err)
+	}
+	if !completed.Completed {
+		t.Errorf("CompleteTask() did not mark task as completed")
+	}
+
+	if err := s.Delete(t.Context(), created.ID, 0); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := s.GetByID(t.Context(), created.ID); err != ErrTaskNotFound {
+		t.Errorf("GetByID() after Delete error = %v, want %v", err, ErrTaskNotFound)
+	}
+	if err := s.Delete(t.Context(), created.ID, 0); err != ErrTaskNotFound {
+		t.Errorf("Delete() of missing task error = %v, want %v", err, ErrTaskNotFound)
+	}
+	if _, err := s.Update(t.Context(), created.ID, got); err != ErrTaskNotFound {
+		t.Errorf("Update() of missing task error = %v, want %v", err, ErrTaskNotFound)
+	}
+}
+
+func TestCanceledContext(t *testing.T) {
+	for name, s := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			created, err := s.Create(t.Context(), models.Task{Title: "Задача"})
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+
+			ctx, cancel := context.WithCancel(t.Context())
+			cancel()
+			if _, err := s.GetByID(ctx, created.ID); !errors.Is(err, context.Canceled) {
+				t.Errorf("GetByID() error = %v, want %v", err, context.Canceled)
+			}
+			if _, err := s.Create(ctx, models.Task{Title: "Другая"}); !errors.Is(err, context.Canceled) {
+				t.Errorf("Create() error = %v, want %v", err, context.Canceled)
+			}
+
+			// Отмененная запись не применилась
+			if _, total, err := s.GetAll(t.Context(), models.TaskQuery{}); err != nil || total != 1 {
+				t.Errorf("GetAll() total = %d, %v, want 1", total, err)
+			}
+		})
+	}
+}
+
+func TestSQLiteTokens(t *testing.T) {
+	s := newTestSQLiteStorage(t)
+
+	user, err := s.CreateUser(t.Context(), models.User{Username: "alice", PasswordHash: "hash"})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if _, err := s.CreateUser(t.Context(), models.User{Username: "alice", PasswordHash: "hash"}); err != ErrUserExists {
+		t.Errorf("CreateUser() duplicate error = %v, want %v", err, ErrUserExists)
+	}
+
+	token := models.RefreshToken{
+		TokenHash: "hash-1",
+		UserID:    user.ID,
+		FamilyID:  "family",
+		ExpiresAt: time.Now().Add(time.Hour),
+		CreatedAt: time.Now(),
+	}
+	if err := s.CreateRefreshToken(t.Context(), token); err != nil {
+		t.Fatalf("CreateRefreshToken() error = %v", err)
+	}
+
+	if err := s.UseRefreshToken(t.Context(), "hash-1"); err != nil {
+		t.Fatalf("UseRefreshToken() error = %v", err)
+	}
+	if err := s.UseRefreshToken(t.Context(), "hash-1"); err != ErrTokenUsed {
+		t.Errorf("UseRefreshToken() second time error = %v, want %v", err, ErrTokenUsed)
+	}
+	if err := s.UseRefreshToken(t.Context(), "missing"); err != ErrTokenNotFound {
+		t.Errorf("UseRefreshToken() of missing token error = %v, want %v", err, ErrTokenNotFound)
+	}
+
+	if err := s.RevokeTokenFamily(t.Context(), "family"); err != nil {
+		t.Fatalf("RevokeTokenFamily() error = %v", err)
+	}
+	got, err := s.GetRefreshToken(t.Context(), "hash-1")
+	if err != nil {
+		t.Fatalf("GetRefreshToken() error = %v", err)
+	}
+	if got.UsedAt == nil || got.RevokedAt == nil {
+		t.Errorf("GetRefreshToken() = %+v, want used and revoked", got)
+	}
+
+	if err := s.RevokeAccessToken(t.Context(), "jti", time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("RevokeAccessToken() error = %v", err)
+	}
+	if revoked, err := s.IsAccessTokenRevoked(t.Context(), "jti"); err != nil || !revoked {
+		t.Errorf("IsAccessTokenRevoked() = %v, %v, want true", revoked, err)
+	}
+	if revoked, _ := s.IsAccessTokenRevoked(t.Context(), "other"); revoked {
+		t.Errorf("IsAccessTokenRevoked() of unknown token = true")
+	}
+}
+
+func TestLists(t *testing.T) {
+	for name, s := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			users := make(map[string]models.User)
+			for _, username := range []string{"owner", "editor"} {
+				user, err := s.CreateUser(t.Context(), models.User{Username: username, PasswordHash: "hash"})
+				if err != nil {
+					t.Fatalf("CreateUser() error = %v", err)
+				}
+				users[username] = user
+			}
+			owner, editor := users["owner"].ID, users["editor"].ID
+
+			list, err := s.CreateList(t.Context(), models.List{Name: "Дом", OwnerID: owner})
+			if err != nil {
+				t.Fatalf("CreateList() error = %v", err)
+			}
+			// Владелец сразу становится участником с ролью owner
+			if member, err := s.GetMember(t.Context(), list.ID, owner); err != nil || member.Role != models.RoleOwner {
+				t.Errorf("GetMember(owner) = %+v, %v, want role owner", member, err)
+			}
+
+			first, err := s.PutMember(t.Context(), models.ListMember{ListID: list.ID, UserID: editor, Role: models.RoleViewer})
+			if err != nil {
+				t.Fatalf("PutMember() error = %v", err)
+			}
+			changed, err := s.PutMember(t.Context(), models.ListMember{ListID: list.ID, UserID: editor, Role: models.RoleEditor})
+			if err != nil {
+				t.Fatalf("PutMember() role change error = %v", err)
+			}
+			if !changed.CreatedAt.Equal(first.CreatedAt) {
+				t.Errorf("PutMember() role change created_at = %v, want %v", changed.CreatedAt, first.CreatedAt)
+			}
+			if member, err := s.GetMember(t.Context(), list.ID, editor); err != nil || member.Role != models.RoleEditor {
+				t.Errorf("GetMember(editor) = %+v, %v, want role editor", member, err)
+			}
+			if members, err := s.GetMembers(t.Context(), list.ID); err != nil || len(members) != 2 {
+				t.Errorf("GetMembers() = %+v, %v, want 2 members", members, err)
+			}
+			if _, err := s.PutMember(t.Context(), models.ListMember{ListID: "missing", UserID: editor, Role: models.RoleViewer}); !errors.Is(err, ErrListNotFound) {
+				t.Errorf("PutMember() to missing list error = %v, want %v", err, ErrListNotFound)
+			}
+
+			updated, err := s.UpdateList(t.Context(), list.ID, models.List{Name: "Дача", Description: "Летом"})
+			if err != nil {
+				t.Fatalf("UpdateList() error = %v", err)
+			}
+			if updated.Name != "Дача" || updated.OwnerID != owner || !updated.CreatedAt.Equal(list.CreatedAt) {
+				t.Errorf("UpdateList() = %+v", updated)
+			}
+			if lists, err := s.GetListsByMember(t.Context(), editor); err != nil || len(lists) != 1 || lists[0].Name != "Дача" {
+				t.Errorf("GetListsByMember(editor) = %+v, %v, want [Дача]", lists, err)
+			}
+
+			if err := s.RemoveMember(t.Context(), list.ID, editor); err != nil {
+				t.Fatalf("RemoveMember() error = %v", err)
+			}
+			if err := s.RemoveMember(t.Context(), list.ID, editor); !errors.Is(err, ErrMemberNotFound) {
+				t.Errorf("RemoveMember() twice error = %v, want %v", err, ErrMemberNotFound)
+			}
+			if lists, err := s.GetListsByMember(t.Context(), editor); err != nil || len(lists) != 0 {
+				t.Errorf("GetListsByMember() after removal = %+v, %v, want none", lists, err)
+			}
+
+			// Удаление списка уносит его участников и задачи, но не личные задачи
+			inList, _ := s.Create(t.Context(), models.Task{Title: "В списке", OwnerID: owner, ListID: list.ID})
+			personal, _ := s.Create(t.Context(), models.Task{Title: "Личная", OwnerID: owner})
+			if err := s.DeleteList(t.Context(), list.ID); err != nil {
+				t.Fatalf("DeleteList() error = %v", err)
+			}
+			if _, err := s.GetList(t.Context(), list.ID); !errors.Is(err, ErrListNotFound) {
+				t.Errorf("GetList() after delete error = %v, want %v", err, ErrListNotFound)
+			}
+			if _, err := s.GetMember(t.Context(), list.ID, owner); !errors.Is(err, ErrMemberNotFound) {
+				t.Errorf("GetMember() after delete error = %v, want %v", err, ErrMemberNotFound)
+			}
+			if lists, err := s.GetListsByMember(t.Context(), owner); err != nil || len(lists) != 0 {
+				t.Errorf("GetListsByMember() after delete = %+v, %v, want none", lists, err)
+			}
+			if _, err := s.GetByID(t.Context(), inList.ID); !errors.Is(err, ErrTaskNotFound) {
+				t.Errorf("GetByID() of list task error = %v, want %v", err, ErrTaskNotFound)
+			}
+			if _, err := s.GetByID(t.Context(), personal.ID); err != nil {
+				t.Errorf("GetByID() of personal task error = %v", err)
+			}
+			if err := s.DeleteList(t.Context(), list.ID); !errors.Is(err, ErrListNotFound) {
+				t.Errorf("DeleteList() twice error = %v, want %v", err, ErrListNotFound)
+			}
+		})
+	}
+}
+
+func TestReminders(t *testing.T) {
+	backends := testBackends(t)
+
+	for name, s := range backends {
+		t.Run(name, func(t *testing.T) {
+			tasks := s.(TaskStorage)
+			now := time.Now().UTC()
+			at := func(d time.Duration) *time.Time {
+				t := now.Add(d)
+				return &t
+			}
+
+			second, _ := tasks.Create(t.Context(), models.Task{Title: "Вторая", RemindAt: at(-time.Minute)})
+			first, _ := tasks.Create(t.Context(), models.Task{Title: "Первая", RemindAt: at(-time.Hour)})
+			tasks.Create(t.Context(), models.Task{Title: "Позже", RemindAt: at(time.Hour)})
+			tasks.Create(t.Context(), models.Task{Title: "Выполнена", RemindAt: at(-time.Hour), Completed: true})
+			tasks.Create(t.Context(), models.Task{Title: "Без напоминания"})
+
+			due, err := s.GetDueReminders(t.Context(), now, 10)
+			if err != nil {
+				t.Fatalf("GetDueReminders() error = %v", err)
+			}
+			if got := titles(due); len(got) != 2 || got[0] != "Первая" || got[1] != "Вторая" {
+				t.Fatalf("GetDueReminders() = %v, want [Первая Вторая]", got)
+			}
+
+			// Напоминание перенесли после выборки - старое не отправляется
+			moved := due[1]
+			moved.RemindAt = at(-30 * time.Second)
+			if _, err := tasks.Update(t.Context(), second.ID, moved); err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if marked, err := s.MarkReminded(t.Context(), second.ID, *due[1].RemindAt); err != nil || marked {
+				t.Errorf("MarkReminded() with stale remind_at = %v, %v, want false", marked, err)
+			}
+
+			if marked, err := s.MarkReminded(t.Context(), first.ID, *due[0].RemindAt); err != nil || !marked {
+				t.Fatalf("MarkReminded() = %v, %v, want true", marked, err)
+			}
+			if marked, _ := s.MarkReminded(t.Context(), first.ID, *due[0].RemindAt); marked {
+				t.Errorf("MarkReminded() twice = true")
+			}
+
+			due, err = s.GetDueReminders(t.Context(), now, 10)
+			if err != nil {
+				t.Fatalf("GetDueReminders() error = %v", err)
+			}
+			if got := titles(due); len(got) != 1 || got[0] != "Вторая" {
+				t.Errorf("GetDueReminders() after mark = %v, want [Вторая]", got)
+			}
+
+			reminded, err := tasks.GetByID(t.Context(), first.ID)
+			if err != nil {
+				t.Fatalf("GetByID() error = %v", err)
+			}
+			if reminded.RemindedAt == nil {
+				t.Errorf("RemindedAt is not set after MarkReminded()")
+			}
+		})
+	}
+}
+
+func TestDependencies(t *testing.T) {
+	backends := testBackends(t)
+
+	for name, s := range backends {
+		t.Run(name, func(t *testing.T) {
+			parent, _ := s.Create(t.Context(), models.Task{Title: "Родитель"})
+			child, _ := s.Create(t.Context(), models.Task{Title: "Подзадача", ParentID: parent.ID})
+			grandchild, _ := s.Create(t.Context(), models.Task{Title: "Вложенная", ParentID: child.ID})
+			blocker, _ := s.Create(t.Context(), models.Task{Title: "Блокирует"})
+
+			children, err := s.GetChildren(t.Context(), parent.ID)
+			if err != nil {
+				t.Fatalf("GetChildren() error = %v", err)
+			}
+			if got := titles(children); len(got) != 1 || got[0] != "Подзадача" || children[0].ParentID != parent.ID {
+				t.Errorf("GetChildren() = %v", children)
+			}
+
+			for _, id := range []string{parent.ID, grandchild.ID} {
+				if err := s.AddDependency(t.Context(), id, blocker.ID); err != nil {
+					t.Fatalf("AddDependency() error = %v", err)
+				}
+			}
+			if err := s.AddDependency(t.Context(), parent.ID, blocker.ID); err != nil {
+				t.Errorf("AddDependency() of existing dependency error = %v", err)
+			}
+			if err := s.AddDependency(t.Context(), parent.ID, "missing"); err != ErrTaskNotFound {
+				t.Errorf("AddDependency() of missing task error = %v, want %v", err, ErrTaskNotFound)
+			}
+
+			blockers, err := s.GetBlockers(t.Context(), parent.ID)
+			if err != nil {
+				t.Fatalf("GetBlockers() error = %v", err)
+			}
+			if got := titles(blockers); len(got) != 1 || got[0] != "Блокирует" {
+				t.Errorf("GetBlockers() = %v, want [Блокирует]", got)
+			}
+
+			if err := s.RemoveDependency(t.Context(), parent.ID, blocker.ID); err != nil {
+				t.Fatalf("RemoveDependency() error = %v", err)
+			}
+			if err := s.RemoveDependency(t.Context(), parent.ID, blocker.ID); err != ErrDependencyNotFound {
+				t.Errorf("RemoveDependency() twice error = %v, want %v", err, ErrDependencyNotFound)
+			}
+
+			// Удаление родителя переносит в корзину всё поддерево, а очистка
+			// корзины удаляет его вместе с зависимостями
+			if err := s.Delete(t.Context(), parent.ID, 0); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			for _, id := range []string{child.ID, grandchild.ID} {
+				if _, err := s.GetByID(t.Context(), id); err != ErrTaskNotFound {
+					t.Errorf("GetByID() of deleted subtask error = %v, want %v", err, ErrTaskNotFound)
+				}
+			}
+			if _, err := s.Purge(t.Context(), time.Now().Add(time.Second)); err != nil {
+				t.Fatalf("Purge() error = %v", err)
+			}
+			if blockers, _ := s.GetBlockers(t.Context(), grandchild.ID); len(blockers) != 0 {
+				t.Errorf("dependencies of purged task were kept: %v", titles(blockers))
+			}
+			if _, err := s.GetByID(t.Context(), blocker.ID); err != nil {
+				t.Errorf("blocker was deleted with its dependent task: %v", err)
+			}
+		})
+	}
+}
+
+func TestTags(t *testing.T) {
+	backends := testBackends(t)
+
+	for name, s := range backends {
+		t.Run(name, func(t *testing.T) {
+			owner, _ := s.CreateUser(t.Context(), models.User{Username: "owner"})
+			other, _ := s.CreateUser(t.Context(), models.User{Username: "other"})
+
+			if _, err := s.CreateTag(t.Context(), models.Tag{Name: "дом", Color: "#00ff00", OwnerID: owner.ID}); err != nil {
+				t.Fatalf("CreateTag() error = %v", err)
+			}
+			if _, err := s.CreateTag(t.Context(), models.Tag{Name: "дом", OwnerID: owner.ID}); err != ErrTagExists {
+				t.Errorf("CreateTag() of existing tag error = %v, want %v", err, ErrTagExists)
+			}
+			if _, err := s.CreateTag(t.Context(), models.Tag{Name: "дом", OwnerID: other.ID}); err != nil {
+				t.Errorf("CreateTag() of same name for other user error = %v", err)
+			}
+
+			both, _ := s.Create(t.Context(), models.Task{Title: "Оба", OwnerID: owner.ID, Tags: []string{"дача", "дом"}})
+			single, _ := s.Create(t.Context(), models.Task{Title: "Один", OwnerID: owner.ID, Tags: []string{"дом"}})
+			foreign, _ := s.Create(t.Context(), models.Task{Title: "Чужая", OwnerID: other.ID, Tags: []string{"дом"}})
+
+			if got, _ := s.GetByID(t.Context(), both.ID); fmt.Sprint(got.Tags) != "[дача дом]" {
+				t.Errorf("GetByID().Tags = %v, want [дача дом]", got.Tags)
+			}
+
+			// Переименование сливает тег с уже имеющимся и не трогает чужие задачи
+			if _, err := s.UpdateTag(t.Context(), owner.ID, "дом", models.Tag{Name: "дача"}); err != nil {
+				t.Fatalf("UpdateTag() error = %v", err)
+			}
+			for id, want := range map[string]string{both.ID: "[дача]", single.ID: "[дача]", foreign.ID: "[дом]"} {
+				if got, _ := s.GetByID(t.Context(), id); fmt.Sprint(got.Tags) != want {
+					t.Errorf("%s tags after rename = %v, want %v", got.Title, got.Tags, want)
+				}
+			}
+			if _, err := s.GetTag(t.Context(), owner.ID, "дом"); err != ErrTagNotFound {
+				t.Errorf("GetTag() of renamed tag error = %v, want %v", err, ErrTagNotFound)
+			}
+			if tag, err := s.GetTag(t.Context(), owner.ID, "дача"); err != nil || tag.Color != "" {
+				t.Errorf("GetTag() = %+v, %v", tag, err)
+			}
+
+			counts, err := s.CountTags(t.Context(), models.TaskQuery{OwnerID: owner.ID})
+			if err != nil {
+				t.Fatalf("CountTags() error = %v", err)
+			}
+			if fmt.Sprint(counts) != "[{дача 2}]" {
+				t.Errorf("CountTags() = %v, want [{дача 2}]", counts)
+			}
+
+			if err := s.DeleteTag(t.Context(), owner.ID, "дача"); err != nil {
+				t.Fatalf("DeleteTag() error = %v", err)
+			}
+			if err := s.DeleteTag(t.Context(), owner.ID, "дача"); err != ErrTagNotFound {
+				t.Errorf("DeleteTag() twice error = %v, want %v", err, ErrTagNotFound)
+			}
+			if got, _ := s.GetByID(t.Context(), both.ID); len(got.Tags) != 0 {
+				t.Errorf("tags after delete = %v, want none", got.Tags)
+			}
+			if tags, _ := s.GetTags(t.Context(), other.ID); len(tags) != 1 {
+				t.Errorf("GetTags() of other user = %v, want one tag", tags)
+			}
+		})
+	}
+}
+
+func TestVersions(t *testing.T) {
+	backends := testBackends(t)
+
+	for name, s := range backends {
+		t.Run(name, func(t *testing.T) {
+			owner, _ := s.CreateUser(t.Context(), models.User{Username: "owner"})
+			task, _ := s.Create(t.Context(), models.Task{Title: "Задача", OwnerID: owner.ID, Tags: []string{"дом"}})
+			if task.Version != 1 {
+				t.Fatalf("Create().Version = %d, want 1", task.Version)
+			}
+
+			stale := task
+			task.Title = "Новый заголовок"
+			updated, err := s.Update(t.Context(), task.ID, task)
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if updated.Version != 2 {
+				t.Errorf("Update().Version = %d, want 2", updated.Version)
+			}
+
+			// Изменения по устаревшей версии отклоняются
+			if _, err := s.Update(t.Context(), task.ID, stale); err != ErrVersionConflict {
+				t.Errorf("Update() with stale version error = %v, want %v", err, ErrVersionConflict)
+			}
+			if _, err := s.CompleteTask(t.Context(), task.ID, stale.Version); err != ErrVersionConflict {
+				t.Errorf("CompleteTask() with stale version error = %v, want %v", err, ErrVersionConflict)
+			}
+			if err := s.Delete(t.Context(), task.ID, stale.Version); err != ErrVersionConflict {
+				t.Errorf("Delete() with stale version error = %v, want %v", err, ErrVersionConflict)
+			}
+
+			completed, err := s.CompleteTask(t.Context(), task.ID, updated.Version)
+			if err != nil {
+				t.Fatalf("CompleteTask() error = %v", err)
+			}
+			if completed.Version != 3 {
+				t.Errorf("CompleteTask().Version = %d, want 3", completed.Version)
+			}
+
+			// Переименование тега меняет задачу, а значит и её версию
+			if _, err := s.CreateTag(t.Context(), models.Tag{Name: "дом", OwnerID: owner.ID}); err != nil {
+				t.Fatalf("CreateTag() error = %v", err)
+			}
+			if _, err := s.UpdateTag(t.Context(), owner.ID, "дом", models.Tag{Name: "дача"}); err != nil {
+				t.Fatalf("UpdateTag() error = %v", err)
+			}
+			if got, _ := s.GetByID(t.Context(), task.ID); got.Version != 4 {
+				t.Errorf("Version after tag rename = %d, want 4", got.Version)
+			}
+
+			if err := s.Delete(t.Context(), task.ID, 4); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if err := s.Delete(t.Context(), task.ID, 4); err != ErrTaskNotFound {
+				t.Errorf("Delete() of missing task error = %v, want %v", err, ErrTaskNotFound)
+			}
+		})
+	}
+}
+
+func TestApplyBatch(t *testing.T) {
+	backends := testBackends(t)
+
+	for name, s := range backends {
+		t.Run(name, func(t *testing.T) {
+			parent, _ := s.Create(t.Context(), models.Task{Title: "Родитель"})
+			child, _ := s.Create(t.Context(), models.Task{Title: "Подзадача", ParentID: parent.ID})
+			other, _ := s.Create(t.Context(), models.Task{Title: "Другая", Tags: []string{"дом"}})
+
+			// Подзадача удалена вместе с родителем, поэтому её обновление
+			// не проходит и весь пакет откатывается
+			renamed := child
+			renamed.Title = "Переименованная"
+			_, err := s.ApplyBatch(t.Context(), []BatchOp{
+				{Kind: BatchCreate, Task: models.Task{Title: "Новая"}},
+				{Kind: BatchDelete, Task: models.Task{ID: parent.ID}},
+				{Kind: BatchUpdate, Task: renamed},
+			})
+			var batchErr *BatchError
+			if !errors.As(err, &batchErr) || batchErr.Index != 2 || !errors.Is(err, ErrTaskNotFound) {
+				t.Fatalf("ApplyBatch() error = %v, want operation 2 to fail with %v", err, ErrTaskNotFound)
+			}
+			if _, total, _ := s.GetAll(t.Context(), models.TaskQuery{Limit: 10}); total != 3 {
+				t.Errorf("tasks after failed batch = %d, want 3", total)
+			}
+
+			other.Title = "Изменённая"
+			other.Tags = []string{"дача"}
+			results, err := s.ApplyBatch(t.Context(), []BatchOp{
+				{Kind: BatchCreate, Task: models.Task{Title: "Новая"}},
+				{Kind: BatchUpdate, Task: other},
+				{Kind: BatchDelete, Task: models.Task{ID: parent.ID, Version: parent.Version}},
+			})
+			if err != nil {
+				t.Fatalf("ApplyBatch() error = %v", err)
+			}
+			if results[0].ID == "" || results[0].Version != 1 {
+				t.Errorf("created task = %+v", results[0])
+			}
+			if results[1].Title != "Изменённая" || results[1].Version != 2 {
+				t.Errorf("updated task = %+v", results[1])
+			}
+			if got, _ := s.GetByID(t.Context(), other.ID); fmt.Sprint(got.Tags) != "[дача]" {
+				t.Errorf("tags after batch = %v, want [дача]", got.Tags)
+			}
+			for _, id := range []string{parent.ID, child.ID} {
+				if _, err := s.GetByID(t.Context(), id); err != ErrTaskNotFound {
+					t.Errorf("GetByID() of deleted task error = %v, want %v", err, ErrTaskNotFound)
+				}
+			}
+
+			// Устаревшая версия отклоняет пакет
+			_, err = s.ApplyBatch(t.Context(), []BatchOp{{Kind: BatchUpdate, Task: other}})
+			if !errors.Is(err, ErrVersionConflict) {
+				t.Errorf("ApplyBatch() with stale version error = %v, want %v", err, ErrVersionConflict)
+			}
+		})
+	}
+}
+
+func TestTrash(t *testing.T) {
+	backends := testBackends(t)
+
+	for name, s := range backends {
+		t.Run(name, func(t *testing.T) {
+			parent, _ := s.Create(t.Context(), models.Task{Title: "Родитель"})
+			child, _ := s.Create(t.Context(), models.Task{Title: "Подзадача", ParentID: parent.ID})
+			grandchild, _ := s.Create(t.Context(), models.Task{Title: "Вложенная", ParentID: child.ID})
+			earlier, _ := s.Create(t.Context(), models.Task{Title: "Удалена раньше", ParentID: parent.ID})
+			s.Create(t.Context(), models.Task{Title: "Другая"})
+
+			if err := s.Delete(t.Context(), earlier.ID, 0); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if err := s.Delete(t.Context(), parent.ID, parent.Version); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+
+			if _, total, _ := s.GetAll(t.Context(), models.TaskQuery{Limit: 10}); total != 1 {
+				t.Errorf("GetAll() total = %d, want 1", total)
+			}
+			trash, total, err := s.GetAll(t.Context(), models.TaskQuery{Limit: 10, Deleted: true, Sort: sortBy(models.SortTitle, false)})
+			if err != nil {
+				t.Fatalf("GetAll() of trash error = %v", err)
+			}
+			if total != 4 || fmt.Sprint(titles(trash)) != "[Вложенная Подзадача Родитель Удалена раньше]" {
+				t.Errorf("GetAll() of trash = %v (total %d)", titles(trash), total)
+			}
+
+			deleted, err := s.GetDeleted(t.Context(), parent.ID)
+			if err != nil || deleted.DeletedAt == nil || deleted.Version != parent.Version+1 {
+				t.Errorf("GetDeleted() = %+v, %v", deleted, err)
+			}
+			if _, err := s.Update(t.Context(), child.ID, child); err != ErrTaskNotFound {
+				t.Errorf("Update() of deleted task error = %v, want %v", err, ErrTaskNotFound)
+			}
+			if err := s.Delete(t.Context(), parent.ID, 0); err != ErrTaskNotFound {
+				t.Errorf("Delete() of deleted task error = %v, want %v", err, ErrTaskNotFound)
+			}
+
+			// Восстанавливаются только подзадачи, удалённые вместе с родителем
+			restored, err := s.Restore(t.Context(), parent.ID)
+			if err != nil {
+				t.Fatalf("Restore() error = %v", err)
+			}
+			if restored.DeletedAt != nil || restored.Version != parent.Version+2 {
+				t.Errorf("Restore() = %+v", restored)
+			}
+			for _, id := range []string{child.ID, grandchild.ID} {
+				if _, err := s.GetByID(t.Context(), id); err != nil {
+					t.Errorf("GetByID() of restored subtask error = %v", err)
+				}
+			}
+			if _, err := s.GetDeleted(t.Context(), earlier.ID); err != nil {
+				t.Errorf("GetDeleted() of earlier deleted subtask error = %v", err)
+			}
+			if _, err := s.Restore(t.Context(), parent.ID); err != ErrTaskNotFound {
+				t.Errorf("Restore() of live task error = %v, want %v", err, ErrTaskNotFound)
+			}
+
+			if purged, err := s.Purge(t.Context(), time.Now().Add(-time.Hour)); err != nil || purged != 0 {
+				t.Errorf("Purge() of recent tasks = %d, %v, want 0", purged, err)
+			}
+			if purged, err := s.Purge(t.Context(), time.Now().Add(time.Second)); err != nil || purged != 1 {
+				t.Errorf("Purge() = %d, %v, want 1", purged, err)
+			}
+			if _, err := s.GetDeleted(t.Context(), earlier.ID); err != ErrTaskNotFound {
+				t.Errorf("GetDeleted() of purged task error = %v, want %v", err, ErrTaskNotFound)
+			}
+		})
+	}
+}
+
+func TestHistory(t *testing.T) {
+	backends := testBackends(t)
+
+	for name, s := range backends {
+		t.Run(name, func(t *testing.T) {
+			task, _ := s.Create(t.Context(), models.Task{Title: "Задача"})
+
+			if _, err := s.AddHistory(t.Context(), models.HistoryEntry{TaskID: "missing", Action: models.HistoryCreated}); err != ErrTaskNotFound {
+				t.Errorf("AddHistory() for missing task error = %v, want %v", err, ErrTaskNotFound)
+			}
+
+			change := models.FieldChange{Field: "title", From: json.RawMessage(`"Задача"`), To: json.RawMessage(`"Новая"`)}
+			for _, action := range []models.HistoryAction{models.HistoryCreated, models.HistoryUpdated, models.HistoryDeleted} {
+				entry := models.HistoryEntry{TaskID: task.ID, ActorID: "user", Action: action, Changes: []models.FieldChange{}}
+				if action == models.HistoryUpdated {
+					entry.Changes = []models.FieldChange{change}
+				}
+				if _, err := s.AddHistory(t.Context(), entry); err != nil {
+					t.Fatalf("AddHistory() error = %v", err)
+				}
+			}
+
+			// Записи идут от новых к старым
+			entries, total, err := s.GetHistory(t.Context(), task.ID, 2, 0)
+			if err != nil {
+				t.Fatalf("GetHistory() error = %v", err)
+			}
+			if total != 3 || len(entries) != 2 || entries[0].Action != models.HistoryDeleted || entries[1].Action != models.HistoryUpdated {
+				t.Fatalf("GetHistory() = %+v (total %d)", entries, total)
+			}
+			if got, _ := json.Marshal(entries[1].Changes); string(got) != `[{"field":"title","from":"Задача","to":"Новая"}]` {
+				t.Errorf("changes = %s", got)
+			}
+			if entries[0].ID == "" || entries[0].ActorID != "user" || entries[0].CreatedAt.IsZero() {
+				t.Errorf("entry = %+v", entries[0])
+			}
+			if entries, _, _ := s.GetHistory(t.Context(), task.ID, 2, 2); len(entries) != 1 || entries[0].Action != models.HistoryCreated {
+				t.Errorf("GetHistory() second page = %+v", entries)
+			}
+
+			// История удаляется вместе с задачей при очистке корзины
+			s.Delete(t.Context(), task.ID, 0)
+			if _, err := s.Purge(t.Context(), time.Now().Add(time.Second)); err != nil {
+				t.Fatalf("Purge() error = %v", err)
+			}
+			if _, total, _ := s.GetHistory(t.Context(), task.ID, 10, 0); total != 0 {
+				t.Errorf("history of purged task = %d entries, want 0", total)
+			}
+		})
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	backends := testBackends(t)
+
+	for name, s := range backends {
+		t.Run(name, func(t *testing.T) {
+			user, _ := s.CreateUser(t.Context(), models.User{Username: "owner", PasswordHash: "hash"})
+			webhook, err := s.CreateWebhook(t.Context(), models.Webhook{
+				OwnerID: user.ID,
+				URL:     "https://example.com/hook",
+				Events:  []models.WebhookEvent{models.WebhookTaskCreated},
+				Secret:  "secret",
+				Active:  true,
+			})
+			if err != nil {
+				t.Fatalf("CreateWebhook() error = %v", err)
+			}
+
+			webhook.Events = models.WebhookEvents
+			webhook.Active = false
+			webhook.Secret = "changed"
+			updated, err := s.UpdateWebhook(t.Context(), webhook.ID, webhook)
+			if err != nil || len(updated.Events) != 4 || updated.Active || updated.Secret != "secret" {
+				t.Errorf("UpdateWebhook() = %+v, %v, want all events, inactive, secret kept", updated, err)
+			}
+			if webhooks, _ := s.GetWebhooks(t.Context(), user.ID); len(webhooks) != 1 || webhooks[0].ID != webhook.ID {
+				t.Errorf("GetWebhooks() = %+v", webhooks)
+			}
+
+			if err := s.AddDeliveries(t.Context(), []models.WebhookDelivery{{WebhookID: "missing"}}); err != ErrWebhookNotFound {
+				t.Errorf("AddDeliveries() for missing webhook error = %v, want %v", err, ErrWebhookNotFound)
+			}
+
+			now := time.Now().UTC().Truncate(time.Millisecond)
+			later := now.Add(time.Hour)
+			deliveries := []models.WebhookDelivery{
+				{WebhookID: webhook.ID, EventID: "1", Event: models.WebhookTaskCreated, Payload: json.RawMessage(`{"n":1}`), Status: models.DeliveryPending, NextAttemptAt: &now},
+				{WebhookID: webhook.ID, EventID: "2", Event: models.WebhookTaskUpdated, Payload: json.RawMessage(`{"n":2}`), Status: models.DeliveryPending, NextAttemptAt: &later},
+			}
+			if err := s.AddDeliveries(t.Context(), deliveries); err != nil {
+				t.Fatalf("AddDeliveries() error = %v", err)
+			}
+
+			// Взятую доставку нельзя взять повторно до конца аренды
+			claimed, err := s.ClaimDeliveries(t.Context(), now, time.Minute, 10)
+			if err != nil || len(claimed) != 1 || claimed[0].ID != deliveries[0].ID || string(claimed[0].Payload) != `{"n":1}` {
+				t.Fatalf("ClaimDeliveries() = %+v, %v", claimed, err)
+			}
+			if again, _ := s.ClaimDeliveries(t.Context(), now.Add(time.Second), time.Minute, 10); len(again) != 0 {
+				t.Errorf("ClaimDeliveries() during lease = %d deliveries, want 0", len(again))
+			}
+
+			dead := claimed[0]
+			dead.Status = models.DeliveryDead
+			dead.Attempts = 3
+			dead.LastStatusCode = 500
+			dead.LastError = "unexpected response status 500"
+			dead.NextAttemptAt = nil
+			if err := s.UpdateDelivery(t.Context(), dead); err != nil {
+				t.Fatalf("UpdateDelivery() error = %v", err)
+			}
+			if got, _ := s.GetDelivery(t.Context(), dead.ID); got.Status != models.DeliveryDead || got.Attempts != 3 || got.NextAttemptAt != nil || got.EventID != "1" {
+				t.Errorf("GetDelivery() = %+v", got)
+			}
+
+			page, total, err := s.GetDeliveries(t.Context(), models.DeliveryQuery{OwnerID: user.ID, Status: models.DeliveryDead, Limit: 10})
+			if err != nil || total != 1 || len(page) != 1 || page[0].ID != dead.ID {
+				t.Errorf("GetDeliveries(dead) = %+v (total %d), %v", page, total, err)
+			}
+			if _, total, _ := s.GetDeliveries(t.Context(), models.DeliveryQuery{WebhookID: webhook.ID, Limit: 1}); total != 2 {
+				t.Errorf("GetDeliveries() total = %d, want 2", total)
+			}
+
+			// Доставки удаляются вместе с вебхуком
+			if err := s.DeleteWebhook(t.Context(), webhook.ID); err != nil {
+				t.Fatalf("DeleteWebhook() error = %v", err)
+			}
+			if _, err := s.GetDelivery(t.Context(), dead.ID); err != ErrDeliveryNotFound {
+				t.Errorf("GetDelivery() after DeleteWebhook error = %v, want %v", err, ErrDeliveryNotFound)
+			}
+			if err := s.DeleteWebhook(t.Context(), webhook.ID); err != ErrWebhookNotFound {
+				t.Errorf("DeleteWebhook() twice error = %v, want %v", err, ErrWebhookNotFound)
+			}
+		})
+	}
+}
+
+func sortBy(field string, desc bool) []models.SortKey {
+	return []models.SortKey{{Field: field, Desc: desc}}
+}
+
+func equalIDs(a, b []models.Task) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].ID != b[i].ID {
+			return false
+		}
+	}
+	return true
+}
+
+func titles(tasks []models.Task) []string {
+	result := make([]string, len(tasks))
+	for i, task := range tasks {
+		result[i] = task.Title
+	}
+	return result
+}
+
+func equalTitles(a, b []models.Task) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Title != b[i].Title {
+			return false
+		}
+	}
+	return true
+}
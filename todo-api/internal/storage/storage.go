@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"todo-api/internal/apperr"
+	"todo-api/internal/models"
+)
+
+var (
+	ErrTaskNotFound = apperr.NotFound("task not found")
+	ErrUserNotFound = apperr.NotFound("user not found")
+	ErrUserExists   = apperr.Conflict("user already exists")
+
+	// ErrVersionConflict - задача изменилась после того, как была прочитана
+	ErrVersionConflict = apperr.Conflict("task version conflict")
+
+	ErrListNotFound   = apperr.NotFound("list not found")
+	ErrMemberNotFound = apperr.NotFound("member not found")
+
+	ErrDependencyNotFound = apperr.NotFound("dependency not found")
+
+	ErrTagNotFound = apperr.NotFound("tag not found")
+	ErrTagExists   = apperr.Conflict("tag already exists")
+
+	ErrTokenNotFound = apperr.NotFound("token not found")
+	ErrTokenUsed     = apperr.Conflict("token already used")
+
+	ErrWebhookNotFound  = apperr.NotFound("webhook not found")
+	ErrDeliveryNotFound = apperr.NotFound("webhook delivery not found")
+)
+
+// TaskStorage описывает хранилище задач, с которым работает TodoService.
+// Все реализации должны одинаково обрабатывать фильтрацию, поиск,
+// сортировку и пагинацию в GetAll.
+//
+// Каждое изменение задачи увеличивает её Version. Update, Delete и
+// CompleteTask с ненулевой версией применяются, только если текущая версия
+// задачи совпадает с ней, иначе возвращают ErrVersionConflict.
+type TaskStorage interface {
+	Create(ctx context.Context, task models.Task) (models.Task, error)
+	GetByID(ctx context.Context, id string) (models.Task, error)
+	GetAll(ctx context.Context, query models.TaskQuery) ([]models.Task, int, error)
+	// Update сверяет версию с updatedTask.Version.
+	Update(ctx context.Context, id string, updatedTask models.Task) (models.Task, error)
+	// Delete переносит задачу вместе со всеми подзадачами в корзину: после
+	// этого их возвращают только GetAll с query.Deleted и методы TrashStorage.
+	Delete(ctx context.Context, id string, version int64) error
+	CompleteTask(ctx context.Context, id string, version int64) (models.Task, error)
+	// GetChildren возвращает прямые подзадачи в порядке создания.
+	GetChildren(ctx context.Context, parentID string) ([]models.Task, error)
+	// ApplyBatch атомарно применяет изменения ops по порядку: либо все, либо
+	// ни одного. Возвращает задачи в порядке ops (для удаления - пустую
+	// задачу) или *BatchError с номером первого неудавшегося изменения.
+	ApplyBatch(ctx context.Context, ops []BatchOp) ([]models.Task, error)
+}
+
+// BatchOpKind - вид изменения в ApplyBatch
+type BatchOpKind string
+
+const (
+	BatchCreate BatchOpKind = "create"
+	BatchUpdate BatchOpKind = "update"
+	BatchDelete BatchOpKind = "delete"
+)
+
+// BatchOp - одно изменение задачи в ApplyBatch. Для обновления и удаления
+// Task.ID задаёт задачу, а Task.Version - ожидаемую версию, как в Update и
+// Delete; при удалении остальные поля Task не используются.
+type BatchOp struct {
+	Kind BatchOpKind
+	Task models.Task
+}
+
+// BatchError сообщает, какое изменение пакета не удалось применить
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch operation %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// TrashStorage работает с задачами в корзине, куда их переносит Delete.
+type TrashStorage interface {
+	// GetDeleted возвращает задачу из корзины.
+	GetDeleted(ctx context.Context, id string) (models.Task, error)
+	// Restore возвращает задачу из корзины вместе с подзадачами, удалёнными
+	// одновременно с ней.
+	Restore(ctx context.Context, id string) (models.Task, error)
+	// Purge окончательно удаляет задачи, попавшие в корзину раньше before,
+	// вместе с их зависимостями и возвращает число удалённых задач.
+	Purge(ctx context.Context, before time.Time) (int, error)
+}
+
+// HistoryStorage хранит историю изменений задач. Записи не меняются и
+// удаляются только вместе с задачей, когда она удаляется окончательно.
+type HistoryStorage interface {
+	// AddHistory сохраняет запись, назначая ей ID и CreatedAt.
+	AddHistory(ctx context.Context, entry models.HistoryEntry) (models.HistoryEntry, error)
+	// GetHistory возвращает страницу записей задачи от новых к старым и
+	// общее число записей.
+	GetHistory(ctx context.Context, taskID string, limit, offset int) ([]models.HistoryEntry, int, error)
+}
+
+// ChangeSource - хранилище, которое сообщает о каждом применённом изменении
+// задачи. Изменения задач, которые уже в корзине, не сообщаются.
+type ChangeSource interface {
+	// OnChange задаёт получателя изменений. fn вызывается под блокировкой
+	// хранилища, поэтому не должна блокироваться и обращаться к хранилищу.
+	OnChange(fn func(event models.ChangeEvent))
+}
+
+// DependencyStorage хранит блокировки между задачами: задача taskID не может
+// быть выполнена, пока не выполнена blockerID.
+type DependencyStorage interface {
+	// AddDependency не возвращает ошибку, если зависимость уже есть.
+	AddDependency(ctx context.Context, taskID, blockerID string) error
+	RemoveDependency(ctx context.Context, taskID, blockerID string) error
+	// GetBlockers возвращает задачи, блокирующие taskID, в порядке создания.
+	GetBlockers(ctx context.Context, taskID string) ([]models.Task, error)
+}
+
+// TagStorage хранит каталоги тегов пользователей. Теги задач хранятся в
+// самих задачах (Task.Tags), каталог задаёт их оформление.
+type TagStorage interface {
+	CreateTag(ctx context.Context, tag models.Tag) (models.Tag, error)
+	GetTag(ctx context.Context, ownerID, name string) (models.Tag, error)
+	GetTags(ctx context.Context, ownerID string) ([]models.Tag, error)
+	// UpdateTag меняет тег каталога. При переименовании тег меняется и во
+	// всех задачах владельца.
+	UpdateTag(ctx context.Context, ownerID, name string, tag models.Tag) (models.Tag, error)
+	// DeleteTag удаляет тег из каталога и из всех задач владельца.
+	DeleteTag(ctx context.Context, ownerID, name string) error
+	// CountTags считает задачи по тегам среди задач, подходящих под фильтры
+	// query (пагинация и сортировка не учитываются). Результат упорядочен
+	// по убыванию числа задач, затем по имени.
+	CountTags(ctx context.Context, query models.TaskQuery) ([]models.TagCount, error)
+}
+
+// UserStorage хранит учётные записи пользователей.
+type UserStorage interface {
+	CreateUser(ctx context.Context, user models.User) (models.User, error)
+	GetUserByID(ctx context.Context, id string) (models.User, error)
+	GetUserByUsername(ctx context.Context, username string) (models.User, error)
+}
+
+// ListStorage хранит общие списки задач и их участников.
+type ListStorage interface {
+	// CreateList создаёт список и добавляет его владельца участником с ролью owner.
+	CreateList(ctx context.Context, list models.List) (models.List, error)
+	GetList(ctx context.Context, id string) (models.List, error)
+	GetListsByMember(ctx context.Context, userID string) ([]models.List, error)
+	UpdateList(ctx context.Context, id string, list models.List) (models.List, error)
+	// DeleteList удаляет список вместе с участниками и всеми его задачами.
+	DeleteList(ctx context.Context, id string) error
+
+	GetMember(ctx context.Context, listID, userID string) (models.ListMember, error)
+	GetMembers(ctx context.Context, listID string) ([]models.ListMember, error)
+	// PutMember добавляет участника или меняет его роль.
+	PutMember(ctx context.Context, member models.ListMember) (models.ListMember, error)
+	RemoveMember(ctx context.Context, listID, userID string) error
+}
+
+// TokenStorage хранит токены обновления и отозванные токены доступа.
+type TokenStorage interface {
+	CreateRefreshToken(ctx context.Context, token models.RefreshToken) error
+	GetRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error)
+	// UseRefreshToken атомарно помечает токен использованным и возвращает
+	// ErrTokenUsed, если он уже был использован.
+	UseRefreshToken(ctx context.Context, tokenHash string) error
+	RevokeTokenFamily(ctx context.Context, familyID string) error
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// ReminderStorage выбирает задачи, по которым пора отправить напоминание.
+type ReminderStorage interface {
+	// GetDueReminders возвращает до limit невыполненных задач, у которых
+	// remind_at не позже now и напоминание ещё не отправлено, в порядке remind_at.
+	GetDueReminders(ctx context.Context, now time.Time, limit int) ([]models.Task, error)
+	// MarkReminded помечает напоминание отправленным, только если remind_at
+	// задачи всё ещё равен remindAt. Возвращает false, если задача удалена
+	// или её напоминание успели изменить.
+	MarkReminded(ctx context.Context, id string, remindAt time.Time) (bool, error)
+}
+
+// WebhookStorage хранит подписки на вебхуки и очередь их доставок.
+// Доставки удаляются вместе с вебхуком.
+type WebhookStorage interface {
+	CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error)
+	GetWebhook(ctx context.Context, id string) (models.Webhook, error)
+	// GetWebhooks возвращает вебхуки пользователя в порядке создания.
+	GetWebhooks(ctx context.Context, ownerID string) ([]models.Webhook, error)
+	UpdateWebhook(ctx context.Context, id string, webhook models.Webhook) (models.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+
+	// AddDeliveries сохраняет доставки одного события, назначая им ID,
+	// CreatedAt и UpdatedAt: все или ни одной.
+	AddDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error
+	GetDelivery(ctx context.Context, id string) (models.WebhookDelivery, error)
+	// GetDeliveries возвращает страницу доставок от новых к старым и общее
+	// число доставок, подходящих под query.
+	GetDeliveries(ctx context.Context, query models.DeliveryQuery) ([]models.WebhookDelivery, int, error)
+	// ClaimDeliveries выбирает до limit доставок pending, у которых
+	// NextAttemptAt не позже now, и переносит их NextAttemptAt на now+lease,
+	// чтобы их не взял другой экземпляр сервера, пока идет попытка.
+	ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error)
+	// UpdateDelivery сохраняет результат попытки доставки.
+	UpdateDelivery(ctx context.Context, delivery models.WebhookDelivery) error
+}
+
+// Storage объединяет все хранилища, которые предоставляет один бэкенд.
+type Storage interface {
+	TaskStorage
+	TrashStorage
+	HistoryStorage
+	DependencyStorage
+	TagStorage
+	UserStorage
+	ListStorage
+	TokenStorage
+	ReminderStorage
+	WebhookStorage
+}
+
+var (
+	_ Storage      = (*MemoryStorage)(nil)
+	_ ChangeSource = (*MemoryStorage)(nil)
+	_ Storage      = (*PostgresStorage)(nil)
+	_ Storage      = (*SQLiteStorage)(nil)
+)
@@ -0,0 +1,103 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+// Metrics - метрики сервера в собственном реестре, который отдается на /metrics
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	storageDuration *prometheus.HistogramVec
+}
+
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "http_requests_total",
+				Help: "Total HTTP requests by method, route template and status",
+			},
+			[]string{"method", "route", "status"},
+		),
+		requestDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "http_request_duration_seconds",
+				Help:    "HTTP request duration in seconds by method, route template and status",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{"method", "route", "status"},
+		),
+		storageDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "storage_operation_duration_seconds",
+				Help:    "Storage operation duration in seconds by operation and result",
+				Buckets: []float64{.0001, .0005, .001, .005, .01, .025, .05, .1, .25, .5, 1},
+			},
+			[]string{"operation", "result"},
+		),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.requestDuration,
+		m.storageDuration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler отдает метрики в текстовом формате Prometheus
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RegisterTaskGauges добавляет число задач в хранилище: открытых,
+// выполненных и в корзине. Задачи считаются при каждом опросе /metrics.
+func (m *Metrics) RegisterTaskGauges(tasks storage.TaskStorage) {
+	m.registry.MustRegister(&taskCollector{tasks: tasks})
+}
+
+var tasksDesc = prometheus.NewDesc(
+	"todo_tasks",
+	"Number of tasks in storage by state",
+	[]string{"state"}, nil,
+)
+
+type taskCollector struct {
+	tasks storage.TaskStorage
+}
+
+func (c *taskCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- tasksDesc
+}
+
+func (c *taskCollector) Collect(ch chan<- prometheus.Metric) {
+	open, completed := false, true
+	for state, query := range map[string]models.TaskQuery{
+		"open":      {Completed: &open},
+		"completed": {Completed: &completed},
+		"deleted":   {Deleted: true},
+	} {
+		// Нужно только общее число, сами задачи не загружаются
+		query.Limit = 1
+		_, total, err := c.tasks.GetAll(context.Background(), query)
+		if err != nil {
+			ch <- prometheus.NewInvalidMetric(tasksDesc, err)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(tasksDesc, prometheus.GaugeValue, float64(total), state)
+	}
+}
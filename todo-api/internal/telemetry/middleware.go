@@ -0,0 +1,56 @@
+package telemetry
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// unmatchedRoute - метка маршрута для запросов, не попавших ни в один
+// маршрут, чтобы произвольные пути не раздували число рядов метрик
+const unmatchedRoute = "unmatched"
+
+// Middleware считает запросы и их длительность по шаблону маршрута и статусу
+// и открывает серверный спан запроса. Спан продолжает трассу из заголовка
+// traceparent и передается дальше через контекст запроса.
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := tracer().Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(c.Request.URL.Path),
+			),
+		)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+
+		labels := []string{c.Request.Method, route, strconv.Itoa(status)}
+		m.requests.WithLabelValues(labels...).Inc()
+		m.requestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,422 @@
+package telemetry
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"todo-api/internal/models"
+	"todo-api/internal/storage"
+)
+
+// instrumentedStorage оборачивает хранилище: каждая операция получает
+// дочерний спан и попадает в гистограмму storage_operation_duration_seconds
+type instrumentedStorage struct {
+	next    storage.Storage
+	metrics *Metrics
+}
+
+// InstrumentStorage возвращает хранилище, которое измеряет операции next.
+// Дополнительные интерфейсы next (например, storage.ChangeSource) обертка не
+// наследует, их нужно получать у next.
+func InstrumentStorage(next storage.Storage, metrics *Metrics) storage.Storage {
+	return &instrumentedStorage{next: next, metrics: metrics}
+}
+
+// start открывает спан операции и возвращает функцию, которая закрывает его,
+// записывает длительность с результатом ok или error и пишет операцию в
+// отладочный лог с ID запроса из ctx
+func (s *instrumentedStorage) start(ctx context.Context, operation string) (context.Context, func(error)) {
+	begin := time.Now()
+	ctx, span := tracer().Start(ctx, "storage."+operation, trace.WithAttributes(semconv.DBOperationName(operation)))
+
+	return ctx, func(err error) {
+		result := "ok"
+		if err != nil {
+			result = "error"
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+		duration := time.Since(begin)
+		s.metrics.storageDuration.WithLabelValues(operation, result).Observe(duration.Seconds())
+		attrs := []slog.Attr{
+			slog.String("operation", operation),
+			slog.String("result", result),
+			slog.Duration("duration", duration),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.Any("error", err))
+		}
+		slog.LogAttrs(ctx, slog.LevelDebug, "Storage operation", attrs...)
+	}
+}
+
+func (s *instrumentedStorage) Create(ctx context.Context, task models.Task) (models.Task, error) {
+	ctx, done := s.start(ctx, "Create")
+	result, err := s.next.Create(ctx, task)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) GetByID(ctx context.Context, id string) (models.Task, error) {
+	ctx, done := s.start(ctx, "GetByID")
+	result, err := s.next.GetByID(ctx, id)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) GetAll(ctx context.Context, query models.TaskQuery) ([]models.Task, int, error) {
+	ctx, done := s.start(ctx, "GetAll")
+	result, total, err := s.next.GetAll(ctx, query)
+	done(err)
+	return result, total, err
+}
+
+func (s *instrumentedStorage) Update(ctx context.Context, id string, updatedTask models.Task) (models.Task, error) {
+	ctx, done := s.start(ctx, "Update")
+	result, err := s.next.Update(ctx, id, updatedTask)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) Delete(ctx context.Context, id string, version int64) error {
+	ctx, done := s.start(ctx, "Delete")
+	err := s.next.Delete(ctx, id, version)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStorage) CompleteTask(ctx context.Context, id string, version int64) (models.Task, error) {
+	ctx, done := s.start(ctx, "CompleteTask")
+	result, err := s.next.CompleteTask(ctx, id, version)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) GetChildren(ctx context.Context, parentID string) ([]models.Task, error) {
+	ctx, done := s.start(ctx, "GetChildren")
+	result, err := s.next.GetChildren(ctx, parentID)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) ApplyBatch(ctx context.Context, ops []storage.BatchOp) ([]models.Task, error) {
+	ctx, done := s.start(ctx, "ApplyBatch")
+	result, err := s.next.ApplyBatch(ctx, ops)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) GetDeleted(ctx context.Context, id string) (models.Task, error) {
+	ctx, done := s.start(ctx, "GetDeleted")
+	result, err := s.next.GetDeleted(ctx, id)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) Restore(ctx context.Context, id string) (models.Task, error) {
+	ctx, done := s.start(ctx, "Restore")
+	result, err := s.next.Restore(ctx, id)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) Purge(ctx context.Context, before time.Time) (int, error) {
+	ctx, done := s.start(ctx, "Purge")
+	result, err := s.next.Purge(ctx, before)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) AddHistory(ctx context.Context, entry models.HistoryEntry) (models.HistoryEntry, error) {
+	ctx, done := s.start(ctx, "AddHistory")
+	result, err := s.next.AddHistory(ctx, entry)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) GetHistory(ctx context.Context, taskID string, limit, offset int) ([]models.HistoryEntry, int, error) {
+	ctx, done := s.start(ctx, "GetHistory")
+	result, total, err := s.next.GetHistory(ctx, taskID, limit, offset)
+	done(err)
+	return result, total, err
+}
+
+func (s *instrumentedStorage) AddDependency(ctx context.Context, taskID, blockerID string) error {
+	ctx, done := s.start(ctx, "AddDependency")
+	err := s.next.AddDependency(ctx, taskID, blockerID)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStorage) RemoveDependency(ctx context.Context, taskID, blockerID string) error {
+	ctx, done := s.start(ctx, "RemoveDependency")
+	err := s.next.RemoveDependency(ctx, taskID, blockerID)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStorage) GetBlockers(ctx context.Context, taskID string) ([]models.Task, error) {
+	ctx, done := s.start(ctx, "GetBlockers")
+	result, err := s.next.GetBlockers(ctx, taskID)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) CreateTag(ctx context.Context, tag models.Tag) (models.Tag, error) {
+	ctx, done := s.start(ctx, "CreateTag")
+	result, err := s.next.CreateTag(ctx, tag)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) GetTag(ctx context.Context, ownerID, name string) (models.Tag, error) {
+	ctx, done := s.start(ctx, "GetTag")
+	result, err := s.next.GetTag(ctx, ownerID, name)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) GetTags(ctx context.Context, ownerID string) ([]models.Tag, error) {
+	ctx, done := s.start(ctx, "GetTags")
+	result, err := s.next.GetTags(ctx, ownerID)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) UpdateTag(ctx context.Context, ownerID, name string, tag models.Tag) (models.Tag, error) {
+	ctx, done := s.start(ctx, "UpdateTag")
+	result, err := s.next.UpdateTag(ctx, ownerID, name, tag)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) DeleteTag(ctx context.Context, ownerID, name string) error {
+	ctx, done := s.start(ctx, "DeleteTag")
+	err := s.next.DeleteTag(ctx, ownerID, name)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStorage) CountTags(ctx context.Context, query models.TaskQuery) ([]models.TagCount, error) {
+	ctx, done := s.start(ctx, "CountTags")
+	result, err := s.next.CountTags(ctx, query)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) CreateUser(ctx context.Context, user models.User) (models.User, error) {
+	ctx, done := s.start(ctx, "CreateUser")
+	result, err := s.next.CreateUser(ctx, user)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) GetUserByID(ctx context.Context, id string) (models.User, error) {
+	ctx, done := s.start(ctx, "GetUserByID")
+	result, err := s.next.GetUserByID(ctx, id)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) GetUserByUsername(ctx context.Context, username string) (models.User, error) {
+	ctx, done := s.start(ctx, "GetUserByUsername")
+	result, err := s.next.GetUserByUsername(ctx, username)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) CreateList(ctx context.Context, list models.List) (models.List, error) {
+	ctx, done := s.start(ctx, "CreateList")
+	result, err := s.next.CreateList(ctx, list)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) GetList(ctx context.Context, id string) (models.List, error) {
+	ctx, done := s.start(ctx, "GetList")
+	result, err := s.next.GetList(ctx, id)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) GetListsByMember(ctx context.Context, userID string) ([]models.List, error) {
+	ctx, done := s.start(ctx, "GetListsByMember")
+	result, err := s.next.GetListsByMember(ctx, userID)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) UpdateList(ctx context.Context, id string, list models.List) (models.List, error) {
+	ctx, done := s.start(ctx, "UpdateList")
+	result, err := s.next.UpdateList(ctx, id, list)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) DeleteList(ctx context.Context, id string) error {
+	ctx, done := s.start(ctx, "DeleteList")
+	err := s.next.DeleteList(ctx, id)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStorage) GetMember(ctx context.Context, listID, userID string) (models.ListMember, error) {
+	ctx, done := s.start(ctx, "GetMember")
+	result, err := s.next.GetMember(ctx, listID, userID)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) GetMembers(ctx context.Context, listID string) ([]models.ListMember, error) {
+	ctx, done := s.start(ctx, "GetMembers")
+	result, err := s.next.GetMembers(ctx, listID)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) PutMember(ctx context.Context, member models.ListMember) (models.ListMember, error) {
+	ctx, done := s.start(ctx, "PutMember")
+	result, err := s.next.PutMember(ctx, member)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) RemoveMember(ctx context.Context, listID, userID string) error {
+	ctx, done := s.start(ctx, "RemoveMember")
+	err := s.next.RemoveMember(ctx, listID, userID)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStorage) CreateRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	ctx, done := s.start(ctx, "CreateRefreshToken")
+	err := s.next.CreateRefreshToken(ctx, token)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStorage) GetRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error) {
+	ctx, done := s.start(ctx, "GetRefreshToken")
+	result, err := s.next.GetRefreshToken(ctx, tokenHash)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) UseRefreshToken(ctx context.Context, tokenHash string) error {
+	ctx, done := s.start(ctx, "UseRefreshToken")
+	err := s.next.UseRefreshToken(ctx, tokenHash)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStorage) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	ctx, done := s.start(ctx, "RevokeTokenFamily")
+	err := s.next.RevokeTokenFamily(ctx, familyID)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStorage) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	ctx, done := s.start(ctx, "RevokeAccessToken")
+	err := s.next.RevokeAccessToken(ctx, jti, expiresAt)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStorage) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	ctx, done := s.start(ctx, "IsAccessTokenRevoked")
+	result, err := s.next.IsAccessTokenRevoked(ctx, jti)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) GetDueReminders(ctx context.Context, now time.Time, limit int) ([]models.Task, error) {
+	ctx, done := s.start(ctx, "GetDueReminders")
+	result, err := s.next.GetDueReminders(ctx, now, limit)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) MarkReminded(ctx context.Context, id string, remindAt time.Time) (bool, error) {
+	ctx, done := s.start(ctx, "MarkReminded")
+	result, err := s.next.MarkReminded(ctx, id, remindAt)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	ctx, done := s.start(ctx, "CreateWebhook")
+	result, err := s.next.CreateWebhook(ctx, webhook)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) GetWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	ctx, done := s.start(ctx, "GetWebhook")
+	result, err := s.next.GetWebhook(ctx, id)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) GetWebhooks(ctx context.Context, ownerID string) ([]models.Webhook, error) {
+	ctx, done := s.start(ctx, "GetWebhooks")
+	result, err := s.next.GetWebhooks(ctx, ownerID)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) UpdateWebhook(ctx context.Context, id string, webhook models.Webhook) (models.Webhook, error) {
+	ctx, done := s.start(ctx, "UpdateWebhook")
+	result, err := s.next.UpdateWebhook(ctx, id, webhook)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) DeleteWebhook(ctx context.Context, id string) error {
+	ctx, done := s.start(ctx, "DeleteWebhook")
+	err := s.next.DeleteWebhook(ctx, id)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStorage) AddDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	ctx, done := s.start(ctx, "AddDeliveries")
+	err := s.next.AddDeliveries(ctx, deliveries)
+	done(err)
+	return err
+}
+
+func (s *instrumentedStorage) GetDelivery(ctx context.Context, id string) (models.WebhookDelivery, error) {
+	ctx, done := s.start(ctx, "GetDelivery")
+	result, err := s.next.GetDelivery(ctx, id)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) GetDeliveries(ctx context.Context, query models.DeliveryQuery) ([]models.WebhookDelivery, int, error) {
+	ctx, done := s.start(ctx, "GetDeliveries")
+	result, total, err := s.next.GetDeliveries(ctx, query)
+	done(err)
+	return result, total, err
+}
+
+func (s *instrumentedStorage) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	ctx, done := s.start(ctx, "ClaimDeliveries")
+	result, err := s.next.ClaimDeliveries(ctx, now, lease, limit)
+	done(err)
+	return result, err
+}
+
+func (s *instrumentedStorage) UpdateDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	ctx, done := s.start(ctx, "UpdateDelivery")
+	err := s.next.UpdateDelivery(ctx, delivery)
+	done(err)
+	return err
+}
@@ -0,0 +1,154 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"todo-api/internal/models"
+	"todo-api/internal/service"
+	"todo-api/internal/storage"
+)
+
+// newTestServer возвращает роутер с метриками и маршрутами, которые
+// проходят через TodoService к измеряемому хранилищу
+func newTestServer(metrics *Metrics) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	source := storage.NewMemoryStorage()
+	metrics.RegisterTaskGauges(source)
+	todos := service.NewTodoService(InstrumentStorage(source, metrics))
+
+	router := gin.New()
+	router.Use(metrics.Middleware())
+	router.POST("/tasks", func(c *gin.Context) {
+		task, err := todos.CreateTask(c.Request.Context(), "user", models.CreateTaskRequest{Title: "Задача"})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, task)
+	})
+	router.GET("/tasks/:id", func(c *gin.Context) {
+		task, err := todos.GetTask(c.Request.Context(), "user", c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, task)
+	})
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	return router
+}
+
+func serve(router http.Handler, method, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	return w
+}
+
+func TestMetrics(t *testing.T) {
+	router := newTestServer(NewMetrics())
+
+	for range 2 {
+		if w := serve(router, http.MethodPost, "/tasks"); w.Code != http.StatusCreated {
+			t.Fatalf("POST /tasks = %d %s", w.Code, w.Body)
+		}
+	}
+	serve(router, http.MethodGet, "/tasks/00000000-0000-0000-0000-000000000000")
+	serve(router, http.MethodGet, "/no/such/route")
+
+	w := serve(router, http.MethodGet, "/metrics")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /metrics = %d", w.Code)
+	}
+
+	body := w.Body.String()
+	for _, want := range []string{
+		`http_requests_total{method="POST",route="/tasks",status="201"} 2`,
+		// Метка маршрута - шаблон, а не путь запроса
+		`http_requests_total{method="GET",route="/tasks/:id",status="404"} 1`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`http_request_duration_seconds_count{method="POST",route="/tasks",status="201"} 2`,
+		`storage_operation_duration_seconds_count{operation="Create",result="ok"} 2`,
+		`storage_operation_duration_seconds_count{operation="GetByID",result="error"} 1`,
+		`todo_tasks{state="open"} 2`,
+		`todo_tasks{state="completed"} 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics do not contain %s", want)
+		}
+	}
+}
+
+type exportedSpan struct {
+	Name        string
+	SpanContext struct {
+		TraceID string
+		SpanID  string
+	}
+	Parent struct {
+		SpanID string
+	}
+}
+
+func TestTracing(t *testing.T) {
+	var out bytes.Buffer
+	shutdown, err := SetupTracing(t.Context(), TracingConfig{Exporter: ExporterStdout, Writer: &out, ServiceName: "todo-api-test"})
+	if err != nil {
+		t.Fatalf("SetupTracing() error = %v", err)
+	}
+
+	router := newTestServer(NewMetrics())
+	req := httptest.NewRequest(http.MethodPost, "/tasks", nil)
+	// Запрос продолжает трассу клиента
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST /tasks = %d %s", w.Code, w.Body)
+	}
+
+	if err := shutdown(t.Context()); err != nil {
+		t.Fatalf("shutdown error = %v", err)
+	}
+
+	spans := make(map[string]exportedSpan)
+	decoder := json.NewDecoder(&out)
+	for {
+		var span exportedSpan
+		if err := decoder.Decode(&span); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatalf("decode span: %v", err)
+		}
+		spans[span.Name] = span
+	}
+
+	// Спаны вложены: запрос -> сервис -> хранилище
+	chain := []string{"POST /tasks", "TodoService.CreateTask", "storage.Create"}
+	parent := "00f067aa0ba902b7"
+	for _, name := range chain {
+		span, ok := spans[name]
+		if !ok {
+			t.Fatalf("span %q not exported, got %v", name, spans)
+		}
+		if span.SpanContext.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || span.Parent.SpanID != parent {
+			t.Errorf("span %q = %+v, want child of %s in client trace", name, span, parent)
+		}
+		parent = span.SpanContext.SpanID
+	}
+}
+
+func TestSetupTracingUnknownExporter(t *testing.T) {
+	if _, err := SetupTracing(t.Context(), TracingConfig{Exporter: "jaeger"}); err == nil {
+		t.Error("SetupTracing(jaeger) error = nil")
+	}
+}
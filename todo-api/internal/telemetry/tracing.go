@@ -0,0 +1,98 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName - имя, под которым сервер создает свои спаны
+const instrumentationName = "todo-api"
+
+// Экспортеры спанов
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// TracingConfig настраивает экспорт спанов
+type TracingConfig struct {
+	// Exporter - none, stdout или otlp
+	Exporter string
+	// Endpoint - адрес коллектора OTLP/HTTP (host:port). Пустой означает
+	// переменные окружения OTEL_EXPORTER_OTLP_* или localhost:4318.
+	Endpoint string
+	// Insecure отправляет спаны в коллектор по HTTP без TLS
+	Insecure bool
+	// Writer получает спаны экспортера stdout; по умолчанию os.Stdout
+	Writer io.Writer
+	// ServiceName попадает в ресурс спанов
+	ServiceName string
+}
+
+// SetupTracing создает провайдер спанов с экспортером из config и делает его
+// глобальным вместе с распространением контекста W3C traceparent. Возвращает
+// функцию, которая отправляет оставшиеся спаны и останавливает провайдер.
+func SetupTracing(ctx context.Context, config TracingConfig) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	switch config.Exporter {
+	case "", ExporterNone:
+		// Без экспортера спаны не создаются, остается только распространение
+		// контекста в заголовках
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		writer := config.Writer
+		if writer == nil {
+			writer = os.Stdout
+		}
+		var err error
+		if exporter, err = stdouttrace.New(stdouttrace.WithWriter(writer)); err != nil {
+			return nil, err
+		}
+	case ExporterOTLP:
+		var options []otlptracehttp.Option
+		if config.Endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpoint(config.Endpoint))
+		}
+		if config.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		var err error
+		if exporter, err = otlptracehttp.New(ctx, options...); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", config.Exporter)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(config.ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return provider.Shutdown, nil
+}
+
+// tracer возвращает трассировщик текущего глобального провайдера
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"time"
 
@@ -35,7 +36,7 @@ func main() {
 			UpdatedAt:   time.Now().Add(-time.Duration(i) * time.Hour),
 		}
 
-		_, err := storage.Create(task)
+		_, err := storage.Create(context.Background(), task)
 		if err != nil {
 			log.Printf("Failed to create task %d: %v", i+1, err)
 		} else {